@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
@@ -11,17 +12,32 @@ import (
 	"syscall"
 	"time"
 
+	"connectrpc.com/connect"
 	"connectrpc.com/grpchealth"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
 
+	"github.com/KasumiMercury/primind-notification-invoker/internal/channel"
 	"github.com/KasumiMercury/primind-notification-invoker/internal/config"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/deadletter"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/email"
 	"github.com/KasumiMercury/primind-notification-invoker/internal/fcm"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/gen/notify/v1/notifyv1connect"
 	"github.com/KasumiMercury/primind-notification-invoker/internal/handler"
 	"github.com/KasumiMercury/primind-notification-invoker/internal/health"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/idempotency"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/jobs"
 	"github.com/KasumiMercury/primind-notification-invoker/internal/observability/logging"
 	"github.com/KasumiMercury/primind-notification-invoker/internal/observability/metrics"
 	"github.com/KasumiMercury/primind-notification-invoker/internal/observability/middleware"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/retry"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/scheduler"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/webhook"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/webpush"
 )
 
 // Version is set via ldflags at build time
@@ -57,7 +73,10 @@ func run() error {
 
 	cfg := config.Load()
 
-	slog.Info("configuration loaded", slog.String("port", cfg.Port))
+	slog.Info("configuration loaded",
+		slog.String("port", cfg.Port),
+		slog.Duration("request_timeout", cfg.RequestTimeout),
+	)
 
 	httpMetrics, err := metrics.NewHTTPMetrics()
 	if err != nil {
@@ -66,7 +85,64 @@ func run() error {
 		return err
 	}
 
-	fcmClient, err := fcm.NewClient(ctx, cfg.FirebaseProjectID, cfg.WebAppBaseURL)
+	rpcMetrics, err := metrics.NewRPCMetrics()
+	if err != nil {
+		slog.Error("failed to initialize RPC metrics", slog.String("error", err.Error()))
+
+		return err
+	}
+
+	notificationMetrics, err := metrics.NewNotificationMetrics()
+	if err != nil {
+		slog.Error("failed to initialize notification metrics", slog.String("error", err.Error()))
+
+		return err
+	}
+
+	var rateLimiter *fcm.RateLimiter
+	if cfg.FCMRateLimit > 0 {
+		rateLimitMetrics, err := metrics.NewRateLimitMetrics()
+		if err != nil {
+			slog.Error("failed to initialize rate limit metrics", slog.String("error", err.Error()))
+
+			return err
+		}
+
+		rateLimiter = fcm.NewRateLimiter(fcm.RateLimitConfig{
+			Rate:     float64(cfg.FCMRateLimit),
+			Burst:    cfg.FCMRateLimitBurst,
+			MaxWait:  cfg.FCMRateLimitMaxWait,
+			Observer: rateLimitMetrics,
+		})
+		if err := rateLimitMetrics.ObserveTokens(rateLimiter.Tokens); err != nil {
+			slog.Error("failed to observe rate limiter", slog.String("error", err.Error()))
+
+			return err
+		}
+
+		slog.Info("FCM rate limit enabled",
+			slog.Int("messages_per_second", cfg.FCMRateLimit),
+			slog.Duration("max_wait", cfg.FCMRateLimitMaxWait),
+		)
+	}
+
+	fcmClient, err := fcm.NewClient(ctx, fcm.Config{
+		ProjectID:     cfg.FirebaseProjectID,
+		WebAppBaseURL: cfg.WebAppBaseURL,
+		Retry: retry.Policy{
+			MaxAttempts:    cfg.FCMRetryMaxAttempts,
+			InitialBackoff: cfg.FCMRetryInitialBackoff,
+			MaxBackoff:     cfg.FCMRetryMaxBackoff,
+		},
+		Parallelism:  cfg.FCMBatchParallelism,
+		Platforms:    cfg.FCMPlatforms,
+		LinkPatterns: cfg.TaskLinkPatterns,
+		RateLimiter:  rateLimiter,
+		Breaker: fcm.BreakerConfig{
+			FailureThreshold: cfg.FCMBreakerFailureThreshold,
+			OpenTimeout:      cfg.FCMBreakerOpenTimeout,
+		},
+	})
 	if err != nil {
 		slog.Error("failed to initialize FCM client", slog.String("error", err.Error()))
 
@@ -75,15 +151,131 @@ func run() error {
 
 	slog.Info("FCM client initialized",
 		slog.String("web_app_base_url", cfg.WebAppBaseURL),
+		slog.Int("retry_max_attempts", cfg.FCMRetryMaxAttempts),
+		slog.Int("batch_parallelism", cfg.FCMBatchParallelism),
 	)
 
-	notificationHandler := handler.NewNotificationHandler(fcmClient)
+	channels := channel.NewRegistry()
+	channels.Register(domain.ChannelFCM, fcmClient)
+
+	if cfg.WebPushVAPIDPrivateKey != "" {
+		vapidKeys, err := webpush.ParseVAPIDKeys(cfg.WebPushVAPIDPrivateKey)
+		if err != nil {
+			slog.Error("failed to load VAPID keys", slog.String("error", err.Error()))
+
+			return err
+		}
+
+		channels.Register(domain.ChannelWebPush, webpush.NewClient(webpush.Config{
+			VAPID:        vapidKeys,
+			Subject:      cfg.WebPushVAPIDSubject,
+			Renderer:     fcmClient,
+			AllowedHosts: cfg.WebPushAllowedHosts,
+		}))
+
+		slog.Info("web push channel enabled", slog.String("vapid_public_key", vapidKeys.PublicKey()))
+	}
+
+	if cfg.ChatWebhookEnabled {
+		channels.Register(domain.ChannelWebhook, webhook.NewClient(webhook.Config{
+			Renderer:     fcmClient,
+			AllowedHosts: cfg.ChatWebhookAllowedHosts,
+		}))
+
+		slog.Info("chat webhook channel enabled", slog.Any("allowed_hosts", cfg.ChatWebhookAllowedHosts))
+	}
+
+	handlerOptions := handler.Options{
+		Channels:         channels,
+		Metrics:          notificationMetrics,
+		BatchMaxItems:    cfg.BatchMaxItems,
+		BatchConcurrency: cfg.BatchConcurrency,
+		BatchItemTimeout: cfg.RequestTimeout,
+	}
+
+	if cfg.SMTPHost != "" {
+		emailClient, err := email.NewClient(email.Config{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+			From:     cfg.SMTPFrom,
+			Renderer: fcmClient,
+		})
+		if err != nil {
+			slog.Error("failed to initialize email fallback", slog.String("error", err.Error()))
+
+			return err
+		}
+
+		handlerOptions.Fallback = emailClient
+
+		slog.Info("email fallback enabled", slog.String("smtp_host", cfg.SMTPHost))
+	}
+
+	db := &database{url: cfg.DatabaseURL}
+
+	idempotencyStore, err := newIdempotencyStore(ctx, cfg, db)
+	if err != nil {
+		slog.Error("failed to initialize idempotency store", slog.String("error", err.Error()))
+
+		return err
+	}
+	handlerOptions.Idempotency = idempotencyStore
+
+	deadLetterSink, err := newDeadLetterSink(ctx, cfg, db)
+	if err != nil {
+		slog.Error("failed to initialize dead-letter sink", slog.String("error", err.Error()))
+
+		return err
+	}
+	handlerOptions.DeadLetter = deadLetterSink
+	handlerOptions.MaxAttempts = cfg.CloudTasksMaxAttempts
+
+	jobStore, err := newJobStore(ctx, cfg, db)
+	if err != nil {
+		slog.Error("failed to initialize job store", slog.String("error", err.Error()))
+
+		return err
+	}
+
+	var jobPool *jobs.Pool
+	if jobStore != nil {
+		jobPool = jobs.NewPool(cfg.JobWorkers, cfg.JobQueueSize)
+		handlerOptions.Jobs = jobStore
+		handlerOptions.JobPool = jobPool
+	}
+
+	notificationScheduler, err := newScheduler(ctx, cfg, db)
+	if err != nil {
+		slog.Error("failed to initialize scheduler", slog.String("error", err.Error()))
+
+		return err
+	}
+	handlerOptions.Scheduler = notificationScheduler
+
+	notificationHandler := handler.NewNotificationHandler(fcmClient, handlerOptions)
+	if err := notificationHandler.StartScheduler(ctx); err != nil {
+		slog.Error("failed to start scheduler", slog.String("error", err.Error()))
+
+		return err
+	}
 
 	// Health check setup
 	healthChecker := health.NewChecker(fcmClient, Version)
 
 	mux := http.NewServeMux()
-	mux.HandleFunc("/notify", notificationHandler.SendNotification)
+	// Batch items get the request timeout each, in the handler.
+	mux.Handle("/notify", middleware.Timeout(http.HandlerFunc(notificationHandler.SendNotification), cfg.RequestTimeout))
+	mux.HandleFunc("/notify/batch", notificationHandler.SendNotificationBatch)
+	mux.Handle("/pubsub/push", middleware.Timeout(http.HandlerFunc(notificationHandler.ReceivePubSub), cfg.RequestTimeout))
+	adminAuth := handler.AdminAuth(cfg.AdminToken)
+	// Job results list the tokens of every target.
+	mux.Handle("GET /jobs/{id}", adminAuth(http.HandlerFunc(notificationHandler.GetJob)))
+	mux.Handle("POST /admin/topics/subscribe", adminAuth(http.HandlerFunc(notificationHandler.SubscribeToTopic)))
+	mux.Handle("POST /admin/topics/unsubscribe", adminAuth(http.HandlerFunc(notificationHandler.UnsubscribeFromTopic)))
+	mux.Handle("GET /admin/scheduled", adminAuth(http.HandlerFunc(notificationHandler.ListScheduled)))
+	mux.Handle("DELETE /admin/scheduled/{task_id}", adminAuth(http.HandlerFunc(notificationHandler.CancelScheduled)))
 	mux.HandleFunc("/health/live", healthChecker.LiveHandler)
 	mux.HandleFunc("/health/ready", healthChecker.ReadyHandler)
 	mux.HandleFunc("/health", healthChecker.ReadyHandler)
@@ -93,40 +285,69 @@ func run() error {
 	grpcHealthPath, grpcHealthHandler := grpchealth.NewHandler(grpcHealthChecker)
 
 	// Wrap with observability middleware
+	const tracerName = "github.com/KasumiMercury/primind-notification-invoker/internal/observability/middleware"
 	wrappedHandler := middleware.HTTP(mux, middleware.HTTPConfig{
 		SkipPaths:  []string{"/health", "/health/live", "/health/ready", "/metrics"},
 		Module:     logging.Module("notification-invoker"),
 		Worker:     true,
-		TracerName: "github.com/KasumiMercury/primind-notification-invoker/internal/observability/middleware",
+		TracerName: tracerName,
+		// CloudEvents in binary mode name the job after the event; structured
+		// events are only known to the handler, which logs them itself.
 		JobNameResolver: func(r *http.Request) string {
-			if messageType := r.Header.Get("message_type"); messageType != "" {
-				return messageType
-			}
-			if eventType := r.Header.Get("event_type"); eventType != "" {
-				return eventType
+			if ceType := r.Header.Get("ce-type"); ceType != "" {
+				if ceSource := r.Header.Get("ce-source"); ceSource != "" {
+					return ceSource + " " + ceType
+				}
+				return ceType
 			}
 
 			return r.URL.Path
 		},
+		JobIDResolver: func(r *http.Request) string {
+			return r.Header.Get("ce-id")
+		},
 		HTTPMetrics: httpMetrics,
 	})
 	wrappedHandler = middleware.PanicRecoveryHTTP(wrappedHandler)
 
-	// Create multiplexed handler for HTTP + gRPC health
+	// notify.v1.NotificationService over Connect, gRPC and gRPC-Web, observed
+	// by its interceptors instead of the HTTP middleware
+	notifyServicePath, notifyServiceHandler := notifyv1connect.NewNotificationServiceHandler(
+		handler.NewNotificationService(notificationHandler),
+		connect.WithInterceptors(
+			middleware.ConnectTracing(tracerName),
+			middleware.ConnectLogging(logging.Module("notification-invoker")),
+			middleware.ConnectMetrics(rpcMetrics),
+			handler.ValidationInterceptor(),
+		),
+	)
+	notifyServiceHandler = middleware.PanicRecoveryHTTP(notifyServiceHandler)
+
+	// Create multiplexed handler for HTTP + gRPC health + NotificationService
 	finalHandler := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		if strings.HasPrefix(req.URL.Path, grpcHealthPath) {
 			grpcHealthHandler.ServeHTTP(w, req)
 			return
 		}
+		if strings.HasPrefix(req.URL.Path, notifyServicePath) {
+			notifyServiceHandler.ServeHTTP(w, req)
+			return
+		}
 		wrappedHandler.ServeHTTP(w, req)
 	})
 
+	// Leave the handlers time to answer once their deadline has passed.
+	writeTimeout := 30 * time.Second
+	if cfg.RequestTimeout > 0 {
+		writeTimeout = cfg.RequestTimeout + 5*time.Second
+	}
+
 	h2s := &http2.Server{}
 	server := &http.Server{
 		Addr:              ":" + cfg.Port,
 		Handler:           h2c.NewHandler(finalHandler, h2s),
 		ReadTimeout:       10 * time.Second,
-		WriteTimeout:      30 * time.Second,
+		WriteTimeout:      writeTimeout,
 		IdleTimeout:       60 * time.Second,
 		ReadHeaderTimeout: 5 * time.Second,
 	}
@@ -137,7 +358,10 @@ func run() error {
 		slog.String("version", Version),
 	)
 
+	shutdownDone := make(chan struct{})
 	go func() {
+		defer close(shutdownDone)
+
 		<-ctx.Done()
 
 		slog.InfoContext(ctx, "shutdown signal received",
@@ -150,6 +374,19 @@ func run() error {
 		if err := server.Shutdown(shutdownCtx); err != nil {
 			slog.Error("failed to shutdown server", slog.String("error", err.Error()))
 		}
+
+		// Held and background sends outlive their requests; let them finish
+		// with what is left of the shutdown budget.
+		if notificationScheduler != nil {
+			if err := notificationScheduler.Shutdown(shutdownCtx); err != nil {
+				slog.Error("failed to stop scheduler", slog.String("error", err.Error()))
+			}
+		}
+		if jobPool != nil {
+			if err := jobPool.Shutdown(shutdownCtx); err != nil {
+				slog.Error("failed to drain background jobs", slog.String("error", err.Error()))
+			}
+		}
 	}()
 
 	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
@@ -161,9 +398,184 @@ func run() error {
 		return err
 	}
 
+	<-shutdownDone
+
 	slog.InfoContext(ctx, "server stopped",
 		slog.String("event", "server.stop"),
 	)
 
 	return nil
 }
+
+// database opens the connection at DATABASE_URL on first use, so that it is
+// only required when a SQL-backed store or sink is selected.
+type database struct {
+	url string
+	db  *gorm.DB
+}
+
+func (d *database) open() (*gorm.DB, error) {
+	if d.db != nil {
+		return d.db, nil
+	}
+	if d.url == "" {
+		return nil, errors.New("DATABASE_URL is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(d.url), &gorm.Config{
+		Logger: logging.NewGormLogger(200 * time.Millisecond).LogMode(gormlogger.Warn),
+	})
+	if err != nil {
+		return nil, err
+	}
+	d.db = db
+
+	return db, nil
+}
+
+// newIdempotencyStore creates the store selected by IDEMPOTENCY_STORE.
+// It returns a nil store when idempotency is disabled.
+func newIdempotencyStore(ctx context.Context, cfg *config.Config, database *database) (idempotency.Store, error) {
+	storeCfg := idempotency.Config{
+		TTL:   cfg.IdempotencyTTL,
+		Lease: cfg.IdempotencyLease,
+	}
+
+	switch cfg.IdempotencyStore {
+	case "none":
+		slog.Info("idempotency disabled")
+
+		return nil, nil
+	case "memory":
+		slog.Info("idempotency enabled", slog.String("store", "memory"), slog.Duration("ttl", cfg.IdempotencyTTL))
+
+		return idempotency.NewMemoryStore(storeCfg), nil
+	case "sql":
+		db, err := database.open()
+		if err != nil {
+			return nil, err
+		}
+
+		store := idempotency.NewSQLStore(db, storeCfg)
+		if err := store.Migrate(ctx); err != nil {
+			return nil, err
+		}
+
+		slog.Info("idempotency enabled", slog.String("store", "sql"), slog.Duration("ttl", cfg.IdempotencyTTL))
+
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown IDEMPOTENCY_STORE %q", cfg.IdempotencyStore)
+	}
+}
+
+// newJobStore creates the store selected by JOB_STORE. It returns a nil
+// store when background jobs are disabled.
+func newJobStore(ctx context.Context, cfg *config.Config, database *database) (jobs.Store, error) {
+	storeCfg := jobs.Config{TTL: cfg.JobTTL, MaxEntries: cfg.JobMaxEntries}
+
+	switch cfg.JobStore {
+	case "none":
+		slog.Info("background jobs disabled")
+
+		return nil, nil
+	case "memory":
+		slog.Info("background jobs enabled", slog.String("store", "memory"), slog.Int("workers", cfg.JobWorkers))
+
+		return jobs.NewMemoryStore(storeCfg), nil
+	case "sql":
+		db, err := database.open()
+		if err != nil {
+			return nil, err
+		}
+
+		store := jobs.NewSQLStore(db, storeCfg)
+		if err := store.Migrate(ctx); err != nil {
+			return nil, err
+		}
+
+		slog.Info("background jobs enabled", slog.String("store", "sql"), slog.Int("workers", cfg.JobWorkers))
+
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown JOB_STORE %q", cfg.JobStore)
+	}
+}
+
+func schedulingEnabled(cfg *config.Config) bool {
+	return cfg.SchedulerHorizon > 0 && cfg.SchedulerStore != "none"
+}
+
+// newScheduler creates the scheduler of send_at, saving held requests to
+// the store selected by SCHEDULER_STORE. It returns a nil scheduler when
+// SCHEDULER_HORIZON is zero or SCHEDULER_STORE is none.
+func newScheduler(ctx context.Context, cfg *config.Config, database *database) (*scheduler.Scheduler, error) {
+	if !schedulingEnabled(cfg) {
+		slog.Info("scheduled sends disabled")
+
+		return nil, nil
+	}
+
+	schedulerCfg := scheduler.Config{
+		Horizon:     cfg.SchedulerHorizon,
+		MaxAttempts: cfg.SchedulerMaxAttempts,
+	}
+
+	switch cfg.SchedulerStore {
+	case "sql":
+		db, err := database.open()
+		if err != nil {
+			return nil, err
+		}
+
+		store := scheduler.NewSQLStore(db)
+		if err := store.Migrate(ctx); err != nil {
+			return nil, err
+		}
+		schedulerCfg.Store = store
+	default:
+		return nil, fmt.Errorf("unknown SCHEDULER_STORE %q", cfg.SchedulerStore)
+	}
+
+	slog.Info("scheduled sends enabled",
+		slog.Duration("horizon", cfg.SchedulerHorizon),
+		slog.String("store", cfg.SchedulerStore),
+		slog.Int("max_attempts", cfg.SchedulerMaxAttempts),
+	)
+
+	return scheduler.New(schedulerCfg), nil
+}
+
+// newDeadLetterSink creates the sink selected by DEAD_LETTER_SINK. It
+// returns nil when nothing gives up: CLOUD_TASKS_MAX_ATTEMPTS leaves retries
+// to Cloud Tasks and scheduled sends are disabled.
+func newDeadLetterSink(ctx context.Context, cfg *config.Config, database *database) (deadletter.Sink, error) {
+	if cfg.CloudTasksMaxAttempts <= 0 && !schedulingEnabled(cfg) {
+		slog.Info("dead-lettering disabled")
+
+		return nil, nil
+	}
+
+	switch cfg.DeadLetterSink {
+	case "log":
+		slog.Info("dead-lettering enabled", slog.String("sink", "log"))
+
+		return deadletter.LogSink{}, nil
+	case "sql":
+		db, err := database.open()
+		if err != nil {
+			return nil, err
+		}
+
+		sink := deadletter.NewSQLSink(db)
+		if err := sink.Migrate(ctx); err != nil {
+			return nil, err
+		}
+
+		slog.Info("dead-lettering enabled", slog.String("sink", "sql"))
+
+		return sink, nil
+	default:
+		return nil, fmt.Errorf("unknown DEAD_LETTER_SINK %q", cfg.DeadLetterSink)
+	}
+}
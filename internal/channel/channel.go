@@ -0,0 +1,65 @@
+// Package channel routes reminders to the delivery channel of each recipient.
+package channel
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/model"
+)
+
+// DefaultTimeout bounds a delivery request of a channel that sets no timeout of its own.
+const DefaultTimeout = 10 * time.Second
+
+// Reminder is the channel-independent content of a notification. Each
+// channel renders it in its own format.
+type Reminder struct {
+	TaskID   domain.TaskID
+	TaskType domain.Type
+	Color    string
+	Mode     domain.DeliveryMode
+	// DryRun asks the channel to validate without delivering anything.
+	DryRun bool
+}
+
+// Content is a reminder rendered for display.
+type Content struct {
+	Title string
+	Body  string
+	// Icon and Link are absolute URLs, or empty when not configured.
+	Icon string
+	Link string
+}
+
+// Renderer produces the text of a reminder. *fcm.Client implements it, so
+// channels that deliver text carry the same templates as push notifications.
+type Renderer interface {
+	Content(reminder Reminder) Content
+}
+
+// Channel delivers reminders to addresses on a single delivery channel.
+type Channel interface {
+	// Deliver sends reminder to every address. The result holds one entry per
+	// address, in order. An error means no address was attempted.
+	Deliver(ctx context.Context, addresses []string, reminder Reminder) (*model.BulkResult, error)
+}
+
+// Redacter is implemented by channels whose addresses are secrets, such as a
+// push subscription with its keys or a webhook URL. Results name such a
+// recipient by Redact(address) instead of its address.
+type Redacter interface {
+	Redact(address string) string
+}
+
+// Origin returns the scheme and host of rawURL, or "" when it has none. It
+// names a recipient whose URL must not be reported in full.
+func Origin(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+
+	return u.Scheme + "://" + u.Host
+}
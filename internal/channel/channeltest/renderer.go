@@ -0,0 +1,18 @@
+// Package channeltest provides stand-ins for the channel package in tests.
+package channeltest
+
+import "github.com/KasumiMercury/primind-notification-invoker/internal/channel"
+
+// Content is what Renderer renders every reminder as.
+var Content = channel.Content{
+	Title: "予定の時間です",
+	Body:  "タスクを確認しましょう",
+	Link:  "https://app.example.com/tasks/1",
+}
+
+// Renderer is a channel.Renderer that renders every reminder as Content.
+type Renderer struct{}
+
+func (Renderer) Content(channel.Reminder) channel.Content {
+	return Content
+}
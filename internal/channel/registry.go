@@ -0,0 +1,175 @@
+package channel
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/model"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/progress"
+)
+
+// Registry maps delivery channels to their implementations. Channels are
+// registered at startup; Register must not be called concurrently with Deliver.
+type Registry struct {
+	channels map[domain.Channel]Channel
+}
+
+func NewRegistry() *Registry {
+	return &Registry{channels: make(map[domain.Channel]Channel)}
+}
+
+// Register makes ch handle recipients on the given channel, replacing any
+// earlier registration.
+func (r *Registry) Register(name domain.Channel, ch Channel) {
+	r.channels[name] = ch
+}
+
+// Has reports whether a channel is registered. A nil Registry has no channels.
+func (r *Registry) Has(name domain.Channel) bool {
+	if r == nil {
+		return false
+	}
+
+	_, ok := r.channels[name]
+	return ok
+}
+
+// Deliver groups recipients by channel and delivers to every channel
+// concurrently. Results keep the recipient order. A channel that fails as a
+// whole does not affect the others: its recipients are marked as failed and
+// the status becomes partial or failed, like a failed FCM batch.
+func (r *Registry) Deliver(ctx context.Context, recipients []domain.Recipient, reminder Reminder) *model.BulkResult {
+	var order []domain.Channel
+	groups := make(map[domain.Channel][]int)
+	for i, recipient := range recipients {
+		if _, ok := groups[recipient.Channel]; !ok {
+			order = append(order, recipient.Channel)
+		}
+		groups[recipient.Channel] = append(groups[recipient.Channel], i)
+	}
+
+	groupResults := make([]*model.BulkResult, len(order))
+	var wg sync.WaitGroup
+	for g, name := range order {
+		wg.Go(func() {
+			indices := groups[name]
+			addresses := make([]string, len(indices))
+			for i, idx := range indices {
+				addresses[i] = recipients[idx].Address
+			}
+
+			// Channels may send through the FCM client, which reports its
+			// own batches; report whole groups here instead.
+			groupResults[g] = r.deliverChannel(progress.WithReporter(ctx, nil), name, addresses, reminder)
+			progress.Report(ctx, len(indices))
+		})
+	}
+	wg.Wait()
+
+	results := make([]model.TokenResult, len(recipients))
+	successCount := 0
+	failedGroups := 0
+	var retryAfter time.Duration
+	for g, name := range order {
+		result := groupResults[g]
+		retryAfter = max(retryAfter, result.RetryAfter)
+		for i, idx := range groups[name] {
+			results[idx] = result.Results[i]
+			results[idx].Channel = name
+		}
+		successCount += result.SuccessCount
+		if result.Status == domain.DeliveryStatusFailed {
+			failedGroups++
+		}
+	}
+
+	status := domain.DeliveryStatusComplete
+	switch {
+	case len(order) > 0 && failedGroups == len(order):
+		status = domain.DeliveryStatusFailed
+	case failedGroups > 0:
+		status = domain.DeliveryStatusPartial
+	default:
+		for _, result := range groupResults {
+			if result.Status == domain.DeliveryStatusPartial {
+				status = domain.DeliveryStatusPartial
+			}
+		}
+	}
+
+	return &model.BulkResult{
+		Total:        len(recipients),
+		SuccessCount: successCount,
+		FailureCount: len(recipients) - successCount,
+		Status:       status,
+		Results:      results,
+		RetryAfter:   retryAfter,
+	}
+}
+
+func (r *Registry) deliverChannel(ctx context.Context, name domain.Channel, addresses []string, reminder Reminder) *model.BulkResult {
+	ch, ok := r.channels[name]
+	if !ok {
+		return failedChannel(redact(nil, addresses), domain.ErrorCodeInvalidArgument, fmt.Errorf("channel %s is not enabled", name))
+	}
+
+	result, err := ch.Deliver(ctx, addresses, reminder)
+	if err != nil {
+		slog.Error("channel delivery failed",
+			"channel", name.String(),
+			"recipient_count", len(addresses),
+			"error", err,
+		)
+		return failedChannel(redact(ch, addresses), domain.ErrorCodeUnavailable, err)
+	}
+	if len(result.Results) != len(addresses) {
+		return failedChannel(redact(ch, addresses), domain.ErrorCodeInternal,
+			fmt.Errorf("channel %s returned %d results for %d recipients", name, len(result.Results), len(addresses)))
+	}
+
+	return result
+}
+
+// redact names addresses as ch reports them. Without a channel it cannot tell
+// whether an address is a secret, so the names are left empty.
+func redact(ch Channel, addresses []string) []string {
+	names := make([]string, len(addresses))
+	if ch == nil {
+		return names
+	}
+
+	redacter, ok := ch.(Redacter)
+	if !ok {
+		return addresses
+	}
+	for i, address := range addresses {
+		names[i] = redacter.Redact(address)
+	}
+
+	return names
+}
+
+// failedChannel marks every recipient of a channel that could not be
+// attempted as failed. names are the recipients as reported in results.
+func failedChannel(names []string, code domain.ErrorCode, err error) *model.BulkResult {
+	results := make([]model.TokenResult, len(names))
+	for i, name := range names {
+		results[i] = model.TokenResult{
+			Token:     name,
+			Error:     err.Error(),
+			ErrorCode: code,
+			Attempts:  1,
+		}
+	}
+
+	return &model.BulkResult{
+		Total:        len(names),
+		FailureCount: len(names),
+		Status:       domain.DeliveryStatusFailed,
+		Results:      results,
+	}
+}
@@ -0,0 +1,185 @@
+package channel
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/model"
+)
+
+const (
+	testChannelA domain.Channel = "a"
+	testChannelB domain.Channel = "b"
+)
+
+// stubChannel succeeds for every address except those in fail, or fails as a whole with err.
+type stubChannel struct {
+	fail      map[string]bool
+	err       error
+	addresses []string
+	reminder  Reminder
+}
+
+func (s *stubChannel) Deliver(_ context.Context, addresses []string, reminder Reminder) (*model.BulkResult, error) {
+	s.addresses = addresses
+	s.reminder = reminder
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	result := &model.BulkResult{Total: len(addresses), Status: domain.DeliveryStatusComplete}
+	for _, address := range addresses {
+		r := model.TokenResult{Token: address, Success: !s.fail[address], Attempts: 1}
+		if r.Success {
+			result.SuccessCount++
+		} else {
+			r.ErrorCode = domain.ErrorCodeUnregistered
+			result.FailureCount++
+		}
+		result.Results = append(result.Results, r)
+	}
+
+	return result, nil
+}
+
+func TestRegistry_Deliver(t *testing.T) {
+	a := &stubChannel{fail: map[string]bool{"a2": true}}
+	b := &stubChannel{}
+	registry := NewRegistry()
+	registry.Register(testChannelA, a)
+	registry.Register(testChannelB, b)
+
+	recipients := []domain.Recipient{
+		{Channel: testChannelA, Address: "a1"},
+		{Channel: testChannelB, Address: "b1"},
+		{Channel: testChannelA, Address: "a2"},
+	}
+	reminder := Reminder{TaskID: "task", TaskType: domain.TypeShort, DryRun: true}
+
+	result := registry.Deliver(context.Background(), recipients, reminder)
+
+	if result.Total != 3 || result.SuccessCount != 2 || result.FailureCount != 1 || result.Status != domain.DeliveryStatusComplete {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	for i, recipient := range recipients {
+		r := result.Results[i]
+		if r.Token != recipient.Address || r.Channel != recipient.Channel {
+			t.Errorf("result %d: expected %s on %s, got %+v", i, recipient.Address, recipient.Channel, r)
+		}
+	}
+	if len(a.addresses) != 2 || len(b.addresses) != 1 {
+		t.Errorf("expected recipients to be grouped by channel, got a=%v b=%v", a.addresses, b.addresses)
+	}
+	if a.reminder != reminder {
+		t.Errorf("expected reminder to be passed through, got %+v", a.reminder)
+	}
+}
+
+func TestRegistry_DeliverChannelFailure(t *testing.T) {
+	tests := []struct {
+		name       string
+		register   map[domain.Channel]Channel
+		wantStatus domain.DeliveryStatus
+		wantCodes  []domain.ErrorCode
+	}{
+		{
+			name: "one channel fails",
+			register: map[domain.Channel]Channel{
+				testChannelA: &stubChannel{},
+				testChannelB: &stubChannel{err: errors.New("connection refused")},
+			},
+			wantStatus: domain.DeliveryStatusPartial,
+			wantCodes:  []domain.ErrorCode{domain.ErrorCodeNone, domain.ErrorCodeUnavailable},
+		},
+		{
+			name: "unregistered channel",
+			register: map[domain.Channel]Channel{
+				testChannelA: &stubChannel{err: errors.New("connection refused")},
+			},
+			wantStatus: domain.DeliveryStatusFailed,
+			wantCodes:  []domain.ErrorCode{domain.ErrorCodeUnavailable, domain.ErrorCodeInvalidArgument},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewRegistry()
+			for name, ch := range tt.register {
+				registry.Register(name, ch)
+			}
+
+			result := registry.Deliver(context.Background(), []domain.Recipient{
+				{Channel: testChannelA, Address: "a1"},
+				{Channel: testChannelB, Address: "b1"},
+			}, Reminder{})
+
+			if result.Status != tt.wantStatus {
+				t.Errorf("expected status %s, got %s", tt.wantStatus, result.Status)
+			}
+			for i, want := range tt.wantCodes {
+				if got := result.Results[i].ErrorCode; got != want {
+					t.Errorf("result %d: expected error code %q, got %q", i, want, got)
+				}
+			}
+		})
+	}
+}
+
+// redactingChannel fails as a whole and names its recipients by their length.
+type redactingChannel struct{}
+
+func (redactingChannel) Deliver(context.Context, []string, Reminder) (*model.BulkResult, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (redactingChannel) Redact(address string) string {
+	return strings.Repeat("*", len(address))
+}
+
+func TestRegistry_DeliverRedactsFailedChannel(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register(testChannelA, redactingChannel{})
+
+	result := registry.Deliver(context.Background(), []domain.Recipient{
+		{Channel: testChannelA, Address: "secret"},
+		{Channel: testChannelB, Address: "secret"},
+	}, Reminder{})
+
+	if got := result.Results[0].Token; got != "******" {
+		t.Errorf("expected the channel to name its recipient, got %q", got)
+	}
+	if got := result.Results[1].Token; got != "" {
+		t.Errorf("expected no name for a recipient of an unregistered channel, got %q", got)
+	}
+}
+
+func TestOrigin(t *testing.T) {
+	tests := map[string]string{
+		"https://hooks.slack.com/services/T0/B0/secret": "https://hooks.slack.com",
+		"https://push.example.com:8443/p?k=v":           "https://push.example.com:8443",
+		"not a url":                                     "",
+		"":                                              "",
+	}
+
+	for rawURL, want := range tests {
+		if got := Origin(rawURL); got != want {
+			t.Errorf("Origin(%q) = %q, want %q", rawURL, got, want)
+		}
+	}
+}
+
+func TestRegistry_Has(t *testing.T) {
+	var nilRegistry *Registry
+	if nilRegistry.Has(testChannelA) {
+		t.Error("expected a nil registry to have no channels")
+	}
+
+	registry := NewRegistry()
+	registry.Register(testChannelA, &stubChannel{})
+	if !registry.Has(testChannelA) || registry.Has(testChannelB) {
+		t.Error("unexpected registered channels")
+	}
+}
@@ -1,9 +1,15 @@
 package config
 
 import (
+	"fmt"
 	"log/slog"
 	"os"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/fcm"
 )
 
 type Config struct {
@@ -11,6 +17,99 @@ type Config struct {
 	FirebaseProjectID string
 	WebAppBaseURL     string
 	LogLevel          slog.Level
+
+	// RequestTimeout is the deadline of each /notify and /pubsub/push request
+	// and of each /notify/batch item, within which retries and rate limit
+	// waits must fit. Zero disables it.
+	RequestTimeout time.Duration
+
+	FCMRetryMaxAttempts    int
+	FCMRetryInitialBackoff time.Duration
+	FCMRetryMaxBackoff     time.Duration
+	FCMBatchParallelism    int
+
+	// FCMRateLimit is the project-wide send budget in messages per second. Zero disables the limiter.
+	FCMRateLimit        int
+	FCMRateLimitBurst   int
+	FCMRateLimitMaxWait time.Duration
+
+	// FCMBreakerFailureThreshold consecutive transport failures open the FCM circuit breaker.
+	FCMBreakerFailureThreshold int
+	FCMBreakerOpenTimeout      time.Duration
+
+	// FCMPlatforms is the presentation of reminders per task type:
+	// fcm.DefaultPlatformOptions with the FCM_* presentation settings applied.
+	FCMPlatforms map[domain.Type]fcm.PlatformOptions
+
+	// TaskLinkPatterns maps a task type to the web app path opened on tap.
+	TaskLinkPatterns map[domain.Type]string
+
+	// WebPushVAPIDPrivateKey enables the native Web Push channel when set.
+	WebPushVAPIDPrivateKey string
+	WebPushVAPIDSubject    string
+	// WebPushAllowedHosts restricts subscription endpoints to these push
+	// services. Empty uses the push services of the major browsers.
+	WebPushAllowedHosts []string
+
+	// SMTPHost enables the email fallback when set.
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+
+	// AdminToken is the bearer token of the /admin endpoints. Empty disables them.
+	AdminToken string
+
+	// DatabaseURL is the PostgreSQL connection used by SQL-backed stores and sinks.
+	DatabaseURL string
+
+	// IdempotencyStore selects where /notify responses are kept for
+	// redeliveries: "memory" (default), "sql" or "none".
+	IdempotencyStore string
+	IdempotencyTTL   time.Duration
+	IdempotencyLease time.Duration
+
+	// CloudTasksMaxAttempts is the attempt after which a still-retryable task
+	// is acknowledged and dead-lettered. Zero (default) always leaves retries
+	// to the queue's retry policy.
+	CloudTasksMaxAttempts int
+	// DeadLetterSink selects where given-up tasks are written: "log" (default) or "sql".
+	DeadLetterSink string
+
+	// ChatWebhookEnabled enables the Slack/Discord-compatible webhook channel.
+	ChatWebhookEnabled bool
+	// ChatWebhookAllowedHosts restricts webhook URLs to these hosts. Empty
+	// uses the Slack and Discord webhook hosts.
+	ChatWebhookAllowedHosts []string
+
+	// BatchMaxItems caps the requests of a /notify/batch call.
+	BatchMaxItems int
+	// BatchConcurrency is the number of batch items sent at once, across all batch calls.
+	BatchConcurrency int
+
+	// JobStore selects where background /notify jobs are tracked: "memory"
+	// (default), "sql" or "none", which always sends synchronously.
+	JobStore string
+	JobTTL   time.Duration
+	// JobMaxEntries bounds the jobs the memory store keeps.
+	JobMaxEntries int
+	// JobWorkers is the number of background jobs sent at once; JobQueueSize
+	// more may wait before /notify turns them away.
+	JobWorkers   int
+	JobQueueSize int
+
+	// SchedulerHorizon is how far ahead send_at may be; later ones are
+	// rejected. Zero disables scheduling.
+	SchedulerHorizon time.Duration
+	// SchedulerStore selects where held requests are saved: "sql"
+	// (PostgreSQL, shared by every instance; default with DatabaseURL) or
+	// "none", which disables scheduling. No store is kept in memory, since
+	// held requests must outlive the instance that accepted them.
+	SchedulerStore string
+	// SchedulerMaxAttempts is the number of sends of a held request that
+	// keeps failing; the last one is dead-lettered like a Cloud Tasks task.
+	SchedulerMaxAttempts int
 }
 
 func Load() *Config {
@@ -24,7 +123,281 @@ func Load() *Config {
 		FirebaseProjectID: os.Getenv("FIREBASE_PROJECT_ID"),
 		WebAppBaseURL:     os.Getenv("WEB_APP_BASE_URL"),
 		LogLevel:          parseLogLevel(os.Getenv("LOG_LEVEL")),
+
+		RequestTimeout: parseDuration(os.Getenv("REQUEST_TIMEOUT"), 25*time.Second),
+
+		FCMRetryMaxAttempts:    parseInt(os.Getenv("FCM_RETRY_MAX_ATTEMPTS"), 3),
+		FCMRetryInitialBackoff: parseDuration(os.Getenv("FCM_RETRY_INITIAL_BACKOFF"), 500*time.Millisecond),
+		FCMRetryMaxBackoff:     parseDuration(os.Getenv("FCM_RETRY_MAX_BACKOFF"), 5*time.Second),
+		FCMBatchParallelism:    parseInt(os.Getenv("FCM_BATCH_PARALLELISM"), 4),
+
+		FCMRateLimit:        parseInt(os.Getenv("FCM_RATE_LIMIT"), 0),
+		FCMRateLimitBurst:   parseInt(os.Getenv("FCM_RATE_LIMIT_BURST"), 0),
+		FCMRateLimitMaxWait: parseDuration(os.Getenv("FCM_RATE_LIMIT_MAX_WAIT"), 5*time.Second),
+
+		FCMBreakerFailureThreshold: parseInt(os.Getenv("FCM_BREAKER_FAILURE_THRESHOLD"), 5),
+		FCMBreakerOpenTimeout:      parseDuration(os.Getenv("FCM_BREAKER_OPEN_TIMEOUT"), 30*time.Second),
+
+		FCMPlatforms: parsePlatformOptions(),
+
+		TaskLinkPatterns: parseLinkPatterns(os.Getenv("TASK_LINK_PATTERNS")),
+
+		WebPushVAPIDPrivateKey: os.Getenv("WEBPUSH_VAPID_PRIVATE_KEY"),
+		WebPushVAPIDSubject:    os.Getenv("WEBPUSH_VAPID_SUBJECT"),
+		WebPushAllowedHosts:    parseList(os.Getenv("WEBPUSH_ALLOWED_HOSTS")),
+
+		SMTPHost:     os.Getenv("SMTP_HOST"),
+		SMTPPort:     getEnv("SMTP_PORT", "587"),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:     os.Getenv("SMTP_FROM"),
+
+		AdminToken: os.Getenv("ADMIN_TOKEN"),
+
+		DatabaseURL: os.Getenv("DATABASE_URL"),
+
+		IdempotencyStore: getEnv("IDEMPOTENCY_STORE", "memory"),
+		IdempotencyTTL:   parseDuration(os.Getenv("IDEMPOTENCY_TTL"), 24*time.Hour),
+		IdempotencyLease: parseDuration(os.Getenv("IDEMPOTENCY_LEASE"), 2*time.Minute),
+
+		CloudTasksMaxAttempts: parseInt(os.Getenv("CLOUD_TASKS_MAX_ATTEMPTS"), 0),
+		DeadLetterSink:        getEnv("DEAD_LETTER_SINK", "log"),
+
+		ChatWebhookEnabled:      parseBool(os.Getenv("CHAT_WEBHOOK_ENABLED"), false),
+		ChatWebhookAllowedHosts: parseList(os.Getenv("CHAT_WEBHOOK_ALLOWED_HOSTS")),
+
+		BatchMaxItems:    parseInt(os.Getenv("BATCH_MAX_ITEMS"), 100),
+		BatchConcurrency: parseInt(os.Getenv("BATCH_CONCURRENCY"), 8),
+
+		JobStore:      getEnv("JOB_STORE", "memory"),
+		JobTTL:        parseDuration(os.Getenv("JOB_TTL"), 24*time.Hour),
+		JobMaxEntries: parseInt(os.Getenv("JOB_MAX_ENTRIES"), 1000),
+		JobWorkers:    parseInt(os.Getenv("JOB_WORKERS"), 4),
+		JobQueueSize:  parseInt(os.Getenv("JOB_QUEUE_SIZE"), 100),
+
+		SchedulerHorizon:     parseDuration(os.Getenv("SCHEDULER_HORIZON"), 15*time.Minute),
+		SchedulerStore:       getEnv("SCHEDULER_STORE", defaultSchedulerStore()),
+		SchedulerMaxAttempts: parseInt(os.Getenv("SCHEDULER_MAX_ATTEMPTS"), 5),
+	}
+}
+
+// defaultSchedulerStore holds scheduled sends only where they survive a
+// restart: in the database, when there is one.
+func defaultSchedulerStore() string {
+	if os.Getenv("DATABASE_URL") == "" {
+		return "none"
+	}
+	return "sql"
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
 	}
+
+	return fallback
+}
+
+// parseKeyValues parses "key=value,key=value" into a map, skipping malformed pairs.
+func parseKeyValues(value string) map[string]string {
+	result := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || key == "" {
+			continue
+		}
+		result[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+
+	return result
+}
+
+// taskTypes are the task types a "default" entry applies to.
+var taskTypes = []domain.Type{domain.TypeShort, domain.TypeNear, domain.TypeRelaxed, domain.TypeScheduled}
+
+// forEachTaskType calls apply with the values of a per-task-type setting
+// parsed by parseKeyValues: a "default" entry for every task type first,
+// then the entries of single task types, which override it. Entries of
+// unknown task types are skipped.
+func forEachTaskType(setting string, configured map[string]string, apply func(t domain.Type, value string)) {
+	if value, ok := configured["default"]; ok {
+		for _, t := range taskTypes {
+			apply(t, value)
+		}
+	}
+
+	for key, value := range configured {
+		if key == "default" {
+			continue
+		}
+
+		taskType, err := domain.NewType(key)
+		if err != nil {
+			slog.Warn("ignoring setting for unknown task type",
+				slog.String("setting", setting),
+				slog.String("task_type", key),
+			)
+
+			continue
+		}
+		apply(taskType, value)
+	}
+}
+
+// platformSettings are the FCM presentation settings, each applying its
+// value to the options of a task type.
+var platformSettings = []struct {
+	name  string
+	apply func(opts *fcm.PlatformOptions, value string) error
+}{
+	{name: "FCM_TTL", apply: func(opts *fcm.PlatformOptions, value string) error {
+		ttl, err := time.ParseDuration(value)
+		if err != nil || ttl < 0 {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		opts.TTL = ttl
+		return nil
+	}},
+	{name: "FCM_PRIORITY", apply: func(opts *fcm.PlatformOptions, value string) error {
+		if value != fcm.PriorityHigh && value != fcm.PriorityNormal {
+			return fmt.Errorf("unknown priority %q", value)
+		}
+		opts.Priority = value
+		return nil
+	}},
+	{name: "FCM_ANDROID_CHANNEL_ID", apply: func(opts *fcm.PlatformOptions, value string) error {
+		opts.AndroidChannelID = value
+		return nil
+	}},
+	{name: "FCM_SOUND", apply: func(opts *fcm.PlatformOptions, value string) error {
+		opts.Sound = value
+		return nil
+	}},
+	{name: "FCM_APNS_BADGE", apply: func(opts *fcm.PlatformOptions, value string) error {
+		if value == "" {
+			opts.Badge = nil
+			return nil
+		}
+		badge, err := strconv.Atoi(value)
+		if err != nil || badge < 0 {
+			return fmt.Errorf("invalid badge %q", value)
+		}
+		opts.Badge = &badge
+		return nil
+	}},
+	{name: "FCM_WEBPUSH_URGENCY", apply: func(opts *fcm.PlatformOptions, value string) error {
+		switch value {
+		case "very-low", fcm.WebpushUrgencyLow, fcm.WebpushUrgencyNormal, fcm.WebpushUrgencyHigh:
+			opts.WebpushUrgency = value
+			return nil
+		default:
+			return fmt.Errorf("unknown urgency %q", value)
+		}
+	}},
+}
+
+// parsePlatformOptions applies the platformSettings in the environment on
+// top of fcm.DefaultPlatformOptions, skipping invalid values.
+func parsePlatformOptions() map[domain.Type]fcm.PlatformOptions {
+	platforms := make(map[domain.Type]fcm.PlatformOptions)
+	for _, t := range taskTypes {
+		platforms[t] = fcm.DefaultPlatformOptions(t)
+	}
+
+	for _, setting := range platformSettings {
+		forEachTaskType(setting.name, parseKeyValues(os.Getenv(setting.name)), func(t domain.Type, value string) {
+			opts := platforms[t]
+			if err := setting.apply(&opts, value); err != nil {
+				slog.Warn("ignoring invalid platform setting",
+					slog.String("setting", setting.name),
+					slog.String("task_type", t.String()),
+					slog.String("error", err.Error()),
+				)
+
+				return
+			}
+			platforms[t] = opts
+		})
+	}
+
+	return platforms
+}
+
+// parseLinkPatterns parses the per-task-type link patterns, where a
+// "default" entry applies to every task type without its own pattern.
+func parseLinkPatterns(value string) map[domain.Type]string {
+	patterns := make(map[domain.Type]string)
+	forEachTaskType("TASK_LINK_PATTERNS", parseKeyValues(value), func(t domain.Type, pattern string) {
+		patterns[t] = pattern
+	})
+
+	return patterns
+}
+
+// parseList parses a comma-separated list, skipping empty entries.
+func parseList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
+
+func parseBool(value string, fallback bool) bool {
+	if value == "" {
+		return fallback
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		slog.Warn("invalid boolean configuration, using default",
+			slog.String("value", value),
+			slog.Bool("default", fallback),
+		)
+
+		return fallback
+	}
+
+	return b
+}
+
+func parseInt(value string, fallback int) int {
+	if value == "" {
+		return fallback
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Warn("invalid integer configuration, using default",
+			slog.String("value", value),
+			slog.Int("default", fallback),
+		)
+
+		return fallback
+	}
+
+	return n
+}
+
+func parseDuration(value string, fallback time.Duration) time.Duration {
+	if value == "" {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		slog.Warn("invalid duration configuration, using default",
+			slog.String("value", value),
+			slog.Duration("default", fallback),
+		)
+
+		return fallback
+	}
+
+	return d
 }
 
 func parseLogLevel(level string) slog.Level {
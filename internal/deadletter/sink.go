@@ -0,0 +1,68 @@
+// Package deadletter records tasks that ran out of delivery attempts so they
+// can be inspected or replayed after Cloud Tasks has stopped retrying them.
+package deadletter
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/model"
+)
+
+// Reasons a task is dead-lettered.
+const (
+	ReasonMaxAttempts = "max_attempts_exceeded"
+)
+
+// Entry is a task that was acknowledged without being delivered.
+type Entry struct {
+	TaskName string
+	TaskID   string
+	TaskType string
+	Reason   string
+	// Attempt is the attempt that gave up, counting from 1.
+	Attempt        int
+	ExecutionCount int
+	// Request is the request as JSON, so the task can be replayed through /notify.
+	Request []byte
+	Result  *model.BulkResult
+	Time    time.Time
+}
+
+// Sink stores dead-lettered tasks. A task is only acknowledged once Write
+// succeeds; on error it stays retryable.
+type Sink interface {
+	Write(ctx context.Context, entry Entry) error
+}
+
+// LogSink writes entries to the structured log, where a log-based sink can
+// route them. The request body is left out because it holds device tokens.
+type LogSink struct{}
+
+var _ Sink = LogSink{}
+
+// Write implements Sink.
+func (LogSink) Write(ctx context.Context, entry Entry) error {
+	errorCodes := make(map[string]int)
+	if entry.Result != nil {
+		for _, r := range entry.Result.Results {
+			if !r.Success {
+				errorCodes[r.ErrorCode.String()]++
+			}
+		}
+	}
+
+	slog.ErrorContext(ctx, "task dead-lettered",
+		"event", "notification.dead_letter",
+		"task_name", entry.TaskName,
+		"task_id", entry.TaskID,
+		"task_type", entry.TaskType,
+		"reason", entry.Reason,
+		"attempt", entry.Attempt,
+		"execution_count", entry.ExecutionCount,
+		"error_codes", errorCodes,
+	)
+
+	return nil
+}
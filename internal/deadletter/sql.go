@@ -0,0 +1,68 @@
+package deadletter
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SQLSink stores entries in the dead_letters table, including the request
+// body for replay. Call Migrate once before use to create the table.
+type SQLSink struct {
+	db *gorm.DB
+}
+
+// sqlEntry is a row of the dead_letters table.
+type sqlEntry struct {
+	ID             uint64 `gorm:"primaryKey"`
+	TaskName       string `gorm:"size:512;index"`
+	TaskID         string `gorm:"size:64;index"`
+	TaskType       string `gorm:"size:32"`
+	Reason         string `gorm:"size:64"`
+	Attempt        int
+	ExecutionCount int
+	Request        []byte
+	// Results is the JSON-encoded per-recipient results of the last attempt.
+	Results   []byte
+	CreatedAt time.Time `gorm:"index"`
+}
+
+func (sqlEntry) TableName() string {
+	return "dead_letters"
+}
+
+var _ Sink = (*SQLSink)(nil)
+
+func NewSQLSink(db *gorm.DB) *SQLSink {
+	return &SQLSink{db: db}
+}
+
+// Migrate creates or updates the dead_letters table.
+func (s *SQLSink) Migrate(ctx context.Context) error {
+	return s.db.WithContext(ctx).AutoMigrate(&sqlEntry{})
+}
+
+// Write implements Sink.
+func (s *SQLSink) Write(ctx context.Context, entry Entry) error {
+	row := sqlEntry{
+		TaskName:       entry.TaskName,
+		TaskID:         entry.TaskID,
+		TaskType:       entry.TaskType,
+		Reason:         entry.Reason,
+		Attempt:        entry.Attempt,
+		ExecutionCount: entry.ExecutionCount,
+		Request:        entry.Request,
+		CreatedAt:      entry.Time,
+	}
+	if entry.Result != nil {
+		results, err := json.Marshal(entry.Result.Results)
+		if err != nil {
+			return err
+		}
+		row.Results = results
+	}
+
+	return s.db.WithContext(ctx).Create(&row).Error
+}
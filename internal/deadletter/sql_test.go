@@ -0,0 +1,50 @@
+package deadletter
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/dbtest"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/model"
+)
+
+func TestSQLSink_Write(t *testing.T) {
+	db := dbtest.Open(t)
+	sink := NewSQLSink(db)
+	if err := sink.Migrate(context.Background()); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	entry := Entry{
+		TaskName:       "tasks/1",
+		TaskID:         "0193a4b2-7c1d-7e8f-9a0b-1c2d3e4f5a6b",
+		TaskType:       "short",
+		Reason:         ReasonMaxAttempts,
+		Attempt:        5,
+		ExecutionCount: 2,
+		Request:        []byte(`{"tokens":["a"]}`),
+		Result: &model.BulkResult{Results: []model.TokenResult{
+			{Token: "a", ErrorCode: domain.ErrorCodeUnavailable},
+		}},
+		Time: time.Unix(1_700_000_000, 0),
+	}
+	if err := sink.Write(context.Background(), entry); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var row sqlEntry
+	if err := db.Take(&row).Error; err != nil {
+		t.Fatalf("failed to read dead letter: %v", err)
+	}
+	if row.TaskName != entry.TaskName || row.Attempt != 5 || row.ExecutionCount != 2 || string(row.Request) != string(entry.Request) {
+		t.Errorf("unexpected row: %+v", row)
+	}
+
+	var results []model.TokenResult
+	if err := json.Unmarshal(row.Results, &results); err != nil || len(results) != 1 || results[0].ErrorCode != domain.ErrorCodeUnavailable {
+		t.Errorf("unexpected results %s: %v", row.Results, err)
+	}
+}
@@ -0,0 +1,35 @@
+package domain
+
+import "fmt"
+
+// Channel is a delivery channel a recipient is reached through.
+type Channel string
+
+const (
+	ChannelFCM     Channel = "fcm"
+	ChannelWebPush Channel = "webpush"
+	ChannelWebhook Channel = "webhook"
+)
+
+func (c Channel) String() string {
+	return string(c)
+}
+
+// Recipient is a single address on a delivery channel. The address format
+// depends on the channel: an FCM registration token, a JSON-serialised
+// PushSubscription for Web Push, or an incoming webhook URL for chat.
+type Recipient struct {
+	Channel Channel
+	Address string
+}
+
+func NewRecipient(channel Channel, address string) (Recipient, error) {
+	if channel == "" {
+		return Recipient{}, fmt.Errorf("%w: empty channel", ErrInvalidRecipient)
+	}
+	if address == "" {
+		return Recipient{}, fmt.Errorf("%w: empty address for channel %s", ErrInvalidRecipient, channel)
+	}
+
+	return Recipient{Channel: channel, Address: address}, nil
+}
@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"fmt"
+
+	notifyv1 "github.com/KasumiMercury/primind-notification-invoker/internal/gen/notify/v1"
+)
+
+func ProtoChannelToDomain(pc notifyv1.Channel) (Channel, error) {
+	switch pc {
+	case notifyv1.Channel_CHANNEL_FCM:
+		return ChannelFCM, nil
+	case notifyv1.Channel_CHANNEL_WEBPUSH:
+		return ChannelWebPush, nil
+	case notifyv1.Channel_CHANNEL_WEBHOOK:
+		return ChannelWebhook, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrInvalidChannel, pc.String())
+	}
+}
+
+func DomainChannelToProto(c Channel) notifyv1.Channel {
+	switch c {
+	case ChannelFCM:
+		return notifyv1.Channel_CHANNEL_FCM
+	case ChannelWebPush:
+		return notifyv1.Channel_CHANNEL_WEBPUSH
+	case ChannelWebhook:
+		return notifyv1.Channel_CHANNEL_WEBHOOK
+	default:
+		return notifyv1.Channel_CHANNEL_UNSPECIFIED
+	}
+}
+
+// ProtoRecipientsToDomain converts request recipients, validating each address.
+func ProtoRecipientsToDomain(recipients []*notifyv1.Recipient) ([]Recipient, error) {
+	result := make([]Recipient, len(recipients))
+	for i, r := range recipients {
+		channel, err := ProtoChannelToDomain(r.GetChannel())
+		if err != nil {
+			return nil, fmt.Errorf("recipient at index %d: %w", i, err)
+		}
+
+		recipient, err := NewRecipient(channel, r.GetAddress())
+		if err != nil {
+			return nil, fmt.Errorf("recipient at index %d: %w", i, err)
+		}
+		result[i] = recipient
+	}
+
+	return result, nil
+}
@@ -0,0 +1,15 @@
+package domain
+
+// DeliveryMode controls whether a reminder is displayed by FCM or handed to the client as data.
+type DeliveryMode string
+
+const (
+	// DeliveryModeNotification sends a notification that FCM displays on the device.
+	DeliveryModeNotification DeliveryMode = "notification"
+	// DeliveryModeDataOnly sends a data-only message that the client renders itself.
+	DeliveryModeDataOnly DeliveryMode = "data_only"
+)
+
+func (m DeliveryMode) String() string {
+	return string(m)
+}
@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"fmt"
+
+	notifyv1 "github.com/KasumiMercury/primind-notification-invoker/internal/gen/notify/v1"
+)
+
+// ProtoDeliveryModeToDomain converts a request delivery mode. An unspecified
+// mode falls back to DeliveryModeNotification.
+func ProtoDeliveryModeToDomain(pm notifyv1.DeliveryMode) (DeliveryMode, error) {
+	switch pm {
+	case notifyv1.DeliveryMode_DELIVERY_MODE_UNSPECIFIED, notifyv1.DeliveryMode_DELIVERY_MODE_NOTIFICATION:
+		return DeliveryModeNotification, nil
+	case notifyv1.DeliveryMode_DELIVERY_MODE_DATA_ONLY:
+		return DeliveryModeDataOnly, nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrInvalidDeliveryMode, pm.String())
+	}
+}
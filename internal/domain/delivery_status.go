@@ -0,0 +1,18 @@
+package domain
+
+// DeliveryStatus summarises how much of a bulk send reached the push service.
+type DeliveryStatus string
+
+const (
+	// DeliveryStatusComplete means every batch was handed to the push service.
+	// Individual tokens may still have failed.
+	DeliveryStatusComplete DeliveryStatus = "complete"
+	// DeliveryStatusPartial means some batches failed before reaching the push service.
+	DeliveryStatusPartial DeliveryStatus = "partial"
+	// DeliveryStatusFailed means no batch reached the push service.
+	DeliveryStatusFailed DeliveryStatus = "failed"
+)
+
+func (s DeliveryStatus) String() string {
+	return string(s)
+}
@@ -0,0 +1,43 @@
+package domain
+
+// ErrorCode classifies why a delivery to a single recipient failed.
+type ErrorCode string
+
+const (
+	ErrorCodeNone             ErrorCode = ""
+	ErrorCodeUnknown          ErrorCode = "unknown"
+	ErrorCodeInvalidArgument  ErrorCode = "invalid_argument"
+	ErrorCodeUnregistered     ErrorCode = "unregistered"
+	ErrorCodeSenderIDMismatch ErrorCode = "sender_id_mismatch"
+	ErrorCodeQuotaExceeded    ErrorCode = "quota_exceeded"
+	ErrorCodeUnavailable      ErrorCode = "unavailable"
+	ErrorCodeInternal         ErrorCode = "internal"
+	// ErrorCodeSubscriptionGone means a Web Push subscription has expired or
+	// was unsubscribed (HTTP 404 or 410 from the push service).
+	ErrorCodeSubscriptionGone ErrorCode = "subscription_gone"
+)
+
+// IsRetryable reports whether sending again may succeed.
+func (c ErrorCode) IsRetryable() bool {
+	switch c {
+	case ErrorCodeQuotaExceeded, ErrorCodeUnavailable, ErrorCodeInternal:
+		return true
+	default:
+		return false
+	}
+}
+
+// ShouldRemoveToken reports whether the recipient token is permanently invalid
+// and should be pruned by the caller.
+func (c ErrorCode) ShouldRemoveToken() bool {
+	switch c {
+	case ErrorCodeUnregistered, ErrorCodeSenderIDMismatch, ErrorCodeSubscriptionGone:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c ErrorCode) String() string {
+	return string(c)
+}
@@ -3,7 +3,13 @@ package domain
 import "errors"
 
 var (
-	ErrInvalidTaskType = errors.New("invalid task type")
-	ErrInvalidTaskID   = errors.New("invalid task id")
-	ErrInvalidToken    = errors.New("invalid fcm token")
+	ErrInvalidTaskType     = errors.New("invalid task type")
+	ErrInvalidTaskID       = errors.New("invalid task id")
+	ErrInvalidToken        = errors.New("invalid fcm token")
+	ErrInvalidTopic        = errors.New("invalid topic")
+	ErrInvalidCondition    = errors.New("invalid condition")
+	ErrInvalidTarget       = errors.New("invalid notification target")
+	ErrInvalidChannel      = errors.New("invalid channel")
+	ErrInvalidRecipient    = errors.New("invalid recipient")
+	ErrInvalidDeliveryMode = errors.New("invalid delivery mode")
 )
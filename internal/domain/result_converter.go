@@ -0,0 +1,41 @@
+package domain
+
+import (
+	notifyv1 "github.com/KasumiMercury/primind-notification-invoker/internal/gen/notify/v1"
+)
+
+func DomainErrorCodeToProto(c ErrorCode) notifyv1.ErrorCode {
+	switch c {
+	case ErrorCodeNone:
+		return notifyv1.ErrorCode_ERROR_CODE_UNSPECIFIED
+	case ErrorCodeInvalidArgument:
+		return notifyv1.ErrorCode_ERROR_CODE_INVALID_ARGUMENT
+	case ErrorCodeUnregistered:
+		return notifyv1.ErrorCode_ERROR_CODE_UNREGISTERED
+	case ErrorCodeSenderIDMismatch:
+		return notifyv1.ErrorCode_ERROR_CODE_SENDER_ID_MISMATCH
+	case ErrorCodeQuotaExceeded:
+		return notifyv1.ErrorCode_ERROR_CODE_QUOTA_EXCEEDED
+	case ErrorCodeUnavailable:
+		return notifyv1.ErrorCode_ERROR_CODE_UNAVAILABLE
+	case ErrorCodeInternal:
+		return notifyv1.ErrorCode_ERROR_CODE_INTERNAL
+	case ErrorCodeSubscriptionGone:
+		return notifyv1.ErrorCode_ERROR_CODE_SUBSCRIPTION_GONE
+	default:
+		return notifyv1.ErrorCode_ERROR_CODE_UNKNOWN
+	}
+}
+
+func DomainDeliveryStatusToProto(s DeliveryStatus) notifyv1.DeliveryStatus {
+	switch s {
+	case DeliveryStatusComplete:
+		return notifyv1.DeliveryStatus_DELIVERY_STATUS_COMPLETE
+	case DeliveryStatusPartial:
+		return notifyv1.DeliveryStatus_DELIVERY_STATUS_PARTIAL
+	case DeliveryStatusFailed:
+		return notifyv1.DeliveryStatus_DELIVERY_STATUS_FAILED
+	default:
+		return notifyv1.DeliveryStatus_DELIVERY_STATUS_UNSPECIFIED
+	}
+}
@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const topicPrefix = "/topics/"
+
+var topicPattern = regexp.MustCompile(`^[a-zA-Z0-9-_.~%]+$`)
+
+// Topic is an FCM topic name without the "/topics/" prefix.
+type Topic string
+
+func NewTopic(topic string) (Topic, error) {
+	name := strings.TrimPrefix(topic, topicPrefix)
+	if name == "" {
+		return "", fmt.Errorf("%w: empty topic", ErrInvalidTopic)
+	}
+	if !topicPattern.MatchString(name) {
+		return "", fmt.Errorf("%w: %s", ErrInvalidTopic, topic)
+	}
+	return Topic(name), nil
+}
+
+func (t Topic) String() string {
+	return string(t)
+}
+
+// Condition is an FCM topic condition expression, e.g. "'a' in topics && 'b' in topics".
+// Its syntax is validated by FCM.
+type Condition string
+
+func NewCondition(condition string) (Condition, error) {
+	if strings.TrimSpace(condition) == "" {
+		return "", fmt.Errorf("%w: empty condition", ErrInvalidCondition)
+	}
+	return Condition(condition), nil
+}
+
+func (c Condition) String() string {
+	return string(c)
+}
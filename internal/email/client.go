@@ -0,0 +1,160 @@
+// Package email sends reminders by email over SMTP, as a fallback for
+// recipients no push channel could reach.
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/channel"
+)
+
+type Config struct {
+	Host string
+	Port string
+	// Username and Password enable SMTP AUTH PLAIN. net/smtp only sends them
+	// over TLS or to localhost.
+	Username string
+	Password string
+	From     string
+	Renderer channel.Renderer
+	// Timeout bounds a whole send when the context has no earlier deadline. Zero uses channel.DefaultTimeout.
+	Timeout time.Duration
+}
+
+type Client struct {
+	addr     string
+	host     string
+	auth     smtp.Auth
+	from     *mail.Address
+	renderer channel.Renderer
+	timeout  time.Duration
+}
+
+func NewClient(cfg Config) (*Client, error) {
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender address: %w", err)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = channel.DefaultTimeout
+	}
+
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	return &Client{
+		addr:     net.JoinHostPort(cfg.Host, cfg.Port),
+		host:     cfg.Host,
+		auth:     auth,
+		from:     from,
+		renderer: cfg.Renderer,
+		timeout:  cfg.Timeout,
+	}, nil
+}
+
+// SendFallback emails the rendered reminder to a single address.
+func (c *Client) SendFallback(ctx context.Context, to string, reminder channel.Reminder) error {
+	recipient, err := mail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("invalid recipient address: %w", err)
+	}
+
+	message := c.compose(recipient, c.renderer.Content(reminder), time.Now())
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	return c.send(ctx, recipient.Address, message)
+}
+
+func (c *Client) send(ctx context.Context, to string, message []byte) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// net/smtp has no context support; the deadline covers the whole conversation.
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+
+	client, err := smtp.NewClient(conn, c.host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: c.host}); err != nil {
+			return err
+		}
+	}
+	if c.auth != nil {
+		if err := client.Auth(c.auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(c.from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// compose builds a text/plain message. Header text is MIME-encoded and the
+// body base64-encoded, since reminder templates are not ASCII.
+func (c *Client) compose(to *mail.Address, content channel.Content, now time.Time) []byte {
+	body := content.Body
+	if content.Link != "" {
+		body += "\r\n\r\n" + content.Link
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", c.from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", to.String())
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", content.Title))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n")
+	buf.WriteString("\r\n")
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(body))
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded + "\r\n")
+
+	return buf.Bytes()
+}
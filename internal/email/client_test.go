@@ -0,0 +1,186 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/channel"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/channel/channeltest"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+)
+
+type smtpMessage struct {
+	from string
+	to   []string
+	data string
+}
+
+// startSMTPServer runs a minimal SMTP stand-in that accepts every message.
+// Recipients listed in reject are refused at RCPT TO.
+func startSMTPServer(t *testing.T, reject map[string]bool) (host, port string, messages <-chan smtpMessage) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan smtpMessage, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(conn, reject, received)
+		}
+	}()
+
+	host, port, _ = net.SplitHostPort(listener.Addr().String())
+	return host, port, received
+}
+
+func serveSMTP(conn net.Conn, reject map[string]bool, received chan<- smtpMessage) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = io.WriteString(conn, line+"\r\n") }
+
+	reply("220 localhost ESMTP stand-in")
+	var msg smtpMessage
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command := strings.ToUpper(line)
+
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			msg.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+			reply("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			to := strings.Trim(line[len("RCPT TO:"):], "<>")
+			if reject[to] {
+				reply("550 no such user")
+				continue
+			}
+			msg.to = append(msg.to, to)
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 end with .")
+			var data strings.Builder
+			for {
+				dataLine, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			msg.data = data.String()
+			received <- msg
+			msg = smtpMessage{}
+			reply("250 queued")
+		case command == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func newTestClient(t *testing.T, host, port string) *Client {
+	t.Helper()
+
+	client, err := NewClient(Config{
+		Host:     host,
+		Port:     port,
+		From:     "Primind <noreply@example.com>",
+		Renderer: channeltest.Renderer{},
+		Timeout:  5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return client
+}
+
+func TestSendFallback(t *testing.T) {
+	host, port, messages := startSMTPServer(t, nil)
+	client := newTestClient(t, host, port)
+
+	if err := client.SendFallback(context.Background(), "user@example.com", channel.Reminder{TaskType: domain.TypeScheduled}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var msg smtpMessage
+	select {
+	case msg = <-messages:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message received")
+	}
+
+	if msg.from != "noreply@example.com" || len(msg.to) != 1 || msg.to[0] != "user@example.com" {
+		t.Errorf("unexpected envelope: from=%q to=%v", msg.from, msg.to)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(msg.data))
+	if err != nil {
+		t.Fatalf("failed to parse message: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != channeltest.Content.Title {
+		t.Errorf("unexpected subject %q: %v", subject, err)
+	}
+
+	body, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, parsed.Body))
+	if err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	if string(body) != channeltest.Content.Body+"\r\n\r\n"+channeltest.Content.Link {
+		t.Errorf("unexpected body: %q", body)
+	}
+}
+
+func TestSendFallback_Errors(t *testing.T) {
+	host, port, _ := startSMTPServer(t, map[string]bool{"gone@example.com": true})
+	client := newTestClient(t, host, port)
+
+	tests := []struct {
+		name string
+		to   string
+	}{
+		{name: "invalid address", to: "not an address"},
+		{name: "rejected recipient", to: "gone@example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := client.SendFallback(context.Background(), tt.to, channel.Reminder{}); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestNewClient_InvalidFrom(t *testing.T) {
+	if _, err := NewClient(Config{Host: "localhost", Port: "25", From: "nobody"}); err == nil {
+		t.Error("expected an error for an invalid sender address")
+	}
+}
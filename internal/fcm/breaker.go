@@ -0,0 +1,188 @@
+package fcm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+)
+
+const (
+	defaultBreakerFailureThreshold = 5
+	defaultBreakerOpenTimeout      = 30 * time.Second
+)
+
+// BreakerState is the state of the FCM circuit breaker.
+type BreakerState string
+
+const (
+	// BreakerClosed lets every send through.
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen rejects every send until the open timeout has passed.
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets a single probe through to decide whether FCM has recovered.
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+func (s BreakerState) String() string {
+	return string(s)
+}
+
+// ErrCircuitOpen is returned instead of sending while the breaker is open.
+// It is classified as unavailable, so the request stays retryable.
+var ErrCircuitOpen = errors.New("FCM circuit breaker is open")
+
+type BreakerConfig struct {
+	// FailureThreshold is the number of consecutive transport failures that
+	// opens the breaker. Zero uses defaultBreakerFailureThreshold.
+	FailureThreshold int
+	// OpenTimeout is how long the breaker stays open before probing FCM again.
+	// Zero uses defaultBreakerOpenTimeout.
+	OpenTimeout time.Duration
+}
+
+// breaker opens after consecutive transport-level failures so that requests
+// fail fast while FCM is degraded instead of each waiting out its timeout.
+// Per-token errors in a successful response do not count: FCM answered.
+type breaker struct {
+	mu    sync.Mutex
+	state BreakerState
+	// generation changes on every state transition, so that outcomes of sends
+	// allowed in an earlier state do not move the current one.
+	generation  uint64
+	failures    int
+	openedAt    time.Time
+	probing     bool
+	threshold   int
+	openTimeout time.Duration
+	now         func() time.Time
+}
+
+// breakerTicket identifies an allowed send to record or release.
+type breakerTicket struct {
+	generation uint64
+	// probe marks the single send allowed while half-open; only its outcome
+	// closes or reopens the breaker.
+	probe bool
+}
+
+func newBreaker(cfg BreakerConfig) *breaker {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = defaultBreakerFailureThreshold
+	}
+	if cfg.OpenTimeout <= 0 {
+		cfg.OpenTimeout = defaultBreakerOpenTimeout
+	}
+
+	return &breaker{
+		state:       BreakerClosed,
+		threshold:   cfg.FailureThreshold,
+		openTimeout: cfg.OpenTimeout,
+		now:         time.Now,
+	}
+}
+
+// allow reports whether a send may go to FCM. Every allowed send must be
+// followed by a call to record or release with the returned ticket.
+func (b *breaker) allow() (breakerTicket, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance()
+
+	ticket := breakerTicket{generation: b.generation}
+	switch b.state {
+	case BreakerOpen:
+		return ticket, ErrCircuitOpen
+	case BreakerHalfOpen:
+		if b.probing {
+			return ticket, ErrCircuitOpen
+		}
+		b.probing = true
+		ticket.probe = true
+	}
+
+	return ticket, nil
+}
+
+// record reports the outcome of an allowed send. err is the error of the
+// call as a whole; nil means FCM answered. Outcomes of sends allowed before
+// the last transition are ignored.
+func (b *breaker) record(ticket breakerTicket, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ticket.generation != b.generation {
+		return
+	}
+	if ticket.probe {
+		b.probing = false
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		// The caller went away or ran out of time before FCM answered; that
+		// says nothing about FCM.
+	case isTransportFailure(err):
+		b.failures++
+		if ticket.probe || b.failures >= b.threshold {
+			b.transition(BreakerOpen)
+			b.openedAt = b.now()
+		}
+	default:
+		b.failures = 0
+		if b.state != BreakerClosed {
+			b.transition(BreakerClosed)
+		}
+	}
+}
+
+// release gives back an allowed send that never reached FCM.
+func (b *breaker) release(ticket breakerTicket) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ticket.probe && ticket.generation == b.generation {
+		b.probing = false
+	}
+}
+
+// currentState returns the state, moving an expired open breaker to half-open.
+func (b *breaker) currentState() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.advance()
+
+	return b.state
+}
+
+func (b *breaker) advance() {
+	if b.state == BreakerOpen && b.now().Sub(b.openedAt) >= b.openTimeout {
+		b.transition(BreakerHalfOpen)
+	}
+}
+
+func (b *breaker) transition(state BreakerState) {
+	b.state = state
+	b.generation++
+	b.probing = false
+}
+
+// isTransportFailure reports whether err means FCM could not be reached or
+// could not serve the request. Client-side cancellation, request deadlines
+// and errors about the message or target itself do not count.
+func isTransportFailure(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	switch classifyError(err) {
+	case domain.ErrorCodeUnavailable, domain.ErrorCodeInternal, domain.ErrorCodeUnknown:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,147 @@
+package fcm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/fcm/fcmtest"
+)
+
+func TestBreaker_Transitions(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newBreaker(BreakerConfig{FailureThreshold: 2, OpenTimeout: time.Minute})
+	b.now = func() time.Time { return now }
+
+	transportErr := errors.New("connection reset")
+	step := func(err error) {
+		t.Helper()
+		ticket, allowErr := b.allow()
+		if allowErr != nil {
+			t.Fatalf("expected the send to be allowed in state %s", b.currentState())
+		}
+		b.record(ticket, err)
+	}
+
+	step(transportErr)
+	if b.currentState() != BreakerClosed {
+		t.Fatalf("expected closed below the threshold, got %s", b.currentState())
+	}
+	step(&SendError{Code: domain.ErrorCodeInvalidArgument, Message: "invalid"})
+	step(transportErr)
+	if b.currentState() != BreakerClosed {
+		t.Fatal("expected an FCM answer to reset the failure count")
+	}
+
+	step(transportErr)
+	if _, err := b.allow(); b.currentState() != BreakerOpen || !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected open after consecutive failures, got %s", b.currentState())
+	}
+
+	now = now.Add(time.Minute)
+	if b.currentState() != BreakerHalfOpen {
+		t.Fatalf("expected half-open after the timeout, got %s", b.currentState())
+	}
+	probe, err := b.allow()
+	if err != nil {
+		t.Fatalf("expected the probe to be allowed, got %v", err)
+	}
+	if _, err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatal("expected a single probe while half-open")
+	}
+	b.record(probe, transportErr)
+	if b.currentState() != BreakerOpen {
+		t.Fatalf("expected a failed probe to reopen, got %s", b.currentState())
+	}
+
+	now = now.Add(time.Minute)
+	step(nil)
+	if b.currentState() != BreakerClosed {
+		t.Fatalf("expected a successful probe to close, got %s", b.currentState())
+	}
+}
+
+func TestBreaker_OnlyTheProbeDecidesHalfOpen(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newBreaker(BreakerConfig{FailureThreshold: 1, OpenTimeout: time.Minute})
+	b.now = func() time.Time { return now }
+
+	slow, err := b.allow()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	failing, _ := b.allow()
+	b.record(failing, errors.New("connection reset"))
+
+	now = now.Add(time.Minute)
+	probe, err := b.allow()
+	if err != nil {
+		t.Fatalf("expected the probe to be allowed, got %v", err)
+	}
+
+	// A send allowed while closed finishes during the probe.
+	b.record(slow, nil)
+	if b.currentState() != BreakerHalfOpen {
+		t.Fatalf("expected a stale success not to close the breaker, got %s", b.currentState())
+	}
+	if _, err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatal("expected the probe to stay outstanding after a stale outcome")
+	}
+
+	b.record(probe, errors.New("connection reset"))
+	if b.currentState() != BreakerOpen {
+		t.Fatalf("expected the failed probe to reopen, got %s", b.currentState())
+	}
+}
+
+func TestBreaker_IgnoresCancellation(t *testing.T) {
+	for _, cause := range []error{context.Canceled, context.DeadlineExceeded} {
+		t.Run(cause.Error(), func(t *testing.T) {
+			b := newBreaker(BreakerConfig{FailureThreshold: 1})
+
+			ticket, err := b.allow()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			b.record(ticket, fmt.Errorf("send: %w", cause))
+
+			if b.currentState() != BreakerClosed {
+				t.Errorf("expected a send that ended with %v not to count, got %s", cause, b.currentState())
+			}
+		})
+	}
+}
+
+func TestSendBulkNotification_CircuitOpen(t *testing.T) {
+	sender := fcmtest.NewSender()
+	sender.FailBatch(errors.New("connection reset"))
+	client := NewClientWithSender(sender, Config{Breaker: BreakerConfig{FailureThreshold: 2}})
+
+	for range 2 {
+		if _, err := client.SendBulkNotification(context.Background(), newTokens(1), testTaskID, domain.TypeShort, "", domain.DeliveryModeNotification); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if client.CircuitState() != "open" || client.DryRun().CircuitState() != "open" {
+		t.Fatalf("expected the breaker to be open, got %s", client.CircuitState())
+	}
+
+	result, err := client.SendBulkNotification(context.Background(), newTokens(1), testTaskID, domain.TypeShort, "", domain.DeliveryModeNotification)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(sender.Messages()) != 2 {
+		t.Errorf("expected the open breaker to fail fast, got %d sends", len(sender.Messages()))
+	}
+	if !result.Retryable() || result.Results[0].ErrorCode != domain.ErrorCodeUnavailable || result.RetryAfter != 0 {
+		t.Errorf("expected a retryable unavailable result, got %+v", result)
+	}
+
+	topicResult := client.SendToTopic(context.Background(), "team", testTaskID, domain.TypeShort, "", domain.DeliveryModeNotification)
+	if topicResult.Results[0].Attempts != 1 || len(sender.SentMessages()) != 0 {
+		t.Errorf("expected the topic send to fail fast without retries, got %+v", topicResult.Results[0])
+	}
+}
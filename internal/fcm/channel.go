@@ -0,0 +1,57 @@
+package fcm
+
+import (
+	"context"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/channel"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/model"
+)
+
+var _ channel.Channel = (*Client)(nil)
+
+// Deliver implements channel.Channel. Addresses are FCM registration tokens.
+func (c *Client) Deliver(ctx context.Context, addresses []string, reminder channel.Reminder) (*model.BulkResult, error) {
+	tokens, err := domain.NewFCMTokens(addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	client := c
+	if reminder.DryRun {
+		client = c.DryRun()
+	}
+
+	return client.SendBulkNotification(ctx, tokens, reminder.TaskID, reminder.TaskType, reminder.Color, reminder.Mode)
+}
+
+// Content renders reminder with the same template, icon and link as an FCM
+// notification, for channels that deliver it outside FCM.
+func (c *Client) Content(reminder channel.Reminder) channel.Content {
+	template := getTemplate(reminder.TaskType)
+
+	return channel.Content{
+		Title: template.Title,
+		Body:  template.Body,
+		Icon:  c.iconURL(reminder.TaskType, reminder.Color),
+		Link:  buildLink(c.webAppBaseURL, c.linkPattern(reminder.TaskType), reminder.TaskID, reminder.TaskType),
+	}
+}
+
+// DataPayload renders reminder into the versioned data payload of a data-only message.
+func (c *Client) DataPayload(reminder channel.Reminder) map[string]string {
+	content := c.Content(reminder)
+
+	return dataPayload(
+		NotificationTemplate{Title: content.Title, Body: content.Body},
+		reminder.TaskID,
+		reminder.TaskType,
+		content.Icon,
+		content.Link,
+	)
+}
+
+// WebpushHeaders returns the TTL and Urgency headers configured for a task type.
+func (c *Client) WebpushHeaders(taskType domain.Type) map[string]string {
+	return webpushHeaders(c.platformOptions(taskType))
+}
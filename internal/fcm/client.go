@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log/slog"
 	"strings"
+	"sync"
+	"time"
 
 	firebase "firebase.google.com/go/v4"
 	"firebase.google.com/go/v4/messaging"
@@ -12,27 +14,71 @@ import (
 	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
 	"github.com/KasumiMercury/primind-notification-invoker/internal/fcm/templates"
 	"github.com/KasumiMercury/primind-notification-invoker/internal/model"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/progress"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/retry"
 )
 
-const maxTokensPerBatch = 500
+const (
+	maxTokensPerBatch  = 500
+	defaultParallelism = 4
+)
 
 type NotificationTemplate struct {
 	Title string
 	Body  string
 }
 
+// Sender is the subset of the FCM API used by Client.
+// *messaging.Client satisfies it; tests substitute an in-memory implementation.
+type Sender interface {
+	// SendEachForMulticast delivers a message to up to 500 tokens and reports a response per token.
+	SendEachForMulticast(ctx context.Context, message *messaging.MulticastMessage) (*messaging.BatchResponse, error)
+	// SendEachForMulticastDryRun validates a multicast message with FCM without delivering it.
+	SendEachForMulticastDryRun(ctx context.Context, message *messaging.MulticastMessage) (*messaging.BatchResponse, error)
+	// Send delivers a message to a single token, topic or condition.
+	Send(ctx context.Context, message *messaging.Message) (string, error)
+	// SendDryRun validates a single-target message with FCM without delivering it.
+	SendDryRun(ctx context.Context, message *messaging.Message) (string, error)
+	SubscribeToTopic(ctx context.Context, tokens []string, topic string) (*messaging.TopicManagementResponse, error)
+	UnsubscribeFromTopic(ctx context.Context, tokens []string, topic string) (*messaging.TopicManagementResponse, error)
+}
+
+type Config struct {
+	ProjectID     string
+	WebAppBaseURL string
+	// Retry bounds re-sends of retryable per-token failures. Zero value uses retry.DefaultPolicy.
+	Retry retry.Policy
+	// Parallelism is the number of batches sent concurrently. Zero uses defaultParallelism.
+	Parallelism int
+	// Platforms overrides DefaultPlatformOptions per task type.
+	Platforms map[domain.Type]PlatformOptions
+	// LinkPatterns overrides DefaultLinkPattern per task type.
+	LinkPatterns map[domain.Type]string
+	// RateLimiter bounds the project-wide send rate. Nil disables limiting.
+	RateLimiter *RateLimiter
+	// Breaker configures the circuit breaker that fails sends fast while FCM is unreachable.
+	Breaker BreakerConfig
+}
+
 type Client struct {
-	messagingClient *messaging.Client
-	webAppBaseURL   string
+	sender        Sender
+	webAppBaseURL string
+	retry         retry.Policy
+	parallelism   int
+	platforms     map[domain.Type]PlatformOptions
+	linkPatterns  map[domain.Type]string
+	rateLimiter   *RateLimiter
+	breaker       *breaker
+	dryRun        bool
 }
 
-func NewClient(ctx context.Context, projectID, webAppBaseURL string) (*Client, error) {
-	config := &firebase.Config{}
-	if projectID != "" {
-		config.ProjectID = projectID
+func NewClient(ctx context.Context, cfg Config) (*Client, error) {
+	fbConfig := &firebase.Config{}
+	if cfg.ProjectID != "" {
+		fbConfig.ProjectID = cfg.ProjectID
 	}
 
-	app, err := firebase.NewApp(ctx, config)
+	app, err := firebase.NewApp(ctx, fbConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -42,123 +88,296 @@ func NewClient(ctx context.Context, projectID, webAppBaseURL string) (*Client, e
 		return nil, err
 	}
 
+	return NewClientWithSender(msgClient, cfg), nil
+}
+
+// NewClientWithSender creates a client that delivers through the given Sender.
+func NewClientWithSender(sender Sender, cfg Config) *Client {
+	if cfg.Retry.MaxAttempts == 0 {
+		cfg.Retry = retry.DefaultPolicy()
+	}
+	if cfg.Parallelism <= 0 {
+		cfg.Parallelism = defaultParallelism
+	}
+
 	return &Client{
-		messagingClient: msgClient,
-		webAppBaseURL:   webAppBaseURL,
-	}, nil
+		sender:        sender,
+		webAppBaseURL: cfg.WebAppBaseURL,
+		retry:         cfg.Retry,
+		parallelism:   cfg.Parallelism,
+		platforms:     cfg.Platforms,
+		linkPatterns:  cfg.LinkPatterns,
+		rateLimiter:   cfg.RateLimiter,
+		breaker:       newBreaker(cfg.Breaker),
+	}
 }
 
-type BulkResult struct {
-	Total        int
-	SuccessCount int
-	FailureCount int
-	Results      []model.TokenResult
+// DryRun returns a client that sends in validate-only mode: FCM checks every
+// message and token as usual but delivers nothing to devices.
+func (c *Client) DryRun() *Client {
+	dryRun := *c
+	dryRun.dryRun = true
+
+	return &dryRun
 }
 
-func (c *Client) SendBulkNotification(ctx context.Context, tokens []domain.FCMToken, taskID domain.TaskID, taskType domain.Type, color string) (*BulkResult, error) {
-	if len(tokens) <= maxTokensPerBatch {
-		return c.sendBatch(ctx, tokens, taskID, taskType, color)
-	}
+// IsDryRun reports whether the client only validates messages.
+func (c *Client) IsDryRun() bool {
+	return c.dryRun
+}
 
-	slog.Debug("splitting tokens into batches",
-		"total_tokens", len(tokens),
-		"max_per_batch", maxTokensPerBatch,
-	)
+// CircuitState reports the circuit breaker state: "closed", "open" or
+// "half_open". DryRun copies share the breaker of their client.
+func (c *Client) CircuitState() string {
+	return c.breaker.currentState().String()
+}
 
-	var allResults []model.TokenResult
-	successCount, failureCount := 0, 0
+// SendBulkNotification sends to all tokens in batches of maxTokensPerBatch.
+// A batch that fails as a whole does not abort the others: its tokens are
+// marked as failed and the result status becomes partial or failed.
+func (c *Client) SendBulkNotification(ctx context.Context, tokens []domain.FCMToken, taskID domain.TaskID, taskType domain.Type, color string, mode domain.DeliveryMode) (*model.BulkResult, error) {
+	// No batch failed when there is none to send.
+	if len(tokens) == 0 {
+		return &model.BulkResult{Status: domain.DeliveryStatusComplete}, nil
+	}
 
+	var batches [][]domain.FCMToken
 	for i := 0; i < len(tokens); i += maxTokensPerBatch {
-		end := i + maxTokensPerBatch
-		if end > len(tokens) {
-			end = len(tokens)
-		}
-		batch := tokens[i:end]
-		batchNum := (i / maxTokensPerBatch) + 1
+		end := min(i+maxTokensPerBatch, len(tokens))
+		batches = append(batches, tokens[i:end])
+	}
 
-		slog.Debug("sending batch", "batch_number", batchNum, "batch_size", len(batch))
+	if len(batches) > 1 {
+		slog.Debug("splitting tokens into batches",
+			"total_tokens", len(tokens),
+			"max_per_batch", maxTokensPerBatch,
+		)
+	}
 
-		result, err := c.sendBatch(ctx, batch, taskID, taskType, color)
-		if err != nil {
-			slog.Error("batch send failed", "batch_number", batchNum, "error", err)
-			return nil, err
+	batchResults := make([]*model.BulkResult, len(batches))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(c.parallelism, len(batches)) {
+		wg.Go(func() {
+			for i := range jobs {
+				batchNum := i + 1
+
+				slog.Debug("sending batch", "batch_number", batchNum, "batch_size", len(batches[i]))
+
+				result, err := c.sendBatch(ctx, batches[i], taskID, taskType, color, mode)
+				progress.Report(ctx, len(batches[i]))
+				if err != nil {
+					slog.Error("batch send failed", "batch_number", batchNum, "error", err)
+					batchResults[i] = failedBatch(batches[i], err)
+					continue
+				}
+				batchResults[i] = result
+
+				slog.Debug("batch completed",
+					"batch_number", batchNum,
+					"success_count", result.SuccessCount,
+					"failure_count", result.FailureCount,
+				)
+			}
+		})
+	}
+	for i := range batches {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	allResults := make([]model.TokenResult, 0, len(tokens))
+	successCount, failureCount, failedBatches := 0, 0, 0
+	var retryAfter time.Duration
+
+	for _, result := range batchResults {
+		if result.Status == domain.DeliveryStatusFailed {
+			failedBatches++
 		}
+		retryAfter = max(retryAfter, result.RetryAfter)
 
 		allResults = append(allResults, result.Results...)
 		successCount += result.SuccessCount
 		failureCount += result.FailureCount
+	}
 
-		slog.Debug("batch completed",
-			"batch_number", batchNum,
-			"success_count", result.SuccessCount,
-			"failure_count", result.FailureCount,
-		)
+	status := domain.DeliveryStatusComplete
+	switch {
+	case failedBatches == len(batches):
+		status = domain.DeliveryStatusFailed
+	case failedBatches > 0:
+		status = domain.DeliveryStatusPartial
 	}
 
-	return &BulkResult{
+	return &model.BulkResult{
 		Total:        len(tokens),
 		SuccessCount: successCount,
 		FailureCount: failureCount,
+		Status:       status,
 		Results:      allResults,
+		RetryAfter:   retryAfter,
 	}, nil
 }
 
-func (c *Client) sendBatch(ctx context.Context, tokens []domain.FCMToken, taskID domain.TaskID, taskType domain.Type, color string) (*BulkResult, error) {
-	template := getTemplate(taskType)
-	tokenStrings := domain.ToStrings(tokens)
-
-	notification := &messaging.Notification{
-		Title: template.Title,
-		Body:  template.Body,
+// failedBatch marks every token of a batch that never reached FCM as failed.
+// Errors that carry no FCM error code are treated as unavailable: the tokens
+// themselves are not at fault.
+func failedBatch(tokens []domain.FCMToken, err error) *model.BulkResult {
+	code := classifyError(err)
+	if code == domain.ErrorCodeUnknown {
+		code = domain.ErrorCodeUnavailable
 	}
 
-	message := &messaging.MulticastMessage{
-		Data: map[string]string{
-			"task_id":   taskID.String(),
-			"task_type": taskType.String(),
-		},
-		Notification: notification,
-		Tokens:       tokenStrings,
+	results := make([]model.TokenResult, len(tokens))
+	for i, token := range tokens {
+		results[i] = model.TokenResult{
+			Token:             token.String(),
+			Channel:           domain.ChannelFCM,
+			Error:             err.Error(),
+			ErrorCode:         code,
+			ShouldRemoveToken: code.ShouldRemoveToken(),
+			Attempts:          1,
+			NotSent:           rejectedLocally(err),
+		}
 	}
 
-	// Add icon URL if web app base URL is configured and color is provided
-	if c.webAppBaseURL != "" && color != "" {
-		iconURL := buildIconURL(c.webAppBaseURL, taskType, color)
-		message.Webpush.Notification.Icon = iconURL
-		message.Android.Notification.Icon = iconURL
-		slog.Debug("notification icon URL set", "icon_url", iconURL)
+	return &model.BulkResult{
+		Total:        len(tokens),
+		FailureCount: len(tokens),
+		Status:       domain.DeliveryStatusFailed,
+		Results:      results,
+		RetryAfter:   retryAfter(err),
 	}
+}
 
-	response, err := c.messagingClient.SendEachForMulticast(ctx, message)
-	if err != nil {
-		slog.Error("FCM multicast send failed", "error", err, "token_count", len(tokens))
-		return nil, err
-	}
+func (c *Client) sendBatch(ctx context.Context, tokens []domain.FCMToken, taskID domain.TaskID, taskType domain.Type, color string, mode domain.DeliveryMode) (*model.BulkResult, error) {
+	template := getTemplate(taskType)
+	tokenStrings := domain.ToStrings(tokens)
+
+	message := c.buildMessage(template, taskID, taskType, c.iconURL(taskType, color), mode)
 
 	results := make([]model.TokenResult, len(tokens))
-	for i, resp := range response.Responses {
-		results[i] = model.TokenResult{
-			Token:     tokenStrings[i],
-			Success:   resp.Success,
-			MessageID: resp.MessageID,
+	pending := make([]int, len(tokens))
+	for i := range tokens {
+		pending[i] = i
+	}
+
+	for attempt := 1; len(pending) > 0; attempt++ {
+		attemptMessage := *message
+		attemptMessage.Tokens = make([]string, len(pending))
+		for i, idx := range pending {
+			attemptMessage.Tokens[i] = tokenStrings[idx]
 		}
-		if resp.Error != nil {
-			results[i].Error = resp.Error.Error()
+
+		response, err := c.sendMulticast(ctx, &attemptMessage)
+		if err != nil {
+			if attempt == 1 {
+				slog.Error("FCM multicast send failed", "error", err, "token_count", len(tokens))
+				return nil, err
+			}
+
+			// Tokens keep the failure from the previous attempt.
+			slog.Warn("FCM multicast retry failed",
+				"error", err,
+				"attempt", attempt,
+				"token_count", len(pending),
+			)
+			break
+		}
+
+		var retryable []int
+		var retryHint time.Duration
+		for i, resp := range response.Responses {
+			idx := pending[i]
+			results[idx] = model.TokenResult{
+				Token:     tokenStrings[idx],
+				Channel:   domain.ChannelFCM,
+				Success:   resp.Success,
+				MessageID: resp.MessageID,
+				Attempts:  attempt,
+			}
+			if resp.Error == nil {
+				continue
+			}
+
+			code := classifyError(resp.Error)
+			results[idx].Error = resp.Error.Error()
+			results[idx].ErrorCode = code
+			results[idx].ShouldRemoveToken = code.ShouldRemoveToken()
 			slog.Warn("FCM send failed for token",
-				"token_index", i,
+				"token_index", idx,
+				"attempt", attempt,
+				"error_code", code.String(),
 				"error", resp.Error.Error(),
 			)
+
+			if code.IsRetryable() {
+				retryable = append(retryable, idx)
+				retryHint = max(retryHint, retryAfter(resp.Error))
+			}
+		}
+
+		if len(retryable) == 0 || attempt >= c.retry.MaxAttempts {
+			break
+		}
+
+		wait := c.retry.Backoff(attempt, retryHint)
+		if !retry.SleepWithinDeadline(ctx, wait) {
+			slog.Warn("FCM retry abandoned before request deadline",
+				"attempt", attempt,
+				"retryable_count", len(retryable),
+				"backoff", wait,
+			)
+			break
+		}
+
+		slog.Debug("retrying FCM send",
+			"attempt", attempt+1,
+			"token_count", len(retryable),
+			"backoff", wait,
+		)
+		pending = retryable
+	}
+
+	successCount := 0
+	for _, r := range results {
+		if r.Success {
+			successCount++
 		}
 	}
 
-	return &BulkResult{
+	return &model.BulkResult{
 		Total:        len(tokens),
-		SuccessCount: response.SuccessCount,
-		FailureCount: response.FailureCount,
+		SuccessCount: successCount,
+		FailureCount: len(tokens) - successCount,
+		Status:       domain.DeliveryStatusComplete,
 		Results:      results,
 	}, nil
 }
 
+func (c *Client) sendMulticast(ctx context.Context, message *messaging.MulticastMessage) (*messaging.BatchResponse, error) {
+	ticket, err := c.breaker.allow()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.rateLimiter.Wait(ctx, len(message.Tokens)); err != nil {
+		c.breaker.release(ticket)
+		return nil, err
+	}
+
+	var response *messaging.BatchResponse
+	if c.dryRun {
+		response, err = c.sender.SendEachForMulticastDryRun(ctx, message)
+	} else {
+		response, err = c.sender.SendEachForMulticast(ctx, message)
+	}
+	c.breaker.record(ticket, err)
+
+	return response, err
+}
+
 func getTemplate(taskType domain.Type) NotificationTemplate {
 	provider, err := templates.GetProvider()
 	if err != nil {
@@ -178,6 +397,18 @@ func getTemplate(taskType domain.Type) NotificationTemplate {
 	}
 }
 
+// iconURL returns the notification icon, or "" unless both a web app base URL and a color are set.
+func (c *Client) iconURL(taskType domain.Type, color string) string {
+	if c.webAppBaseURL == "" || color == "" {
+		return ""
+	}
+
+	iconURL := buildIconURL(c.webAppBaseURL, taskType, color)
+	slog.Debug("notification icon URL set", "icon_url", iconURL)
+
+	return iconURL
+}
+
 func buildIconURL(baseURL string, taskType domain.Type, color string) string {
 	colorHex := strings.TrimPrefix(color, "#")
 	return fmt.Sprintf("%s/api/notification-icon/%s/%s.png",
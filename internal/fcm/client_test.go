@@ -0,0 +1,492 @@
+package fcm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/messaging"
+	"google.golang.org/api/option"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/fcm/fcmtest"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/model"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/retry"
+)
+
+var _ Sender = (*fcmtest.Sender)(nil)
+
+const testTaskID = domain.TaskID("0193a4b2-7c1d-7e8f-9a0b-1c2d3e4f5a6b")
+
+func newTokens(n int) []domain.FCMToken {
+	tokens := make([]domain.FCMToken, n)
+	for i := range tokens {
+		tokens[i] = domain.FCMToken(fmt.Sprintf("token-%04d", i))
+	}
+	return tokens
+}
+
+func TestSendBulkNotification_Batching(t *testing.T) {
+	tests := []struct {
+		name        string
+		tokenCount  int
+		wantBatches []int
+	}{
+		{name: "single token", tokenCount: 1, wantBatches: []int{1}},
+		{name: "exactly one batch", tokenCount: maxTokensPerBatch, wantBatches: []int{maxTokensPerBatch}},
+		{name: "one over batch size", tokenCount: maxTokensPerBatch + 1, wantBatches: []int{maxTokensPerBatch, 1}},
+		{name: "several batches", tokenCount: 1234, wantBatches: []int{500, 500, 234}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := fcmtest.NewSender()
+			client := NewClientWithSender(sender, Config{})
+			tokens := newTokens(tt.tokenCount)
+
+			result, err := client.SendBulkNotification(context.Background(), tokens, testTaskID, domain.TypeShort, "", domain.DeliveryModeNotification)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// Batches are sent concurrently, so compare sizes irrespective of order.
+			var gotBatches []int
+			for _, m := range sender.Messages() {
+				gotBatches = append(gotBatches, len(m.Tokens))
+			}
+			slices.Sort(gotBatches)
+			wantBatches := slices.Sorted(slices.Values(tt.wantBatches))
+			if !slices.Equal(gotBatches, wantBatches) {
+				t.Fatalf("expected batches %v, got %v", wantBatches, gotBatches)
+			}
+
+			if result.Total != tt.tokenCount || result.SuccessCount != tt.tokenCount || result.FailureCount != 0 {
+				t.Errorf("unexpected counts: total=%d success=%d failure=%d", result.Total, result.SuccessCount, result.FailureCount)
+			}
+			for i, r := range result.Results {
+				if r.Token != tokens[i].String() {
+					t.Fatalf("result %d: expected token %q, got %q", i, tokens[i], r.Token)
+				}
+			}
+		})
+	}
+}
+
+func TestSendBulkNotification_ResponseMapping(t *testing.T) {
+	sender := fcmtest.NewSender()
+	sender.FailToken("token-0001", errors.New("requested entity was not found"))
+	client := NewClientWithSender(sender, Config{})
+
+	result, err := client.SendBulkNotification(context.Background(), newTokens(3), testTaskID, domain.TypeNear, "", domain.DeliveryModeNotification)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.SuccessCount != 2 || result.FailureCount != 1 {
+		t.Fatalf("expected 2 successes and 1 failure, got %d and %d", result.SuccessCount, result.FailureCount)
+	}
+
+	failed := result.Results[1]
+	if failed.Success || failed.Error != "requested entity was not found" || failed.MessageID != "" {
+		t.Errorf("unexpected failed result: %+v", failed)
+	}
+	for _, i := range []int{0, 2} {
+		if !result.Results[i].Success || result.Results[i].MessageID == "" {
+			t.Errorf("result %d: expected success with message id, got %+v", i, result.Results[i])
+		}
+	}
+
+	data := sender.Messages()[0].Data
+	if data["task_id"] != testTaskID.String() || data["task_type"] != domain.TypeNear.String() {
+		t.Errorf("unexpected data payload: %v", data)
+	}
+}
+
+func TestSendBulkNotification_BatchError(t *testing.T) {
+	sender := fcmtest.NewSender()
+	sender.FailBatch(errors.New("transport closed"))
+	client := NewClientWithSender(sender, Config{})
+
+	result, err := client.SendBulkNotification(context.Background(), newTokens(2), testTaskID, domain.TypeShort, "", domain.DeliveryModeNotification)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Status != domain.DeliveryStatusFailed || result.FailureCount != 2 {
+		t.Fatalf("expected failed status with 2 failures, got %s with %d", result.Status, result.FailureCount)
+	}
+	for _, r := range result.Results {
+		if r.Success || r.Error != "transport closed" || r.ErrorCode != domain.ErrorCodeUnavailable {
+			t.Errorf("unexpected result: %+v", r)
+		}
+	}
+	if !result.Retryable() {
+		t.Error("expected a failed send with no successes to be retryable")
+	}
+}
+
+func TestSendBulkNotification_NoTokens(t *testing.T) {
+	sender := fcmtest.NewSender()
+	client := NewClientWithSender(sender, Config{})
+
+	result, err := client.SendBulkNotification(context.Background(), nil, testTaskID, domain.TypeShort, "", domain.DeliveryModeNotification)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Status != domain.DeliveryStatusComplete || result.Total != 0 {
+		t.Errorf("expected a complete send of nothing, got %s with total %d", result.Status, result.Total)
+	}
+	if result.Retryable() {
+		t.Error("expected a send of nothing not to be retryable")
+	}
+	if len(sender.Messages()) != 0 {
+		t.Errorf("expected nothing to be sent, got %d sends", len(sender.Messages()))
+	}
+}
+
+func TestSendBulkNotification_PartialBatchFailure(t *testing.T) {
+	sender := fcmtest.NewSender()
+	tokens := newTokens(maxTokensPerBatch + 10)
+	sender.FailBatchWithToken(tokens[maxTokensPerBatch].String(), errors.New("connection reset"))
+	client := NewClientWithSender(sender, Config{})
+
+	result, err := client.SendBulkNotification(context.Background(), tokens, testTaskID, domain.TypeShort, "", domain.DeliveryModeNotification)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Status != domain.DeliveryStatusPartial {
+		t.Fatalf("expected partial status, got %s", result.Status)
+	}
+	if result.SuccessCount != maxTokensPerBatch || result.FailureCount != 10 {
+		t.Errorf("expected %d successes and 10 failures, got %d and %d", maxTokensPerBatch, result.SuccessCount, result.FailureCount)
+	}
+	for i, r := range result.Results {
+		if r.Token != tokens[i].String() {
+			t.Fatalf("result %d out of order", i)
+		}
+		if wantSuccess := i < maxTokensPerBatch; r.Success != wantSuccess {
+			t.Errorf("result %d: expected success=%v, got %+v", i, wantSuccess, r)
+		}
+	}
+	if result.Retryable() {
+		t.Error("a partial delivery must not be retryable")
+	}
+}
+
+func TestSendBulkNotification_RetriesTransientFailures(t *testing.T) {
+	sender := fcmtest.NewSender()
+	sender.FailTokenTimes("token-0000", 2, &SendError{Code: domain.ErrorCodeUnavailable, Message: "unavailable"})
+	sender.FailToken("token-0001", &SendError{Code: domain.ErrorCodeUnregistered, Message: "unregistered"})
+	sender.FailToken("token-0002", &SendError{Code: domain.ErrorCodeQuotaExceeded, Message: "quota exceeded"})
+	client := NewClientWithSender(sender, Config{
+		Retry: retry.Policy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond},
+	})
+
+	result, err := client.SendBulkNotification(context.Background(), newTokens(4), testTaskID, domain.TypeShort, "", domain.DeliveryModeNotification)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []struct {
+		success  bool
+		attempts int
+	}{
+		{success: true, attempts: 3},
+		{success: false, attempts: 1},
+		{success: false, attempts: 3},
+		{success: true, attempts: 1},
+	}
+	for i, w := range want {
+		r := result.Results[i]
+		if r.Success != w.success || r.Attempts != w.attempts {
+			t.Errorf("result %d: expected success=%v attempts=%d, got %+v", i, w.success, w.attempts, r)
+		}
+	}
+
+	if result.SuccessCount != 2 || result.FailureCount != 2 {
+		t.Errorf("expected 2 successes and 2 failures, got %d and %d", result.SuccessCount, result.FailureCount)
+	}
+
+	messages := sender.Messages()
+	if len(messages) != 3 {
+		t.Fatalf("expected 3 sends, got %d", len(messages))
+	}
+	if got := messages[1].Tokens; len(got) != 2 || got[0] != "token-0000" || got[1] != "token-0002" {
+		t.Errorf("expected only retryable tokens to be re-sent, got %v", got)
+	}
+}
+
+func TestSendBulkNotification_RetryStopsAtDeadline(t *testing.T) {
+	sender := fcmtest.NewSender()
+	sender.FailToken("token-0000", &SendError{Code: domain.ErrorCodeUnavailable, Message: "unavailable", RetryAfter: time.Minute})
+	client := NewClientWithSender(sender, Config{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	result, err := client.SendBulkNotification(ctx, newTokens(1), testTaskID, domain.TypeShort, "", domain.DeliveryModeNotification)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(sender.Messages()) != 1 {
+		t.Errorf("expected no retry past the deadline, got %d sends", len(sender.Messages()))
+	}
+	if result.Results[0].Attempts != 1 || result.Results[0].Success {
+		t.Errorf("unexpected result: %+v", result.Results[0])
+	}
+}
+
+func TestSendBulkNotification_ErrorClassification(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantCode   domain.ErrorCode
+		wantRemove bool
+	}{
+		{name: "unregistered", err: &SendError{Code: domain.ErrorCodeUnregistered, Message: "gone"}, wantCode: domain.ErrorCodeUnregistered, wantRemove: true},
+		{name: "sender id mismatch", err: &SendError{Code: domain.ErrorCodeSenderIDMismatch, Message: "mismatch"}, wantCode: domain.ErrorCodeSenderIDMismatch, wantRemove: true},
+		{name: "invalid argument", err: &SendError{Code: domain.ErrorCodeInvalidArgument, Message: "bad"}, wantCode: domain.ErrorCodeInvalidArgument},
+		{name: "unclassified", err: errors.New("boom"), wantCode: domain.ErrorCodeUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := fcmtest.NewSender()
+			sender.FailToken("token-0000", tt.err)
+			client := NewClientWithSender(sender, Config{})
+
+			result, err := client.SendBulkNotification(context.Background(), newTokens(1), testTaskID, domain.TypeShort, "", domain.DeliveryModeNotification)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			r := result.Results[0]
+			if r.ErrorCode != tt.wantCode || r.ShouldRemoveToken != tt.wantRemove {
+				t.Errorf("expected code=%q remove=%v, got code=%q remove=%v", tt.wantCode, tt.wantRemove, r.ErrorCode, r.ShouldRemoveToken)
+			}
+		})
+	}
+}
+
+// fcmErrorTransport answers every FCM request with the same error response.
+type fcmErrorTransport struct {
+	status     int
+	body       string
+	retryAfter string
+}
+
+func (t *fcmErrorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	header := http.Header{"Content-Type": {"application/json"}}
+	if t.retryAfter != "" {
+		header.Set("Retry-After", t.retryAfter)
+	}
+
+	return &http.Response{
+		StatusCode: t.status,
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(t.body)),
+		Request:    req,
+	}, nil
+}
+
+// newSDKSender returns a Firebase SDK client whose requests all fail with the given response.
+func newSDKSender(t *testing.T, transport *fcmErrorTransport) *messaging.Client {
+	t.Helper()
+
+	ctx := context.Background()
+	app, err := firebase.NewApp(ctx, &firebase.Config{ProjectID: "test-project"}, option.WithHTTPClient(&http.Client{Transport: transport}))
+	if err != nil {
+		t.Fatalf("failed to create app: %v", err)
+	}
+	client, err := app.Messaging(ctx)
+	if err != nil {
+		t.Fatalf("failed to create messaging client: %v", err)
+	}
+	return client
+}
+
+func fcmErrorBody(status, errorCode string) string {
+	return fmt.Sprintf(`{"error": {"status": %q, "message": "test error", "details": [`+
+		`{"@type": "type.googleapis.com/google.firebase.fcm.v1.FcmError", "errorCode": %q}]}}`, status, errorCode)
+}
+
+func TestSendBulkNotification_SDKErrorClassification(t *testing.T) {
+	// The SDK itself retries 503 responses unless Retry-After is beyond two
+	// minutes, so unavailable responses carry a longer one.
+	tests := []struct {
+		name           string
+		transport      fcmErrorTransport
+		wantCode       domain.ErrorCode
+		wantRemove     bool
+		wantRetryAfter time.Duration
+	}{
+		{
+			name:       "unregistered",
+			transport:  fcmErrorTransport{status: http.StatusNotFound, body: fcmErrorBody("NOT_FOUND", "UNREGISTERED")},
+			wantCode:   domain.ErrorCodeUnregistered,
+			wantRemove: true,
+		},
+		{
+			name:      "invalid argument",
+			transport: fcmErrorTransport{status: http.StatusBadRequest, body: fcmErrorBody("INVALID_ARGUMENT", "INVALID_ARGUMENT")},
+			wantCode:  domain.ErrorCodeInvalidArgument,
+		},
+		{
+			name:       "sender id mismatch",
+			transport:  fcmErrorTransport{status: http.StatusForbidden, body: fcmErrorBody("PERMISSION_DENIED", "SENDER_ID_MISMATCH")},
+			wantCode:   domain.ErrorCodeSenderIDMismatch,
+			wantRemove: true,
+		},
+		{
+			name:           "quota exceeded",
+			transport:      fcmErrorTransport{status: http.StatusTooManyRequests, body: fcmErrorBody("RESOURCE_EXHAUSTED", "QUOTA_EXCEEDED"), retryAfter: "30"},
+			wantCode:       domain.ErrorCodeQuotaExceeded,
+			wantRetryAfter: 30 * time.Second,
+		},
+		{
+			name:           "unavailable",
+			transport:      fcmErrorTransport{status: http.StatusServiceUnavailable, body: fcmErrorBody("UNAVAILABLE", "UNAVAILABLE"), retryAfter: "180"},
+			wantCode:       domain.ErrorCodeUnavailable,
+			wantRetryAfter: 3 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := newSDKSender(t, &tt.transport)
+			client := NewClientWithSender(sender, Config{Retry: retry.Policy{MaxAttempts: 1}})
+
+			result, err := client.SendBulkNotification(context.Background(), newTokens(1), testTaskID, domain.TypeShort, "", domain.DeliveryModeNotification)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			r := result.Results[0]
+			if r.ErrorCode != tt.wantCode || r.ShouldRemoveToken != tt.wantRemove {
+				t.Errorf("expected code=%q remove=%v, got code=%q remove=%v", tt.wantCode, tt.wantRemove, r.ErrorCode, r.ShouldRemoveToken)
+			}
+
+			_, sendErr := sender.Send(context.Background(), &messaging.Message{Token: "token-0000"})
+			if got := retryAfter(sendErr); got != tt.wantRetryAfter {
+				t.Errorf("expected retry after %s, got %s", tt.wantRetryAfter, got)
+			}
+		})
+	}
+}
+
+// gatedSender holds every multicast call until the test releases it, and
+// tracks how many are in flight at once.
+type gatedSender struct {
+	*fcmtest.Sender
+
+	started chan struct{}
+	release chan struct{}
+
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+func newGatedSender() *gatedSender {
+	return &gatedSender{
+		Sender:  fcmtest.NewSender(),
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+}
+
+func (s *gatedSender) SendEachForMulticast(ctx context.Context, message *messaging.MulticastMessage) (*messaging.BatchResponse, error) {
+	s.mu.Lock()
+	s.inFlight++
+	s.maxInFlight = max(s.maxInFlight, s.inFlight)
+	s.mu.Unlock()
+
+	s.started <- struct{}{}
+	<-s.release
+
+	s.mu.Lock()
+	s.inFlight--
+	s.mu.Unlock()
+
+	return s.Sender.SendEachForMulticast(ctx, message)
+}
+
+func TestSendBulkNotification_BoundedParallelism(t *testing.T) {
+	sender := newGatedSender()
+	client := NewClientWithSender(sender, Config{Parallelism: 2})
+	tokens := newTokens(5 * maxTokensPerBatch)
+
+	type outcome struct {
+		result *model.BulkResult
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := client.SendBulkNotification(context.Background(), tokens, testTaskID, domain.TypeShort, "", domain.DeliveryModeNotification)
+		done <- outcome{result, err}
+	}()
+
+	// Keep two batches in flight: each release lets the next batch start.
+	<-sender.started
+	<-sender.started
+	for range 3 {
+		sender.release <- struct{}{}
+		<-sender.started
+	}
+	for range 2 {
+		sender.release <- struct{}{}
+	}
+
+	out := <-done
+	if out.err != nil {
+		t.Fatalf("unexpected error: %v", out.err)
+	}
+
+	if sender.maxInFlight != 2 {
+		t.Errorf("expected 2 batches in flight, got %d", sender.maxInFlight)
+	}
+	for i, r := range out.result.Results {
+		if r.Token != tokens[i].String() {
+			t.Fatalf("result %d out of order: expected %q, got %q", i, tokens[i], r.Token)
+		}
+	}
+}
+
+func TestSendBulkNotification_DryRun(t *testing.T) {
+	sender := fcmtest.NewSender()
+	sender.FailToken("token-0001", &SendError{Code: domain.ErrorCodeInvalidArgument, Message: "invalid"})
+	client := NewClientWithSender(sender, Config{})
+	dryRun := client.DryRun()
+
+	if client.IsDryRun() || !dryRun.IsDryRun() {
+		t.Fatal("DryRun must return a separate validate-only client")
+	}
+
+	result, err := dryRun.SendBulkNotification(context.Background(), newTokens(2), testTaskID, domain.TypeShort, "", domain.DeliveryModeNotification)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(sender.Messages()) != 0 || len(sender.DryRunMessages()) != 1 {
+		t.Fatalf("expected only a dry-run send, got %d sends and %d dry runs", len(sender.Messages()), len(sender.DryRunMessages()))
+	}
+	if result.SuccessCount != 1 || result.Results[1].ErrorCode != domain.ErrorCodeInvalidArgument {
+		t.Errorf("expected validation failures to be reported, got %+v", result)
+	}
+
+	dryRun.SendToTopic(context.Background(), domain.Topic("team-a"), testTaskID, domain.TypeShort, "", domain.DeliveryModeNotification)
+	if len(sender.SentMessages()) != 0 || len(sender.DryRunSentMessages()) != 1 {
+		t.Errorf("expected topic send to be a dry run")
+	}
+}
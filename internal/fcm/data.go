@@ -0,0 +1,80 @@
+package fcm
+
+import (
+	"strconv"
+	"time"
+
+	"firebase.google.com/go/v4/messaging"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+)
+
+// DataSchemaVersion is the version of the data-only payload schema. It is
+// bumped whenever a key is renamed or its meaning changes, so clients can
+// keep rendering older payloads.
+const DataSchemaVersion = "1"
+
+// Data payload keys. Notification messages carry only the task and link keys;
+// data-only messages carry all of them. Optional keys are omitted when empty.
+const (
+	DataKeySchemaVersion = "schema_version"
+	DataKeyTaskID        = "task_id"
+	DataKeyTaskType      = "task_type"
+	DataKeyTitle         = "title"
+	DataKeyBody          = "body"
+	DataKeyIcon          = "icon"
+	DataKeyLink          = "link"
+)
+
+// dataPayload builds the data map of a data-only message, carrying everything
+// the client needs to render the reminder itself.
+func dataPayload(template NotificationTemplate, taskID domain.TaskID, taskType domain.Type, iconURL, link string) map[string]string {
+	data := map[string]string{
+		DataKeySchemaVersion: DataSchemaVersion,
+		DataKeyTaskID:        taskID.String(),
+		DataKeyTaskType:      taskType.String(),
+		DataKeyTitle:         template.Title,
+		DataKeyBody:          template.Body,
+	}
+	if iconURL != "" {
+		data[DataKeyIcon] = iconURL
+	}
+	if link != "" {
+		data[DataKeyLink] = link
+	}
+
+	return data
+}
+
+func androidDataConfig(opts PlatformOptions) *messaging.AndroidConfig {
+	config := &messaging.AndroidConfig{Priority: opts.Priority}
+	if opts.TTL > 0 {
+		ttl := opts.TTL
+		config.TTL = &ttl
+	}
+
+	return config
+}
+
+func webpushDataConfig(opts PlatformOptions) *messaging.WebpushConfig {
+	// Without a notification the service worker receives the push event and renders it.
+	return &messaging.WebpushConfig{Headers: webpushHeaders(opts)}
+}
+
+func apnsDataConfig(opts PlatformOptions, now time.Time) *messaging.APNSConfig {
+	// APNs only accepts background pushes at priority 5.
+	headers := map[string]string{
+		"apns-push-type": "background",
+		"apns-priority":  "5",
+	}
+	if opts.TTL > 0 {
+		headers["apns-expiration"] = strconv.FormatInt(now.Add(opts.TTL).Unix(), 10)
+	}
+
+	return &messaging.APNSConfig{
+		Headers: headers,
+		Payload: &messaging.APNSPayload{
+			Aps: &messaging.Aps{ContentAvailable: true},
+		},
+	}
+}
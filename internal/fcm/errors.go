@@ -0,0 +1,81 @@
+package fcm
+
+import (
+	"errors"
+	"time"
+
+	"firebase.google.com/go/v4/errorutils"
+	"firebase.google.com/go/v4/messaging"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/retry"
+)
+
+// SendError is a per-token error carrying an explicit error code.
+// Senders that are not backed by the Firebase SDK report failures with it
+// so they are classified the same way as SDK errors.
+type SendError struct {
+	Code       domain.ErrorCode
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *SendError) Error() string {
+	return e.Message
+}
+
+func classifyError(err error) domain.ErrorCode {
+	var sendErr *SendError
+
+	switch {
+	case err == nil:
+		return domain.ErrorCodeNone
+	case errors.As(err, &sendErr):
+		return sendErr.Code
+	case isRateLimited(err):
+		return domain.ErrorCodeQuotaExceeded
+	case errors.Is(err, ErrCircuitOpen):
+		return domain.ErrorCodeUnavailable
+	case messaging.IsUnregistered(err):
+		return domain.ErrorCodeUnregistered
+	case messaging.IsInvalidArgument(err):
+		return domain.ErrorCodeInvalidArgument
+	case messaging.IsSenderIDMismatch(err):
+		return domain.ErrorCodeSenderIDMismatch
+	case messaging.IsQuotaExceeded(err):
+		return domain.ErrorCodeQuotaExceeded
+	case messaging.IsUnavailable(err):
+		return domain.ErrorCodeUnavailable
+	case messaging.IsInternal(err):
+		return domain.ErrorCodeInternal
+	default:
+		return domain.ErrorCodeUnknown
+	}
+}
+
+// rejectedLocally reports whether a send was turned away before reaching FCM
+// by the rate limiter or the circuit breaker. Retrying it within the same
+// request does not help: the limiter already waited as long as the request
+// allows, and the breaker stays open for longer than a backoff.
+func rejectedLocally(err error) bool {
+	return isRateLimited(err) || errors.Is(err, ErrCircuitOpen)
+}
+
+// retryAfter returns the server-requested delay before the next attempt, or zero.
+func retryAfter(err error) time.Duration {
+	var sendErr *SendError
+	if errors.As(err, &sendErr) {
+		return sendErr.RetryAfter
+	}
+	var rateErr *RateLimitError
+	if errors.As(err, &rateErr) {
+		return rateErr.RetryAfter
+	}
+
+	resp := errorutils.HTTPResponse(err)
+	if resp == nil {
+		return 0
+	}
+
+	return retry.ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+}
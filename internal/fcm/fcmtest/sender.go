@@ -0,0 +1,292 @@
+// Package fcmtest provides an in-memory fcm.Sender for tests that must run
+// without Firebase credentials.
+package fcmtest
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"firebase.google.com/go/v4/messaging"
+)
+
+// Sender records every multicast message it receives and answers with a
+// per-token response. Tokens succeed unless configured to fail.
+type Sender struct {
+	mu            sync.Mutex
+	messages      []*messaging.MulticastMessage
+	sent          []*messaging.Message
+	dryRuns       []*messaging.MulticastMessage
+	dryRunSent    []*messaging.Message
+	subscriptions map[string]map[string]bool
+	failures      map[string]*failure
+	batchErr      error
+	batchFails    map[string]error
+	messageSeq    int
+}
+
+type failure struct {
+	err       error
+	remaining int // -1 fails forever
+}
+
+// NewSender creates a Sender on which every token succeeds.
+func NewSender() *Sender {
+	return &Sender{
+		subscriptions: make(map[string]map[string]bool),
+		failures:      make(map[string]*failure),
+		batchFails:    make(map[string]error),
+	}
+}
+
+// FailToken makes every send to token fail with err. For Send, token may
+// also be a topic name or condition; for topic management it fails the
+// (un)subscription of that token.
+func (s *Sender) FailToken(token string, err error) {
+	s.FailTokenTimes(token, -1, err)
+}
+
+// FailTokenTimes makes the next n sends to token fail with err; later sends
+// succeed. A negative n fails forever.
+func (s *Sender) FailTokenTimes(token string, n int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures[token] = &failure{err: err, remaining: n}
+}
+
+// FailBatch makes every SendEachForMulticast call return err without any
+// per-token responses. A nil err restores normal behaviour.
+func (s *Sender) FailBatch(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.batchErr = err
+}
+
+// FailBatchWithToken makes every SendEachForMulticast call whose message
+// includes token return err, failing the whole batch.
+func (s *Sender) FailBatchWithToken(token string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.batchFails[token] = err
+}
+
+// Messages returns the multicast messages received so far, in call order.
+func (s *Sender) Messages() []*messaging.MulticastMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*messaging.MulticastMessage(nil), s.messages...)
+}
+
+// SentMessages returns the single-target messages received by Send, in call order.
+func (s *Sender) SentMessages() []*messaging.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*messaging.Message(nil), s.sent...)
+}
+
+// DryRunMessages returns the multicast messages received by
+// SendEachForMulticastDryRun, in call order. They are not part of Messages.
+func (s *Sender) DryRunMessages() []*messaging.MulticastMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*messaging.MulticastMessage(nil), s.dryRuns...)
+}
+
+// DryRunSentMessages returns the single-target messages received by SendDryRun, in call order.
+func (s *Sender) DryRunSentMessages() []*messaging.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*messaging.Message(nil), s.dryRunSent...)
+}
+
+// Subscribers returns the tokens currently subscribed to topic.
+func (s *Sender) Subscribers(topic string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var tokens []string
+	for token := range s.subscriptions[topic] {
+		tokens = append(tokens, token)
+	}
+	slices.Sort(tokens)
+	return tokens
+}
+
+// SentTokens returns every token that was attempted, in call order.
+func (s *Sender) SentTokens() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var tokens []string
+	for _, m := range s.messages {
+		tokens = append(tokens, m.Tokens...)
+	}
+	return tokens
+}
+
+// SendEachForMulticast implements fcm.Sender.
+func (s *Sender) SendEachForMulticast(ctx context.Context, message *messaging.MulticastMessage) (*messaging.BatchResponse, error) {
+	return s.multicast(ctx, message, false)
+}
+
+// SendEachForMulticastDryRun implements fcm.Sender. Configured failures apply
+// as they would to a real send, since FCM validates every token.
+func (s *Sender) SendEachForMulticastDryRun(ctx context.Context, message *messaging.MulticastMessage) (*messaging.BatchResponse, error) {
+	return s.multicast(ctx, message, true)
+}
+
+func (s *Sender) multicast(ctx context.Context, message *messaging.MulticastMessage, dryRun bool) (*messaging.BatchResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if dryRun {
+		s.dryRuns = append(s.dryRuns, message)
+	} else {
+		s.messages = append(s.messages, message)
+	}
+
+	if s.batchErr != nil {
+		return nil, s.batchErr
+	}
+	for _, token := range message.Tokens {
+		if err, ok := s.batchFails[token]; ok {
+			return nil, err
+		}
+	}
+
+	response := &messaging.BatchResponse{
+		Responses: make([]*messaging.SendResponse, len(message.Tokens)),
+	}
+	for i, token := range message.Tokens {
+		if err := s.consumeFailure(token); err != nil {
+			response.Responses[i] = &messaging.SendResponse{Error: err}
+			response.FailureCount++
+			continue
+		}
+
+		response.Responses[i] = &messaging.SendResponse{
+			Success:   true,
+			MessageID: s.nextMessageID(dryRun),
+		}
+		response.SuccessCount++
+	}
+
+	return response, nil
+}
+
+// Send implements fcm.Sender.
+func (s *Sender) Send(ctx context.Context, message *messaging.Message) (string, error) {
+	return s.send(ctx, message, false)
+}
+
+// SendDryRun implements fcm.Sender.
+func (s *Sender) SendDryRun(ctx context.Context, message *messaging.Message) (string, error) {
+	return s.send(ctx, message, true)
+}
+
+func (s *Sender) send(ctx context.Context, message *messaging.Message, dryRun bool) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if dryRun {
+		s.dryRunSent = append(s.dryRunSent, message)
+	} else {
+		s.sent = append(s.sent, message)
+	}
+
+	target := message.Token
+	switch {
+	case message.Topic != "":
+		target = message.Topic
+	case message.Condition != "":
+		target = message.Condition
+	}
+	if err := s.consumeFailure(target); err != nil {
+		return "", err
+	}
+
+	return s.nextMessageID(dryRun), nil
+}
+
+// SubscribeToTopic implements fcm.Sender.
+func (s *Sender) SubscribeToTopic(ctx context.Context, tokens []string, topic string) (*messaging.TopicManagementResponse, error) {
+	return s.manageTopic(ctx, tokens, topic, true)
+}
+
+// UnsubscribeFromTopic implements fcm.Sender.
+func (s *Sender) UnsubscribeFromTopic(ctx context.Context, tokens []string, topic string) (*messaging.TopicManagementResponse, error) {
+	return s.manageTopic(ctx, tokens, topic, false)
+}
+
+func (s *Sender) manageTopic(ctx context.Context, tokens []string, topic string, subscribe bool) (*messaging.TopicManagementResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.batchErr != nil {
+		return nil, s.batchErr
+	}
+
+	if s.subscriptions[topic] == nil {
+		s.subscriptions[topic] = make(map[string]bool)
+	}
+
+	response := &messaging.TopicManagementResponse{}
+	for i, token := range tokens {
+		if err := s.consumeFailure(token); err != nil {
+			response.FailureCount++
+			response.Errors = append(response.Errors, &messaging.ErrorInfo{Index: i, Reason: err.Error()})
+			continue
+		}
+
+		if subscribe {
+			s.subscriptions[topic][token] = true
+		} else {
+			delete(s.subscriptions[topic], token)
+		}
+		response.SuccessCount++
+	}
+
+	return response, nil
+}
+
+// nextMessageID returns a message ID in the FCM format. Like FCM, dry runs
+// answer with a fixed fake ID.
+func (s *Sender) nextMessageID(dryRun bool) string {
+	if dryRun {
+		return "projects/fake/messages/fake_message_id"
+	}
+
+	s.messageSeq++
+	return fmt.Sprintf("projects/fake/messages/%d", s.messageSeq)
+}
+
+func (s *Sender) consumeFailure(token string) error {
+	f, ok := s.failures[token]
+	if !ok || f.remaining == 0 {
+		return nil
+	}
+	if f.remaining > 0 {
+		f.remaining--
+	}
+	return f.err
+}
@@ -0,0 +1,42 @@
+package fcm
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+)
+
+// DefaultLinkPattern is the web app path opened when a reminder is tapped.
+// {task_id} and {task_type} are replaced with the reminder's values.
+const DefaultLinkPattern = "/tasks/{task_id}"
+
+// linkPattern returns the configured path pattern for a task type.
+func (c *Client) linkPattern(taskType domain.Type) string {
+	if pattern, ok := c.linkPatterns[taskType]; ok {
+		return pattern
+	}
+
+	return DefaultLinkPattern
+}
+
+// buildLink returns the click-through URL for a task, or "" without a base URL.
+func buildLink(baseURL, pattern string, taskID domain.TaskID, taskType domain.Type) string {
+	if baseURL == "" || pattern == "" {
+		return ""
+	}
+
+	path := strings.NewReplacer(
+		"{task_id}", url.PathEscape(taskID.String()),
+		"{task_type}", strings.ToLower(taskType.String()),
+	).Replace(pattern)
+
+	return strings.TrimSuffix(baseURL, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+// isHTTPS reports whether link may be used as a Webpush FCM options link,
+// which FCM rejects unless it is an HTTPS URL.
+func isHTTPS(link string) bool {
+	u, err := url.Parse(link)
+	return err == nil && u.Scheme == "https"
+}
@@ -0,0 +1,195 @@
+package fcm
+
+import (
+	"strconv"
+	"time"
+
+	"firebase.google.com/go/v4/messaging"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+)
+
+// Delivery priorities. They map to the Android priority of the same name
+// and to APNs priorities 5 and 10.
+const (
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+)
+
+// Web Push urgencies (RFC 8030).
+const (
+	WebpushUrgencyLow    = "low"
+	WebpushUrgencyNormal = "normal"
+	WebpushUrgencyHigh   = "high"
+)
+
+// PlatformOptions controls how a reminder is presented on each platform.
+type PlatformOptions struct {
+	// TTL is how long FCM keeps the message for an offline device. Zero uses the FCM default.
+	TTL time.Duration
+
+	Priority         string
+	AndroidChannelID string
+	// Sound is the sound played on Android and iOS. Empty delivers silently.
+	Sound string
+	// Badge sets the iOS app badge. Nil leaves the badge unchanged.
+	Badge *int
+
+	WebpushUrgency string
+}
+
+// DefaultPlatformOptions returns the presentation defaults for a task type.
+// More urgent task types are delivered with higher priority and a shorter TTL,
+// since a late reminder for them is no longer useful.
+func DefaultPlatformOptions(taskType domain.Type) PlatformOptions {
+	switch taskType {
+	case domain.TypeShort:
+		return PlatformOptions{
+			TTL:              15 * time.Minute,
+			AndroidChannelID: "reminder_short",
+			Priority:         PriorityHigh,
+			Sound:            "default",
+			WebpushUrgency:   WebpushUrgencyHigh,
+		}
+	case domain.TypeScheduled:
+		return PlatformOptions{
+			TTL:              30 * time.Minute,
+			AndroidChannelID: "reminder_scheduled",
+			Priority:         PriorityHigh,
+			Sound:            "default",
+			WebpushUrgency:   WebpushUrgencyHigh,
+		}
+	case domain.TypeNear:
+		return PlatformOptions{
+			TTL:              2 * time.Hour,
+			AndroidChannelID: "reminder_near",
+			Priority:         PriorityHigh,
+			Sound:            "default",
+			WebpushUrgency:   WebpushUrgencyNormal,
+		}
+	case domain.TypeRelaxed:
+		return PlatformOptions{
+			TTL:              12 * time.Hour,
+			AndroidChannelID: "reminder_relaxed",
+			Priority:         PriorityNormal,
+			WebpushUrgency:   WebpushUrgencyLow,
+		}
+	default:
+		return PlatformOptions{
+			Priority:       PriorityNormal,
+			Sound:          "default",
+			WebpushUrgency: WebpushUrgencyNormal,
+		}
+	}
+}
+
+// platformOptions returns the configured options for a task type, falling back to the defaults.
+func (c *Client) platformOptions(taskType domain.Type) PlatformOptions {
+	if opts, ok := c.platforms[taskType]; ok {
+		return opts
+	}
+
+	return DefaultPlatformOptions(taskType)
+}
+
+// buildMessage creates the multicast message for a reminder without recipients.
+// Every platform sub-config is allocated here, so callers may set fields on them directly.
+func (c *Client) buildMessage(template NotificationTemplate, taskID domain.TaskID, taskType domain.Type, iconURL string, mode domain.DeliveryMode) *messaging.MulticastMessage {
+	opts := c.platformOptions(taskType)
+	link := buildLink(c.webAppBaseURL, c.linkPattern(taskType), taskID, taskType)
+
+	if mode == domain.DeliveryModeDataOnly {
+		return &messaging.MulticastMessage{
+			Data:    dataPayload(template, taskID, taskType, iconURL, link),
+			Android: androidDataConfig(opts),
+			Webpush: webpushDataConfig(opts),
+			APNS:    apnsDataConfig(opts, time.Now()),
+		}
+	}
+
+	message := &messaging.MulticastMessage{
+		Data: map[string]string{
+			DataKeyTaskID:   taskID.String(),
+			DataKeyTaskType: taskType.String(),
+		},
+		Notification: &messaging.Notification{
+			Title: template.Title,
+			Body:  template.Body,
+		},
+		Android: androidConfig(opts, iconURL),
+		Webpush: webpushConfig(opts, iconURL),
+		APNS:    apnsConfig(opts, time.Now()),
+	}
+
+	// Native apps open the link from the data payload; browsers use the FCM options link.
+	if link != "" {
+		message.Data[DataKeyLink] = link
+		if isHTTPS(link) {
+			message.Webpush.FCMOptions = &messaging.WebpushFCMOptions{Link: link}
+		}
+	}
+
+	return message
+}
+
+func androidConfig(opts PlatformOptions, iconURL string) *messaging.AndroidConfig {
+	config := &messaging.AndroidConfig{
+		Priority: opts.Priority,
+		Notification: &messaging.AndroidNotification{
+			Icon:      iconURL,
+			ChannelID: opts.AndroidChannelID,
+			Sound:     opts.Sound,
+		},
+	}
+	if opts.TTL > 0 {
+		ttl := opts.TTL
+		config.TTL = &ttl
+	}
+
+	return config
+}
+
+func webpushConfig(opts PlatformOptions, iconURL string) *messaging.WebpushConfig {
+	return &messaging.WebpushConfig{
+		Headers: webpushHeaders(opts),
+		Notification: &messaging.WebpushNotification{
+			Icon: iconURL,
+		},
+	}
+}
+
+func webpushHeaders(opts PlatformOptions) map[string]string {
+	headers := make(map[string]string)
+	if opts.TTL > 0 {
+		headers["TTL"] = strconv.Itoa(int(opts.TTL.Seconds()))
+	}
+	if opts.WebpushUrgency != "" {
+		headers["Urgency"] = opts.WebpushUrgency
+	}
+
+	return headers
+}
+
+func apnsConfig(opts PlatformOptions, now time.Time) *messaging.APNSConfig {
+	headers := map[string]string{
+		"apns-push-type": "alert",
+		"apns-priority":  "10",
+	}
+	if opts.Priority == PriorityNormal {
+		// APNs priority 5 lets the device batch delivery to save power.
+		headers["apns-priority"] = "5"
+	}
+	if opts.TTL > 0 {
+		headers["apns-expiration"] = strconv.FormatInt(now.Add(opts.TTL).Unix(), 10)
+	}
+
+	return &messaging.APNSConfig{
+		Headers: headers,
+		Payload: &messaging.APNSPayload{
+			Aps: &messaging.Aps{
+				Sound: opts.Sound,
+				Badge: opts.Badge,
+			},
+		},
+	}
+}
@@ -0,0 +1,230 @@
+package fcm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/channel"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/fcm/fcmtest"
+)
+
+func TestSendBulkNotification_PlatformConfigs(t *testing.T) {
+	sender := fcmtest.NewSender()
+	client := NewClientWithSender(sender, Config{WebAppBaseURL: "https://app.example.com/"})
+
+	if _, err := client.SendBulkNotification(context.Background(), newTokens(1), testTaskID, domain.TypeShort, "#EF4444", domain.DeliveryModeNotification); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	message := sender.Messages()[0]
+	wantIcon := "https://app.example.com/api/notification-icon/short/EF4444.png"
+
+	if message.Android == nil || message.Android.Notification == nil {
+		t.Fatal("android config must be populated")
+	}
+	if message.Android.Notification.Icon != wantIcon || message.Android.Notification.ChannelID != "reminder_short" {
+		t.Errorf("unexpected android notification: %+v", message.Android.Notification)
+	}
+	if message.Android.Priority != PriorityHigh || message.Android.TTL == nil || *message.Android.TTL != 15*time.Minute {
+		t.Errorf("unexpected android config: %+v", message.Android)
+	}
+
+	if message.Webpush == nil || message.Webpush.Notification == nil || message.Webpush.Notification.Icon != wantIcon {
+		t.Fatalf("unexpected webpush config: %+v", message.Webpush)
+	}
+	if message.Webpush.Headers["Urgency"] != WebpushUrgencyHigh || message.Webpush.Headers["TTL"] != "900" {
+		t.Errorf("unexpected webpush headers: %v", message.Webpush.Headers)
+	}
+
+	if message.APNS == nil || message.APNS.Payload == nil || message.APNS.Payload.Aps == nil {
+		t.Fatal("apns config must be populated")
+	}
+	if message.APNS.Headers["apns-priority"] != "10" || message.APNS.Headers["apns-expiration"] == "" {
+		t.Errorf("unexpected apns headers: %v", message.APNS.Headers)
+	}
+	if message.APNS.Payload.Aps.Sound != "default" {
+		t.Errorf("unexpected aps sound: %q", message.APNS.Payload.Aps.Sound)
+	}
+}
+
+func TestSendBulkNotification_PlatformDefaultsByTaskType(t *testing.T) {
+	sender := fcmtest.NewSender()
+	client := NewClientWithSender(sender, Config{})
+
+	if _, err := client.SendBulkNotification(context.Background(), newTokens(1), testTaskID, domain.TypeRelaxed, "", domain.DeliveryModeNotification); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	message := sender.Messages()[0]
+	if message.Android.Priority != PriorityNormal || message.APNS.Headers["apns-priority"] != "5" {
+		t.Errorf("expected relaxed reminders to use normal priority, got android=%q apns=%q",
+			message.Android.Priority, message.APNS.Headers["apns-priority"])
+	}
+	if message.Android.Notification.Sound != "" || message.APNS.Payload.Aps.Sound != "" {
+		t.Error("expected relaxed reminders to be silent")
+	}
+	if message.Android.Notification.Icon != "" {
+		t.Error("expected no icon without a web app base URL")
+	}
+}
+
+func TestSendBulkNotification_PlatformOverride(t *testing.T) {
+	badge := 1
+	sender := fcmtest.NewSender()
+	client := NewClientWithSender(sender, Config{
+		Platforms: map[domain.Type]PlatformOptions{
+			domain.TypeNear: {AndroidChannelID: "custom", Priority: PriorityHigh, Badge: &badge},
+		},
+	})
+
+	if _, err := client.SendBulkNotification(context.Background(), newTokens(1), testTaskID, domain.TypeNear, "", domain.DeliveryModeNotification); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	message := sender.Messages()[0]
+	if message.Android.Notification.ChannelID != "custom" || message.Android.TTL != nil {
+		t.Errorf("unexpected android config: %+v", message.Android)
+	}
+	if message.APNS.Payload.Aps.Badge == nil || *message.APNS.Payload.Aps.Badge != 1 {
+		t.Error("expected badge override to be applied")
+	}
+	if _, ok := message.Webpush.Headers["TTL"]; ok {
+		t.Error("expected no TTL header without a TTL")
+	}
+}
+
+func TestSendBulkNotification_DeepLink(t *testing.T) {
+	tests := []struct {
+		name          string
+		baseURL       string
+		patterns      map[domain.Type]string
+		taskType      domain.Type
+		wantLink      string
+		wantWebpushOK bool
+	}{
+		{
+			name:          "default pattern",
+			baseURL:       "https://app.example.com/",
+			taskType:      domain.TypeShort,
+			wantLink:      "https://app.example.com/tasks/" + testTaskID.String(),
+			wantWebpushOK: true,
+		},
+		{
+			name:          "per task type pattern",
+			baseURL:       "https://app.example.com",
+			patterns:      map[domain.Type]string{domain.TypeScheduled: "/schedule/{task_type}/{task_id}"},
+			taskType:      domain.TypeScheduled,
+			wantLink:      "https://app.example.com/schedule/scheduled/" + testTaskID.String(),
+			wantWebpushOK: true,
+		},
+		{
+			name:     "non https base url keeps data link only",
+			baseURL:  "http://localhost:5173",
+			taskType: domain.TypeNear,
+			wantLink: "http://localhost:5173/tasks/" + testTaskID.String(),
+		},
+		{
+			name:     "no base url",
+			taskType: domain.TypeNear,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := fcmtest.NewSender()
+			client := NewClientWithSender(sender, Config{WebAppBaseURL: tt.baseURL, LinkPatterns: tt.patterns})
+
+			if _, err := client.SendBulkNotification(context.Background(), newTokens(1), testTaskID, tt.taskType, "", domain.DeliveryModeNotification); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			message := sender.Messages()[0]
+			if got := message.Data["link"]; got != tt.wantLink {
+				t.Errorf("expected data link %q, got %q", tt.wantLink, got)
+			}
+
+			hasWebpushLink := message.Webpush.FCMOptions != nil && message.Webpush.FCMOptions.Link == tt.wantLink
+			if hasWebpushLink != tt.wantWebpushOK {
+				t.Errorf("expected webpush link set=%v, got %+v", tt.wantWebpushOK, message.Webpush.FCMOptions)
+			}
+		})
+	}
+}
+
+func TestSendBulkNotification_DataOnly(t *testing.T) {
+	sender := fcmtest.NewSender()
+	client := NewClientWithSender(sender, Config{WebAppBaseURL: "https://app.example.com"})
+
+	if _, err := client.SendBulkNotification(context.Background(), newTokens(1), testTaskID, domain.TypeShort, "#EF4444", domain.DeliveryModeDataOnly); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	message := sender.Messages()[0]
+	if message.Notification != nil || message.Android.Notification != nil || message.Webpush.Notification != nil {
+		t.Fatal("expected no notification payload in data-only mode")
+	}
+	if message.Webpush.FCMOptions != nil {
+		t.Errorf("expected no webpush link in data-only mode, got %+v", message.Webpush.FCMOptions)
+	}
+
+	want := map[string]string{
+		DataKeySchemaVersion: DataSchemaVersion,
+		DataKeyTaskID:        testTaskID.String(),
+		DataKeyTaskType:      "short",
+		DataKeyIcon:          "https://app.example.com/api/notification-icon/short/EF4444.png",
+		DataKeyLink:          "https://app.example.com/tasks/" + testTaskID.String(),
+	}
+	for key, value := range want {
+		if got := message.Data[key]; got != value {
+			t.Errorf("data[%q] = %q, want %q", key, got, value)
+		}
+	}
+	if message.Data[DataKeyTitle] == "" || message.Data[DataKeyBody] == "" {
+		t.Errorf("expected title and body in data, got %v", message.Data)
+	}
+
+	if message.APNS.Headers["apns-push-type"] != "background" || message.APNS.Headers["apns-priority"] != "5" {
+		t.Errorf("unexpected apns headers: %v", message.APNS.Headers)
+	}
+	if !message.APNS.Payload.Aps.ContentAvailable {
+		t.Error("expected content-available for a background push")
+	}
+	if message.Android.Priority != PriorityHigh || message.Webpush.Headers["Urgency"] != WebpushUrgencyHigh {
+		t.Errorf("expected task type priority to be kept, got android=%q webpush=%v", message.Android.Priority, message.Webpush.Headers)
+	}
+}
+
+func TestSendBulkNotification_DataOnlyOmitsEmptyKeys(t *testing.T) {
+	sender := fcmtest.NewSender()
+	client := NewClientWithSender(sender, Config{})
+
+	if _, err := client.SendBulkNotification(context.Background(), newTokens(1), testTaskID, domain.TypeRelaxed, "", domain.DeliveryModeDataOnly); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data := sender.Messages()[0].Data
+	for _, key := range []string{DataKeyIcon, DataKeyLink} {
+		if _, ok := data[key]; ok {
+			t.Errorf("expected %q to be omitted, got %v", key, data)
+		}
+	}
+}
+
+func TestDataPayload(t *testing.T) {
+	client := NewClientWithSender(fcmtest.NewSender(), Config{WebAppBaseURL: "https://app.example.com"})
+
+	data := client.DataPayload(channel.Reminder{TaskID: testTaskID, TaskType: domain.TypeNear, Color: "#10B981"})
+	if data[DataKeySchemaVersion] != DataSchemaVersion || data[DataKeyTitle] == "" {
+		t.Errorf("unexpected payload: %v", data)
+	}
+	if data[DataKeyIcon] != "https://app.example.com/api/notification-icon/near/10B981.png" {
+		t.Errorf("unexpected icon: %q", data[DataKeyIcon])
+	}
+
+	headers := client.WebpushHeaders(domain.TypeNear)
+	if headers["TTL"] != "7200" || headers["Urgency"] != WebpushUrgencyNormal {
+		t.Errorf("unexpected webpush headers: %v", headers)
+	}
+}
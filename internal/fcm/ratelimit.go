@@ -0,0 +1,129 @@
+package fcm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const defaultRateLimitMaxWait = 5 * time.Second
+
+// Rate limit outcomes reported to a RateLimitObserver.
+const (
+	RateLimitAllowed  = "allowed"
+	RateLimitDelayed  = "delayed"
+	RateLimitRejected = "rejected"
+	RateLimitCanceled = "canceled"
+)
+
+// RateLimitObserver receives the outcome of every limiter acquisition.
+type RateLimitObserver interface {
+	ObserveRateLimit(ctx context.Context, outcome string, messages int, wait time.Duration)
+}
+
+type RateLimitConfig struct {
+	// Rate is the sustained budget in messages per second.
+	Rate float64
+	// Burst is the bucket size. It is raised to at least one full multicast batch.
+	Burst int
+	// MaxWait bounds how long a send waits for budget. Zero uses defaultRateLimitMaxWait.
+	MaxWait  time.Duration
+	Observer RateLimitObserver
+}
+
+// RateLimiter is a token bucket over FCM messages. One limiter is shared by
+// every request (and by DryRun copies of the client), so concurrent requests
+// draw from a single per-project budget. Each token of a multicast counts as
+// one message.
+type RateLimiter struct {
+	limiter  *rate.Limiter
+	maxWait  time.Duration
+	observer RateLimitObserver
+}
+
+func NewRateLimiter(cfg RateLimitConfig) *RateLimiter {
+	if cfg.MaxWait <= 0 {
+		cfg.MaxWait = defaultRateLimitMaxWait
+	}
+
+	return &RateLimiter{
+		limiter:  rate.NewLimiter(rate.Limit(cfg.Rate), max(cfg.Burst, maxTokensPerBatch)),
+		maxWait:  cfg.MaxWait,
+		observer: cfg.Observer,
+	}
+}
+
+// RateLimitError reports that the budget would not allow a send before the
+// wait deadline. RetryAfter is when the budget is expected to suffice.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("FCM send rate limit exceeded, retry after %s", e.RetryAfter)
+}
+
+func isRateLimited(err error) bool {
+	var rateErr *RateLimitError
+	return errors.As(err, &rateErr)
+}
+
+// Wait blocks until n messages fit the budget. It gives up immediately with a
+// *RateLimitError when the wait would exceed MaxWait or the context deadline,
+// so the request can be answered while the caller still listens.
+// A nil RateLimiter never waits.
+func (l *RateLimiter) Wait(ctx context.Context, n int) error {
+	if l == nil {
+		return nil
+	}
+
+	now := time.Now()
+	reservation := l.limiter.ReserveN(now, n)
+	if !reservation.OK() {
+		return fmt.Errorf("%d messages exceed the rate limit burst %d", n, l.limiter.Burst())
+	}
+
+	delay := reservation.DelayFrom(now)
+	if delay == 0 {
+		l.observe(ctx, RateLimitAllowed, n, 0)
+		return nil
+	}
+
+	budget := l.maxWait
+	if deadline, ok := ctx.Deadline(); ok {
+		budget = min(budget, deadline.Sub(now))
+	}
+	if delay > budget {
+		reservation.CancelAt(now)
+		l.observe(ctx, RateLimitRejected, n, 0)
+		return &RateLimitError{RetryAfter: delay}
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		reservation.Cancel()
+		l.observe(ctx, RateLimitCanceled, n, time.Since(now))
+		return ctx.Err()
+	case <-timer.C:
+		l.observe(ctx, RateLimitDelayed, n, delay)
+		return nil
+	}
+}
+
+// Tokens returns the messages that can currently be sent without waiting.
+// It is negative while reservations are queued.
+func (l *RateLimiter) Tokens() float64 {
+	return l.limiter.Tokens()
+}
+
+func (l *RateLimiter) observe(ctx context.Context, outcome string, messages int, wait time.Duration) {
+	if l.observer != nil {
+		l.observer.ObserveRateLimit(ctx, outcome, messages, wait)
+	}
+}
@@ -0,0 +1,164 @@
+package fcm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/fcm/fcmtest"
+)
+
+type recordingObserver struct {
+	mu       sync.Mutex
+	outcomes []string
+}
+
+func (o *recordingObserver) ObserveRateLimit(_ context.Context, outcome string, _ int, _ time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.outcomes = append(o.outcomes, outcome)
+}
+
+func TestRateLimiter_Wait(t *testing.T) {
+	observer := &recordingObserver{}
+	// 10000 messages/s refills a 500-message burst in 50ms.
+	limiter := NewRateLimiter(RateLimitConfig{Rate: 10000, MaxWait: time.Second, Observer: observer})
+
+	if err := limiter.Wait(context.Background(), maxTokensPerBatch); err != nil {
+		t.Fatalf("unexpected error for the initial burst: %v", err)
+	}
+
+	start := time.Now()
+	if err := limiter.Wait(context.Background(), 100); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if waited := time.Since(start); waited < 5*time.Millisecond {
+		t.Errorf("expected to wait for budget, waited %s", waited)
+	}
+
+	want := []string{RateLimitAllowed, RateLimitDelayed}
+	if len(observer.outcomes) != len(want) || observer.outcomes[0] != want[0] || observer.outcomes[1] != want[1] {
+		t.Errorf("expected outcomes %v, got %v", want, observer.outcomes)
+	}
+}
+
+func TestRateLimiter_Rejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxWait time.Duration
+		timeout time.Duration
+	}{
+		{name: "max wait", maxWait: 10 * time.Millisecond},
+		{name: "context deadline", maxWait: time.Minute, timeout: 10 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewRateLimiter(RateLimitConfig{Rate: 1, Burst: maxTokensPerBatch, MaxWait: tt.maxWait})
+			if err := limiter.Wait(context.Background(), maxTokensPerBatch); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			start := time.Now()
+			err := limiter.Wait(ctx, 10)
+
+			var rateErr *RateLimitError
+			if !errors.As(err, &rateErr) {
+				t.Fatalf("expected RateLimitError, got %v", err)
+			}
+			if rateErr.RetryAfter < 9*time.Second || rateErr.RetryAfter > 10*time.Second {
+				t.Errorf("expected retry after about 10s, got %s", rateErr.RetryAfter)
+			}
+			if time.Since(start) > 5*time.Millisecond {
+				t.Error("expected the rejection without waiting")
+			}
+			if tokens := limiter.Tokens(); tokens < -1 {
+				t.Errorf("expected the rejected reservation to be returned, tokens=%f", tokens)
+			}
+		})
+	}
+}
+
+func TestRateLimiter_Nil(t *testing.T) {
+	var limiter *RateLimiter
+	if err := limiter.Wait(context.Background(), maxTokensPerBatch); err != nil {
+		t.Fatalf("expected a nil limiter to allow every send, got %v", err)
+	}
+}
+
+func TestSendBulkNotification_RateLimited(t *testing.T) {
+	sender := fcmtest.NewSender()
+	limiter := NewRateLimiter(RateLimitConfig{Rate: 1, MaxWait: time.Millisecond})
+	client := NewClientWithSender(sender, Config{RateLimiter: limiter})
+
+	if _, err := client.SendBulkNotification(context.Background(), newTokens(maxTokensPerBatch), testTaskID, domain.TypeShort, "", domain.DeliveryModeNotification); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := client.DryRun().SendBulkNotification(context.Background(), newTokens(2), testTaskID, domain.TypeShort, "", domain.DeliveryModeNotification)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Status != domain.DeliveryStatusFailed || !result.Retryable() || result.RetryAfter <= 0 {
+		t.Fatalf("expected a retryable rate-limited result, got %+v", result)
+	}
+	if result.Results[0].ErrorCode != domain.ErrorCodeQuotaExceeded || result.Results[0].ShouldRemoveToken {
+		t.Errorf("unexpected token result: %+v", result.Results[0])
+	}
+	if len(sender.Messages()) != 1 || len(sender.DryRunMessages()) != 0 {
+		t.Errorf("expected the limited send to never reach FCM, got %d sends and %d dry runs", len(sender.Messages()), len(sender.DryRunMessages()))
+	}
+}
+
+func TestSendBulkNotification_PartlyRateLimited(t *testing.T) {
+	sender := fcmtest.NewSender()
+	limiter := NewRateLimiter(RateLimitConfig{Rate: 1, MaxWait: time.Millisecond})
+	client := NewClientWithSender(sender, Config{RateLimiter: limiter, Parallelism: 1})
+
+	result, err := client.SendBulkNotification(context.Background(), newTokens(2*maxTokensPerBatch), testTaskID, domain.TypeShort, "", domain.DeliveryModeNotification)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.Status != domain.DeliveryStatusPartial || result.SuccessCount != maxTokensPerBatch {
+		t.Fatalf("expected the first batch to be sent, got %+v", result.Status)
+	}
+	// A retry would notify the first batch again, so only the rejected
+	// batch is marked; it still carries a retry hint.
+	if result.Retryable() || result.RetryAfter <= 0 {
+		t.Errorf("expected a non-retryable result with a retry hint, got retryable=%v retry_after=%s", result.Retryable(), result.RetryAfter)
+	}
+	if result.Results[0].NotSent || !result.Results[maxTokensPerBatch].NotSent {
+		t.Errorf("expected only the rejected batch to be marked not sent")
+	}
+}
+
+func TestSendToTopic_RateLimitedIsNotRetried(t *testing.T) {
+	sender := fcmtest.NewSender()
+	limiter := NewRateLimiter(RateLimitConfig{Rate: 1, MaxWait: time.Millisecond})
+	if err := limiter.Wait(context.Background(), maxTokensPerBatch); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client := NewClientWithSender(sender, Config{RateLimiter: limiter})
+
+	result := client.SendToTopic(context.Background(), "team", testTaskID, domain.TypeNear, "", domain.DeliveryModeNotification)
+
+	if result.Results[0].Attempts != 1 || result.Results[0].ErrorCode != domain.ErrorCodeQuotaExceeded || result.RetryAfter <= 0 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if len(sender.SentMessages()) != 0 {
+		t.Error("expected no send to reach FCM")
+	}
+}
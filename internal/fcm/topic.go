@@ -0,0 +1,178 @@
+package fcm
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"firebase.google.com/go/v4/messaging"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/model"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/retry"
+)
+
+// SendToTopic sends a reminder to every device subscribed to topic.
+func (c *Client) SendToTopic(ctx context.Context, topic domain.Topic, taskID domain.TaskID, taskType domain.Type, color string, mode domain.DeliveryMode) *model.BulkResult {
+	message := c.targetMessage(taskID, taskType, color, mode)
+	message.Topic = topic.String()
+
+	return c.sendToTarget(ctx, "/topics/"+topic.String(), message)
+}
+
+// SendToCondition sends a reminder to every device matching a topic condition.
+func (c *Client) SendToCondition(ctx context.Context, condition domain.Condition, taskID domain.TaskID, taskType domain.Type, color string, mode domain.DeliveryMode) *model.BulkResult {
+	message := c.targetMessage(taskID, taskType, color, mode)
+	message.Condition = condition.String()
+
+	return c.sendToTarget(ctx, condition.String(), message)
+}
+
+func (c *Client) targetMessage(taskID domain.TaskID, taskType domain.Type, color string, mode domain.DeliveryMode) *messaging.Message {
+	multicast := c.buildMessage(getTemplate(taskType), taskID, taskType, c.iconURL(taskType, color), mode)
+
+	return &messaging.Message{
+		Data:         multicast.Data,
+		Notification: multicast.Notification,
+		Android:      multicast.Android,
+		Webpush:      multicast.Webpush,
+		APNS:         multicast.APNS,
+	}
+}
+
+// sendToTarget sends a topic or condition message, retrying retryable failures
+// like sendBatch does. The single result is labelled with target.
+func (c *Client) sendToTarget(ctx context.Context, target string, message *messaging.Message) *model.BulkResult {
+	result := model.TokenResult{Token: target, Channel: domain.ChannelFCM}
+	var hint time.Duration
+
+	for attempt := 1; ; attempt++ {
+		result.Attempts = attempt
+
+		messageID, err := c.send(ctx, message)
+		if err == nil {
+			result.Success = true
+			result.MessageID = messageID
+			result.Error = ""
+			result.ErrorCode = domain.ErrorCodeNone
+			break
+		}
+
+		// A single send has no per-token response, so an unclassified error
+		// is a transport failure rather than a problem with the target.
+		code := classifyError(err)
+		if code == domain.ErrorCodeUnknown {
+			code = domain.ErrorCodeUnavailable
+		}
+		result.Error = err.Error()
+		result.ErrorCode = code
+		hint = retryAfter(err)
+
+		slog.Warn("FCM send failed for target",
+			"target", target,
+			"attempt", attempt,
+			"error_code", code.String(),
+			"error", err.Error(),
+		)
+
+		if !code.IsRetryable() || rejectedLocally(err) || attempt >= c.retry.MaxAttempts {
+			break
+		}
+
+		if !retry.SleepWithinDeadline(ctx, c.retry.Backoff(attempt, hint)) {
+			break
+		}
+	}
+
+	bulk := &model.BulkResult{
+		Total:   1,
+		Status:  domain.DeliveryStatusComplete,
+		Results: []model.TokenResult{result},
+	}
+	if result.Success {
+		bulk.SuccessCount = 1
+	} else {
+		bulk.FailureCount = 1
+		bulk.Status = domain.DeliveryStatusFailed
+		bulk.RetryAfter = hint
+	}
+
+	return bulk
+}
+
+func (c *Client) send(ctx context.Context, message *messaging.Message) (string, error) {
+	ticket, err := c.breaker.allow()
+	if err != nil {
+		return "", err
+	}
+	if err := c.rateLimiter.Wait(ctx, 1); err != nil {
+		c.breaker.release(ticket)
+		return "", err
+	}
+
+	var messageID string
+	if c.dryRun {
+		messageID, err = c.sender.SendDryRun(ctx, message)
+	} else {
+		messageID, err = c.sender.Send(ctx, message)
+	}
+	c.breaker.record(ticket, err)
+
+	return messageID, err
+}
+
+// TopicResult is the outcome of a topic subscription change.
+type TopicResult struct {
+	SuccessCount int
+	FailureCount int
+	Errors       []TopicError
+}
+
+// TopicError describes a token whose subscription could not be changed.
+type TopicError struct {
+	Index  int
+	Token  string
+	Reason string
+}
+
+// SubscribeToTopic subscribes up to 1000 tokens to topic.
+func (c *Client) SubscribeToTopic(ctx context.Context, tokens []domain.FCMToken, topic domain.Topic) (*TopicResult, error) {
+	tokenStrings := domain.ToStrings(tokens)
+
+	resp, err := c.sender.SubscribeToTopic(ctx, tokenStrings, topic.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return newTopicResult(resp, tokenStrings), nil
+}
+
+// UnsubscribeFromTopic unsubscribes up to 1000 tokens from topic.
+func (c *Client) UnsubscribeFromTopic(ctx context.Context, tokens []domain.FCMToken, topic domain.Topic) (*TopicResult, error) {
+	tokenStrings := domain.ToStrings(tokens)
+
+	resp, err := c.sender.UnsubscribeFromTopic(ctx, tokenStrings, topic.String())
+	if err != nil {
+		return nil, err
+	}
+
+	return newTopicResult(resp, tokenStrings), nil
+}
+
+func newTopicResult(resp *messaging.TopicManagementResponse, tokens []string) *TopicResult {
+	result := &TopicResult{
+		SuccessCount: resp.SuccessCount,
+		FailureCount: resp.FailureCount,
+		Errors:       make([]TopicError, 0, len(resp.Errors)),
+	}
+
+	for _, e := range resp.Errors {
+		topicErr := TopicError{Index: e.Index, Reason: e.Reason}
+		if e.Index >= 0 && e.Index < len(tokens) {
+			topicErr.Token = tokens[e.Index]
+		}
+		result.Errors = append(result.Errors, topicErr)
+	}
+
+	return result
+}
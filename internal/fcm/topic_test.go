@@ -0,0 +1,73 @@
+package fcm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/fcm/fcmtest"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/retry"
+)
+
+func TestSendToTopic(t *testing.T) {
+	tests := []struct {
+		name         string
+		failTimes    int
+		err          error
+		wantSuccess  bool
+		wantAttempts int
+		wantCode     domain.ErrorCode
+	}{
+		{name: "success", wantSuccess: true, wantAttempts: 1},
+		{name: "transient failure is retried", failTimes: 2, err: errors.New("connection reset"), wantSuccess: true, wantAttempts: 3},
+		{name: "permanent failure", failTimes: -1, err: &SendError{Code: domain.ErrorCodeInvalidArgument, Message: "invalid"}, wantAttempts: 1, wantCode: domain.ErrorCodeInvalidArgument},
+		{name: "retries exhausted", failTimes: -1, err: errors.New("connection reset"), wantAttempts: 3, wantCode: domain.ErrorCodeUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := fcmtest.NewSender()
+			if tt.err != nil {
+				sender.FailTokenTimes("team-a", tt.failTimes, tt.err)
+			}
+			client := NewClientWithSender(sender, Config{
+				Retry: retry.Policy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond},
+			})
+
+			result := client.SendToTopic(context.Background(), domain.Topic("team-a"), testTaskID, domain.TypeShort, "", domain.DeliveryModeNotification)
+
+			r := result.Results[0]
+			if r.Token != "/topics/team-a" || r.Success != tt.wantSuccess || r.Attempts != tt.wantAttempts || r.ErrorCode != tt.wantCode {
+				t.Errorf("unexpected result: %+v", r)
+			}
+			wantStatus := domain.DeliveryStatusComplete
+			if !tt.wantSuccess {
+				wantStatus = domain.DeliveryStatusFailed
+			}
+			if result.Status != wantStatus {
+				t.Errorf("expected status %s, got %s", wantStatus, result.Status)
+			}
+
+			message := sender.SentMessages()[0]
+			if message.Topic != "team-a" || message.Data["task_id"] != testTaskID.String() || message.Android == nil {
+				t.Errorf("unexpected message: %+v", message)
+			}
+		})
+	}
+}
+
+func TestSendToCondition(t *testing.T) {
+	sender := fcmtest.NewSender()
+	client := NewClientWithSender(sender, Config{})
+	condition := domain.Condition("'team-a' in topics && !('muted' in topics)")
+
+	result := client.SendToCondition(context.Background(), condition, testTaskID, domain.TypeNear, "", domain.DeliveryModeNotification)
+	if result.SuccessCount != 1 || result.Results[0].Token != condition.String() {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if got := sender.SentMessages()[0].Condition; got != condition.String() {
+		t.Errorf("expected condition %q, got %q", condition, got)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	v1 "github.com/KasumiMercury/primind-notification-invoker/internal/gen/common/v1"
 	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
 	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
 	reflect "reflect"
 	sync "sync"
 	unsafe "unsafe"
@@ -23,13 +24,326 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// Channel is a delivery channel a recipient is reached through
+type Channel int32
+
+const (
+	Channel_CHANNEL_UNSPECIFIED Channel = 0
+	// Firebase Cloud Messaging; the address is an FCM registration token
+	Channel_CHANNEL_FCM Channel = 1
+	// native Web Push; the address is a JSON-serialised PushSubscription
+	// ({"endpoint": ..., "keys": {"p256dh": ..., "auth": ...}})
+	Channel_CHANNEL_WEBPUSH Channel = 2
+	// Slack / Discord-compatible incoming webhook; the address is the webhook URL
+	Channel_CHANNEL_WEBHOOK Channel = 3
+)
+
+// Enum value maps for Channel.
+var (
+	Channel_name = map[int32]string{
+		0: "CHANNEL_UNSPECIFIED",
+		1: "CHANNEL_FCM",
+		2: "CHANNEL_WEBPUSH",
+		3: "CHANNEL_WEBHOOK",
+	}
+	Channel_value = map[string]int32{
+		"CHANNEL_UNSPECIFIED": 0,
+		"CHANNEL_FCM":         1,
+		"CHANNEL_WEBPUSH":     2,
+		"CHANNEL_WEBHOOK":     3,
+	}
+)
+
+func (x Channel) Enum() *Channel {
+	p := new(Channel)
+	*p = x
+	return p
+}
+
+func (x Channel) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Channel) Descriptor() protoreflect.EnumDescriptor {
+	return file_notify_v1_notify_proto_enumTypes[0].Descriptor()
+}
+
+func (Channel) Type() protoreflect.EnumType {
+	return &file_notify_v1_notify_proto_enumTypes[0]
+}
+
+func (x Channel) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Channel.Descriptor instead.
+func (Channel) EnumDescriptor() ([]byte, []int) {
+	return file_notify_v1_notify_proto_rawDescGZIP(), []int{0}
+}
+
+// DeliveryMode controls whether FCM displays the reminder or hands it to the client
+type DeliveryMode int32
+
+const (
+	// treated as DELIVERY_MODE_NOTIFICATION
+	DeliveryMode_DELIVERY_MODE_UNSPECIFIED  DeliveryMode = 0
+	DeliveryMode_DELIVERY_MODE_NOTIFICATION DeliveryMode = 1
+	// data-only message; the title, body, icon and link are sent in the data payload for the client to render
+	DeliveryMode_DELIVERY_MODE_DATA_ONLY DeliveryMode = 2
+)
+
+// Enum value maps for DeliveryMode.
+var (
+	DeliveryMode_name = map[int32]string{
+		0: "DELIVERY_MODE_UNSPECIFIED",
+		1: "DELIVERY_MODE_NOTIFICATION",
+		2: "DELIVERY_MODE_DATA_ONLY",
+	}
+	DeliveryMode_value = map[string]int32{
+		"DELIVERY_MODE_UNSPECIFIED":  0,
+		"DELIVERY_MODE_NOTIFICATION": 1,
+		"DELIVERY_MODE_DATA_ONLY":    2,
+	}
+)
+
+func (x DeliveryMode) Enum() *DeliveryMode {
+	p := new(DeliveryMode)
+	*p = x
+	return p
+}
+
+func (x DeliveryMode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (DeliveryMode) Descriptor() protoreflect.EnumDescriptor {
+	return file_notify_v1_notify_proto_enumTypes[1].Descriptor()
+}
+
+func (DeliveryMode) Type() protoreflect.EnumType {
+	return &file_notify_v1_notify_proto_enumTypes[1]
+}
+
+func (x DeliveryMode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use DeliveryMode.Descriptor instead.
+func (DeliveryMode) EnumDescriptor() ([]byte, []int) {
+	return file_notify_v1_notify_proto_rawDescGZIP(), []int{1}
+}
+
+// ErrorCode classifies why delivery to a single token failed
+type ErrorCode int32
+
+const (
+	ErrorCode_ERROR_CODE_UNSPECIFIED        ErrorCode = 0
+	ErrorCode_ERROR_CODE_UNKNOWN            ErrorCode = 1
+	ErrorCode_ERROR_CODE_INVALID_ARGUMENT   ErrorCode = 2
+	ErrorCode_ERROR_CODE_UNREGISTERED       ErrorCode = 3
+	ErrorCode_ERROR_CODE_SENDER_ID_MISMATCH ErrorCode = 4
+	ErrorCode_ERROR_CODE_QUOTA_EXCEEDED     ErrorCode = 5
+	ErrorCode_ERROR_CODE_UNAVAILABLE        ErrorCode = 6
+	ErrorCode_ERROR_CODE_INTERNAL           ErrorCode = 7
+	// the Web Push subscription expired or was unsubscribed (404 / 410)
+	ErrorCode_ERROR_CODE_SUBSCRIPTION_GONE ErrorCode = 8
+)
+
+// Enum value maps for ErrorCode.
+var (
+	ErrorCode_name = map[int32]string{
+		0: "ERROR_CODE_UNSPECIFIED",
+		1: "ERROR_CODE_UNKNOWN",
+		2: "ERROR_CODE_INVALID_ARGUMENT",
+		3: "ERROR_CODE_UNREGISTERED",
+		4: "ERROR_CODE_SENDER_ID_MISMATCH",
+		5: "ERROR_CODE_QUOTA_EXCEEDED",
+		6: "ERROR_CODE_UNAVAILABLE",
+		7: "ERROR_CODE_INTERNAL",
+		8: "ERROR_CODE_SUBSCRIPTION_GONE",
+	}
+	ErrorCode_value = map[string]int32{
+		"ERROR_CODE_UNSPECIFIED":        0,
+		"ERROR_CODE_UNKNOWN":            1,
+		"ERROR_CODE_INVALID_ARGUMENT":   2,
+		"ERROR_CODE_UNREGISTERED":       3,
+		"ERROR_CODE_SENDER_ID_MISMATCH": 4,
+		"ERROR_CODE_QUOTA_EXCEEDED":     5,
+		"ERROR_CODE_UNAVAILABLE":        6,
+		"ERROR_CODE_INTERNAL":           7,
+		"ERROR_CODE_SUBSCRIPTION_GONE":  8,
+	}
+)
+
+func (x ErrorCode) Enum() *ErrorCode {
+	p := new(ErrorCode)
+	*p = x
+	return p
+}
+
+func (x ErrorCode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ErrorCode) Descriptor() protoreflect.EnumDescriptor {
+	return file_notify_v1_notify_proto_enumTypes[2].Descriptor()
+}
+
+func (ErrorCode) Type() protoreflect.EnumType {
+	return &file_notify_v1_notify_proto_enumTypes[2]
+}
+
+func (x ErrorCode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ErrorCode.Descriptor instead.
+func (ErrorCode) EnumDescriptor() ([]byte, []int) {
+	return file_notify_v1_notify_proto_rawDescGZIP(), []int{2}
+}
+
+// DeliveryStatus summarises how much of a bulk send reached FCM
+type DeliveryStatus int32
+
+const (
+	DeliveryStatus_DELIVERY_STATUS_UNSPECIFIED DeliveryStatus = 0
+	// every batch reached FCM; individual tokens may still have failed
+	DeliveryStatus_DELIVERY_STATUS_COMPLETE DeliveryStatus = 1
+	// some batches failed before reaching FCM; their tokens are marked as failed
+	DeliveryStatus_DELIVERY_STATUS_PARTIAL DeliveryStatus = 2
+	// no batch reached FCM
+	DeliveryStatus_DELIVERY_STATUS_FAILED DeliveryStatus = 3
+)
+
+// Enum value maps for DeliveryStatus.
+var (
+	DeliveryStatus_name = map[int32]string{
+		0: "DELIVERY_STATUS_UNSPECIFIED",
+		1: "DELIVERY_STATUS_COMPLETE",
+		2: "DELIVERY_STATUS_PARTIAL",
+		3: "DELIVERY_STATUS_FAILED",
+	}
+	DeliveryStatus_value = map[string]int32{
+		"DELIVERY_STATUS_UNSPECIFIED": 0,
+		"DELIVERY_STATUS_COMPLETE":    1,
+		"DELIVERY_STATUS_PARTIAL":     2,
+		"DELIVERY_STATUS_FAILED":      3,
+	}
+)
+
+func (x DeliveryStatus) Enum() *DeliveryStatus {
+	p := new(DeliveryStatus)
+	*p = x
+	return p
+}
+
+func (x DeliveryStatus) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (DeliveryStatus) Descriptor() protoreflect.EnumDescriptor {
+	return file_notify_v1_notify_proto_enumTypes[3].Descriptor()
+}
+
+func (DeliveryStatus) Type() protoreflect.EnumType {
+	return &file_notify_v1_notify_proto_enumTypes[3]
+}
+
+func (x DeliveryStatus) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use DeliveryStatus.Descriptor instead.
+func (DeliveryStatus) EnumDescriptor() ([]byte, []int) {
+	return file_notify_v1_notify_proto_rawDescGZIP(), []int{3}
+}
+
+// JobState is the lifecycle stage of a notification sent in the background
+type JobState int32
+
+const (
+	JobState_JOB_STATE_UNSPECIFIED JobState = 0
+	JobState_JOB_STATE_QUEUED      JobState = 1
+	JobState_JOB_STATE_RUNNING     JobState = 2
+	// the send finished; response holds its results
+	JobState_JOB_STATE_SUCCEEDED JobState = 3
+	// the send failed; status and response or error say why
+	JobState_JOB_STATE_FAILED JobState = 4
+)
+
+// Enum value maps for JobState.
+var (
+	JobState_name = map[int32]string{
+		0: "JOB_STATE_UNSPECIFIED",
+		1: "JOB_STATE_QUEUED",
+		2: "JOB_STATE_RUNNING",
+		3: "JOB_STATE_SUCCEEDED",
+		4: "JOB_STATE_FAILED",
+	}
+	JobState_value = map[string]int32{
+		"JOB_STATE_UNSPECIFIED": 0,
+		"JOB_STATE_QUEUED":      1,
+		"JOB_STATE_RUNNING":     2,
+		"JOB_STATE_SUCCEEDED":   3,
+		"JOB_STATE_FAILED":      4,
+	}
+)
+
+func (x JobState) Enum() *JobState {
+	p := new(JobState)
+	*p = x
+	return p
+}
+
+func (x JobState) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (JobState) Descriptor() protoreflect.EnumDescriptor {
+	return file_notify_v1_notify_proto_enumTypes[4].Descriptor()
+}
+
+func (JobState) Type() protoreflect.EnumType {
+	return &file_notify_v1_notify_proto_enumTypes[4]
+}
+
+func (x JobState) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use JobState.Descriptor instead.
+func (JobState) EnumDescriptor() ([]byte, []int) {
+	return file_notify_v1_notify_proto_rawDescGZIP(), []int{4}
+}
+
 // NotificationRequest is sent from throttling via primind-tasks to notification-invoker
 type NotificationRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Tokens        []string               `protobuf:"bytes,1,rep,name=tokens,proto3" json:"tokens,omitempty"`
-	TaskId        string                 `protobuf:"bytes,2,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
-	TaskType      v1.TaskType            `protobuf:"varint,3,opt,name=task_type,json=taskType,proto3,enum=common.v1.TaskType" json:"task_type,omitempty"`
-	Color         string                 `protobuf:"bytes,4,opt,name=color,proto3" json:"color,omitempty"`
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Tokens   []string               `protobuf:"bytes,1,rep,name=tokens,proto3" json:"tokens,omitempty"`
+	TaskId   string                 `protobuf:"bytes,2,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	TaskType v1.TaskType            `protobuf:"varint,3,opt,name=task_type,json=taskType,proto3,enum=common.v1.TaskType" json:"task_type,omitempty"`
+	Color    string                 `protobuf:"bytes,4,opt,name=color,proto3" json:"color,omitempty"`
+	// topic is an FCM topic name, with or without the "/topics/" prefix
+	Topic string `protobuf:"bytes,5,opt,name=topic,proto3" json:"topic,omitempty"`
+	// condition is an FCM topic condition, e.g. "'team-a' in topics || 'team-b' in topics"
+	Condition string `protobuf:"bytes,6,opt,name=condition,proto3" json:"condition,omitempty"`
+	// delivery_mode selects between a displayed notification (the default) and a data-only message
+	DeliveryMode DeliveryMode `protobuf:"varint,7,opt,name=delivery_mode,json=deliveryMode,proto3,enum=notify.v1.DeliveryMode" json:"delivery_mode,omitempty"`
+	// dry_run validates the messages with FCM without delivering them to devices
+	DryRun bool `protobuf:"varint,8,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	// recipients addresses each recipient on its own delivery channel
+	Recipients []*Recipient `protobuf:"bytes,9,rep,name=recipients,proto3" json:"recipients,omitempty"`
+	// fallback_email receives the reminder by email when no recipient could be reached
+	FallbackEmail string `protobuf:"bytes,10,opt,name=fallback_email,json=fallbackEmail,proto3" json:"fallback_email,omitempty"`
+	// idempotency_key identifies redeliveries of the same reminder; it takes
+	// precedence over the Idempotency-Key and X-CloudTasks-TaskName headers
+	IdempotencyKey string `protobuf:"bytes,11,opt,name=idempotency_key,json=idempotencyKey,proto3" json:"idempotency_key,omitempty"`
+	// schedule_time is when the reminder was scheduled; with task_id it keys
+	// idempotency when no explicit key or Cloud Tasks task name is present
+	ScheduleTime *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=schedule_time,json=scheduleTime,proto3" json:"schedule_time,omitempty"`
+	// send_at holds the reminder until this time; it must fall within the
+	// scheduling horizon. An absent or past send_at sends immediately
+	SendAt        *timestamppb.Timestamp `protobuf:"bytes,13,opt,name=send_at,json=sendAt,proto3" json:"send_at,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -92,20 +406,149 @@ func (x *NotificationRequest) GetColor() string {
 	return ""
 }
 
-// TokenResult represents the result for a single FCM token
-type TokenResult struct {
+func (x *NotificationRequest) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *NotificationRequest) GetCondition() string {
+	if x != nil {
+		return x.Condition
+	}
+	return ""
+}
+
+func (x *NotificationRequest) GetDeliveryMode() DeliveryMode {
+	if x != nil {
+		return x.DeliveryMode
+	}
+	return DeliveryMode_DELIVERY_MODE_UNSPECIFIED
+}
+
+func (x *NotificationRequest) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+func (x *NotificationRequest) GetRecipients() []*Recipient {
+	if x != nil {
+		return x.Recipients
+	}
+	return nil
+}
+
+func (x *NotificationRequest) GetFallbackEmail() string {
+	if x != nil {
+		return x.FallbackEmail
+	}
+	return ""
+}
+
+func (x *NotificationRequest) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+func (x *NotificationRequest) GetScheduleTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ScheduleTime
+	}
+	return nil
+}
+
+func (x *NotificationRequest) GetSendAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.SendAt
+	}
+	return nil
+}
+
+// Recipient is a single address on a delivery channel
+type Recipient struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Token         string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
-	Success       bool                   `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"`
-	MessageId     string                 `protobuf:"bytes,3,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
-	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	Channel       Channel                `protobuf:"varint,1,opt,name=channel,proto3,enum=notify.v1.Channel" json:"channel,omitempty"`
+	Address       string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Recipient) Reset() {
+	*x = Recipient{}
+	mi := &file_notify_v1_notify_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Recipient) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Recipient) ProtoMessage() {}
+
+func (x *Recipient) ProtoReflect() protoreflect.Message {
+	mi := &file_notify_v1_notify_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Recipient.ProtoReflect.Descriptor instead.
+func (*Recipient) Descriptor() ([]byte, []int) {
+	return file_notify_v1_notify_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Recipient) GetChannel() Channel {
+	if x != nil {
+		return x.Channel
+	}
+	return Channel_CHANNEL_UNSPECIFIED
+}
+
+func (x *Recipient) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+// TokenResult represents the result for a single FCM token or recipient
+type TokenResult struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// token is the FCM token, the recipient address, or the topic / condition for topic sends.
+	// Web Push and webhook recipients are named by the origin of their endpoint,
+	// since their addresses hold secrets
+	Token     string `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	Success   bool   `protobuf:"varint,2,opt,name=success,proto3" json:"success,omitempty"`
+	MessageId string `protobuf:"bytes,3,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	Error     string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	// attempts is the number of sends made for this token, including retries
+	Attempts  int32     `protobuf:"varint,5,opt,name=attempts,proto3" json:"attempts,omitempty"`
+	ErrorCode ErrorCode `protobuf:"varint,6,opt,name=error_code,json=errorCode,proto3,enum=notify.v1.ErrorCode" json:"error_code,omitempty"`
+	// should_remove_token is set when the token is permanently invalid and should be pruned
+	ShouldRemoveToken bool `protobuf:"varint,7,opt,name=should_remove_token,json=shouldRemoveToken,proto3" json:"should_remove_token,omitempty"`
+	// channel is the delivery channel the token was sent through
+	Channel Channel `protobuf:"varint,8,opt,name=channel,proto3,enum=notify.v1.Channel" json:"channel,omitempty"`
+	// retryable is set when the token was never sent to, because the rate limit
+	// or the circuit breaker turned it away; sending to it again may reach it
+	Retryable     bool `protobuf:"varint,9,opt,name=retryable,proto3" json:"retryable,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *TokenResult) Reset() {
 	*x = TokenResult{}
-	mi := &file_notify_v1_notify_proto_msgTypes[1]
+	mi := &file_notify_v1_notify_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -117,7 +560,7 @@ func (x *TokenResult) String() string {
 func (*TokenResult) ProtoMessage() {}
 
 func (x *TokenResult) ProtoReflect() protoreflect.Message {
-	mi := &file_notify_v1_notify_proto_msgTypes[1]
+	mi := &file_notify_v1_notify_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -130,7 +573,7 @@ func (x *TokenResult) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TokenResult.ProtoReflect.Descriptor instead.
 func (*TokenResult) Descriptor() ([]byte, []int) {
-	return file_notify_v1_notify_proto_rawDescGZIP(), []int{1}
+	return file_notify_v1_notify_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *TokenResult) GetToken() string {
@@ -161,21 +604,73 @@ func (x *TokenResult) GetError() string {
 	return ""
 }
 
+func (x *TokenResult) GetAttempts() int32 {
+	if x != nil {
+		return x.Attempts
+	}
+	return 0
+}
+
+func (x *TokenResult) GetErrorCode() ErrorCode {
+	if x != nil {
+		return x.ErrorCode
+	}
+	return ErrorCode_ERROR_CODE_UNSPECIFIED
+}
+
+func (x *TokenResult) GetShouldRemoveToken() bool {
+	if x != nil {
+		return x.ShouldRemoveToken
+	}
+	return false
+}
+
+func (x *TokenResult) GetChannel() Channel {
+	if x != nil {
+		return x.Channel
+	}
+	return Channel_CHANNEL_UNSPECIFIED
+}
+
+func (x *TokenResult) GetRetryable() bool {
+	if x != nil {
+		return x.Retryable
+	}
+	return false
+}
+
 // NotificationResponse is the response from notification-invoker
 type NotificationResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Total         int32                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
-	SuccessCount  int32                  `protobuf:"varint,3,opt,name=success_count,json=successCount,proto3" json:"success_count,omitempty"`
-	FailureCount  int32                  `protobuf:"varint,4,opt,name=failure_count,json=failureCount,proto3" json:"failure_count,omitempty"`
-	Results       []*TokenResult         `protobuf:"bytes,5,rep,name=results,proto3" json:"results,omitempty"`
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Success      bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Total        int32                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	SuccessCount int32                  `protobuf:"varint,3,opt,name=success_count,json=successCount,proto3" json:"success_count,omitempty"`
+	FailureCount int32                  `protobuf:"varint,4,opt,name=failure_count,json=failureCount,proto3" json:"failure_count,omitempty"`
+	Results      []*TokenResult         `protobuf:"bytes,5,rep,name=results,proto3" json:"results,omitempty"`
+	Status       DeliveryStatus         `protobuf:"varint,6,opt,name=status,proto3,enum=notify.v1.DeliveryStatus" json:"status,omitempty"`
+	// retryable is set when a retry may succeed and is needed: no token was reached and one failed
+	// retryably, or the fallback email was due but could not be sent. A partial send is not retried
+	// as a whole; its results mark the tokens never sent to
+	Retryable bool `protobuf:"varint,7,opt,name=retryable,proto3" json:"retryable,omitempty"`
+	// dry_run is set when the messages were only validated and nothing was delivered
+	DryRun bool `protobuf:"varint,8,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	// fallback_used is set when the reminder was sent to the fallback email
+	FallbackUsed bool `protobuf:"varint,9,opt,name=fallback_used,json=fallbackUsed,proto3" json:"fallback_used,omitempty"`
+	// fallback_error describes why sending to the fallback email failed
+	FallbackError string `protobuf:"bytes,10,opt,name=fallback_error,json=fallbackError,proto3" json:"fallback_error,omitempty"`
+	// dead_lettered is set when the task ran out of attempts: it is acknowledged
+	// and written to the dead-letter sink instead of being retried
+	DeadLettered bool `protobuf:"varint,11,opt,name=dead_lettered,json=deadLettered,proto3" json:"dead_lettered,omitempty"`
+	// scheduled is set when the reminder is held until its send_at; nothing
+	// has been sent yet, so only total is set
+	Scheduled     bool `protobuf:"varint,12,opt,name=scheduled,proto3" json:"scheduled,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *NotificationResponse) Reset() {
 	*x = NotificationResponse{}
-	mi := &file_notify_v1_notify_proto_msgTypes[2]
+	mi := &file_notify_v1_notify_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -187,7 +682,7 @@ func (x *NotificationResponse) String() string {
 func (*NotificationResponse) ProtoMessage() {}
 
 func (x *NotificationResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_notify_v1_notify_proto_msgTypes[2]
+	mi := &file_notify_v1_notify_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -200,7 +695,7 @@ func (x *NotificationResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use NotificationResponse.ProtoReflect.Descriptor instead.
 func (*NotificationResponse) Descriptor() ([]byte, []int) {
-	return file_notify_v1_notify_proto_rawDescGZIP(), []int{2}
+	return file_notify_v1_notify_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *NotificationResponse) GetSuccess() bool {
@@ -238,30 +733,515 @@ func (x *NotificationResponse) GetResults() []*TokenResult {
 	return nil
 }
 
-// ErrorResponse is the standard error response for notify service
-type ErrorResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+func (x *NotificationResponse) GetStatus() DeliveryStatus {
+	if x != nil {
+		return x.Status
+	}
+	return DeliveryStatus_DELIVERY_STATUS_UNSPECIFIED
 }
 
-func (x *ErrorResponse) Reset() {
-	*x = ErrorResponse{}
-	mi := &file_notify_v1_notify_proto_msgTypes[3]
-	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-	ms.StoreMessageInfo(mi)
+func (x *NotificationResponse) GetRetryable() bool {
+	if x != nil {
+		return x.Retryable
+	}
+	return false
 }
 
-func (x *ErrorResponse) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *NotificationResponse) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
 }
 
-func (*ErrorResponse) ProtoMessage() {}
-
-func (x *ErrorResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_notify_v1_notify_proto_msgTypes[3]
+func (x *NotificationResponse) GetFallbackUsed() bool {
+	if x != nil {
+		return x.FallbackUsed
+	}
+	return false
+}
+
+func (x *NotificationResponse) GetFallbackError() string {
+	if x != nil {
+		return x.FallbackError
+	}
+	return ""
+}
+
+func (x *NotificationResponse) GetDeadLettered() bool {
+	if x != nil {
+		return x.DeadLettered
+	}
+	return false
+}
+
+func (x *NotificationResponse) GetScheduled() bool {
+	if x != nil {
+		return x.Scheduled
+	}
+	return false
+}
+
+// BatchNotificationRequest sends several notifications in one call; each
+// request is validated and sent on its own
+type BatchNotificationRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Requests      []*NotificationRequest `protobuf:"bytes,1,rep,name=requests,proto3" json:"requests,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchNotificationRequest) Reset() {
+	*x = BatchNotificationRequest{}
+	mi := &file_notify_v1_notify_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchNotificationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchNotificationRequest) ProtoMessage() {}
+
+func (x *BatchNotificationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_notify_v1_notify_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchNotificationRequest.ProtoReflect.Descriptor instead.
+func (*BatchNotificationRequest) Descriptor() ([]byte, []int) {
+	return file_notify_v1_notify_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *BatchNotificationRequest) GetRequests() []*NotificationRequest {
+	if x != nil {
+		return x.Requests
+	}
+	return nil
+}
+
+// BatchNotificationResult is the outcome of one request of a batch
+type BatchNotificationResult struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// index is the position of the request in the batch
+	Index int32 `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	// status is the HTTP status /notify would have answered the request with
+	Status int32 `protobuf:"varint,2,opt,name=status,proto3" json:"status,omitempty"`
+	// response is set when the request was sent
+	Response *NotificationResponse `protobuf:"bytes,3,opt,name=response,proto3" json:"response,omitempty"`
+	// error is set when the request was not sent
+	Error string `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	// retryable is set when sending the request again may succeed
+	Retryable     bool `protobuf:"varint,5,opt,name=retryable,proto3" json:"retryable,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchNotificationResult) Reset() {
+	*x = BatchNotificationResult{}
+	mi := &file_notify_v1_notify_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchNotificationResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchNotificationResult) ProtoMessage() {}
+
+func (x *BatchNotificationResult) ProtoReflect() protoreflect.Message {
+	mi := &file_notify_v1_notify_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchNotificationResult.ProtoReflect.Descriptor instead.
+func (*BatchNotificationResult) Descriptor() ([]byte, []int) {
+	return file_notify_v1_notify_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *BatchNotificationResult) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *BatchNotificationResult) GetStatus() int32 {
+	if x != nil {
+		return x.Status
+	}
+	return 0
+}
+
+func (x *BatchNotificationResult) GetResponse() *NotificationResponse {
+	if x != nil {
+		return x.Response
+	}
+	return nil
+}
+
+func (x *BatchNotificationResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *BatchNotificationResult) GetRetryable() bool {
+	if x != nil {
+		return x.Retryable
+	}
+	return false
+}
+
+// BatchNotificationResponse is the response to a BatchNotificationRequest
+type BatchNotificationResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// results holds one result per request, in request order
+	Results []*BatchNotificationResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	Total   int32                      `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	// success_count counts requests with a 2xx status
+	SuccessCount  int32 `protobuf:"varint,3,opt,name=success_count,json=successCount,proto3" json:"success_count,omitempty"`
+	FailureCount  int32 `protobuf:"varint,4,opt,name=failure_count,json=failureCount,proto3" json:"failure_count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchNotificationResponse) Reset() {
+	*x = BatchNotificationResponse{}
+	mi := &file_notify_v1_notify_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchNotificationResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchNotificationResponse) ProtoMessage() {}
+
+func (x *BatchNotificationResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_notify_v1_notify_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchNotificationResponse.ProtoReflect.Descriptor instead.
+func (*BatchNotificationResponse) Descriptor() ([]byte, []int) {
+	return file_notify_v1_notify_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *BatchNotificationResponse) GetResults() []*BatchNotificationResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+func (x *BatchNotificationResponse) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *BatchNotificationResponse) GetSuccessCount() int32 {
+	if x != nil {
+		return x.SuccessCount
+	}
+	return 0
+}
+
+func (x *BatchNotificationResponse) GetFailureCount() int32 {
+	if x != nil {
+		return x.FailureCount
+	}
+	return 0
+}
+
+// Job is a notification sent in the background, as returned by /notify with
+// Prefer: respond-async and by GET /jobs/{id}
+type Job struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Id    string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	State JobState               `protobuf:"varint,2,opt,name=state,proto3,enum=notify.v1.JobState" json:"state,omitempty"`
+	// total is the number of targets to reach; done counts those attempted so far
+	Total int32 `protobuf:"varint,3,opt,name=total,proto3" json:"total,omitempty"`
+	Done  int32 `protobuf:"varint,4,opt,name=done,proto3" json:"done,omitempty"`
+	// status is the HTTP status /notify would have answered with, once finished
+	Status int32 `protobuf:"varint,5,opt,name=status,proto3" json:"status,omitempty"`
+	// response holds the per-token results, once finished
+	Response *NotificationResponse `protobuf:"bytes,6,opt,name=response,proto3" json:"response,omitempty"`
+	// error is set when the job failed before sending
+	Error         string                 `protobuf:"bytes,7,opt,name=error,proto3" json:"error,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Job) Reset() {
+	*x = Job{}
+	mi := &file_notify_v1_notify_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Job) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Job) ProtoMessage() {}
+
+func (x *Job) ProtoReflect() protoreflect.Message {
+	mi := &file_notify_v1_notify_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Job.ProtoReflect.Descriptor instead.
+func (*Job) Descriptor() ([]byte, []int) {
+	return file_notify_v1_notify_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Job) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Job) GetState() JobState {
+	if x != nil {
+		return x.State
+	}
+	return JobState_JOB_STATE_UNSPECIFIED
+}
+
+func (x *Job) GetTotal() int32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *Job) GetDone() int32 {
+	if x != nil {
+		return x.Done
+	}
+	return 0
+}
+
+func (x *Job) GetStatus() int32 {
+	if x != nil {
+		return x.Status
+	}
+	return 0
+}
+
+func (x *Job) GetResponse() *NotificationResponse {
+	if x != nil {
+		return x.Response
+	}
+	return nil
+}
+
+func (x *Job) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Job) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Job) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+// ScheduledNotification is a reminder held until its send_at
+type ScheduledNotification struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	TaskId        string                 `protobuf:"bytes,2,opt,name=task_id,json=taskId,proto3" json:"task_id,omitempty"`
+	SendAt        *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=send_at,json=sendAt,proto3" json:"send_at,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	DryRun        bool                   `protobuf:"varint,5,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScheduledNotification) Reset() {
+	*x = ScheduledNotification{}
+	mi := &file_notify_v1_notify_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScheduledNotification) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScheduledNotification) ProtoMessage() {}
+
+func (x *ScheduledNotification) ProtoReflect() protoreflect.Message {
+	mi := &file_notify_v1_notify_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScheduledNotification.ProtoReflect.Descriptor instead.
+func (*ScheduledNotification) Descriptor() ([]byte, []int) {
+	return file_notify_v1_notify_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ScheduledNotification) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ScheduledNotification) GetTaskId() string {
+	if x != nil {
+		return x.TaskId
+	}
+	return ""
+}
+
+func (x *ScheduledNotification) GetSendAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.SendAt
+	}
+	return nil
+}
+
+func (x *ScheduledNotification) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *ScheduledNotification) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+// ScheduledNotificationList lists held reminders, as returned by
+// GET /admin/scheduled and DELETE /admin/scheduled/{task_id}
+type ScheduledNotificationList struct {
+	state         protoimpl.MessageState   `protogen:"open.v1"`
+	Notifications []*ScheduledNotification `protobuf:"bytes,1,rep,name=notifications,proto3" json:"notifications,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScheduledNotificationList) Reset() {
+	*x = ScheduledNotificationList{}
+	mi := &file_notify_v1_notify_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScheduledNotificationList) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScheduledNotificationList) ProtoMessage() {}
+
+func (x *ScheduledNotificationList) ProtoReflect() protoreflect.Message {
+	mi := &file_notify_v1_notify_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScheduledNotificationList.ProtoReflect.Descriptor instead.
+func (*ScheduledNotificationList) Descriptor() ([]byte, []int) {
+	return file_notify_v1_notify_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ScheduledNotificationList) GetNotifications() []*ScheduledNotification {
+	if x != nil {
+		return x.Notifications
+	}
+	return nil
+}
+
+// ErrorResponse is the standard error response for notify service
+type ErrorResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Error         string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ErrorResponse) Reset() {
+	*x = ErrorResponse{}
+	mi := &file_notify_v1_notify_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ErrorResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ErrorResponse) ProtoMessage() {}
+
+func (x *ErrorResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_notify_v1_notify_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -274,7 +1254,7 @@ func (x *ErrorResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ErrorResponse.ProtoReflect.Descriptor instead.
 func (*ErrorResponse) Descriptor() ([]byte, []int) {
-	return file_notify_v1_notify_proto_rawDescGZIP(), []int{3}
+	return file_notify_v1_notify_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *ErrorResponse) GetSuccess() bool {
@@ -291,31 +1271,328 @@ func (x *ErrorResponse) GetError() string {
 	return ""
 }
 
+// TopicSubscriptionRequest subscribes or unsubscribes FCM tokens to a topic
+type TopicSubscriptionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Topic         string                 `protobuf:"bytes,1,opt,name=topic,proto3" json:"topic,omitempty"`
+	Tokens        []string               `protobuf:"bytes,2,rep,name=tokens,proto3" json:"tokens,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TopicSubscriptionRequest) Reset() {
+	*x = TopicSubscriptionRequest{}
+	mi := &file_notify_v1_notify_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TopicSubscriptionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TopicSubscriptionRequest) ProtoMessage() {}
+
+func (x *TopicSubscriptionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_notify_v1_notify_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TopicSubscriptionRequest.ProtoReflect.Descriptor instead.
+func (*TopicSubscriptionRequest) Descriptor() ([]byte, []int) {
+	return file_notify_v1_notify_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *TopicSubscriptionRequest) GetTopic() string {
+	if x != nil {
+		return x.Topic
+	}
+	return ""
+}
+
+func (x *TopicSubscriptionRequest) GetTokens() []string {
+	if x != nil {
+		return x.Tokens
+	}
+	return nil
+}
+
+// TopicSubscriptionError describes a token that could not be (un)subscribed
+type TopicSubscriptionError struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Index         int32                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Token         string                 `protobuf:"bytes,2,opt,name=token,proto3" json:"token,omitempty"`
+	Reason        string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TopicSubscriptionError) Reset() {
+	*x = TopicSubscriptionError{}
+	mi := &file_notify_v1_notify_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TopicSubscriptionError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TopicSubscriptionError) ProtoMessage() {}
+
+func (x *TopicSubscriptionError) ProtoReflect() protoreflect.Message {
+	mi := &file_notify_v1_notify_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TopicSubscriptionError.ProtoReflect.Descriptor instead.
+func (*TopicSubscriptionError) Descriptor() ([]byte, []int) {
+	return file_notify_v1_notify_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *TopicSubscriptionError) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *TopicSubscriptionError) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *TopicSubscriptionError) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+// TopicSubscriptionResponse is the result of a topic (un)subscription
+type TopicSubscriptionResponse struct {
+	state         protoimpl.MessageState    `protogen:"open.v1"`
+	Success       bool                      `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	SuccessCount  int32                     `protobuf:"varint,2,opt,name=success_count,json=successCount,proto3" json:"success_count,omitempty"`
+	FailureCount  int32                     `protobuf:"varint,3,opt,name=failure_count,json=failureCount,proto3" json:"failure_count,omitempty"`
+	Errors        []*TopicSubscriptionError `protobuf:"bytes,4,rep,name=errors,proto3" json:"errors,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TopicSubscriptionResponse) Reset() {
+	*x = TopicSubscriptionResponse{}
+	mi := &file_notify_v1_notify_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TopicSubscriptionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TopicSubscriptionResponse) ProtoMessage() {}
+
+func (x *TopicSubscriptionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_notify_v1_notify_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TopicSubscriptionResponse.ProtoReflect.Descriptor instead.
+func (*TopicSubscriptionResponse) Descriptor() ([]byte, []int) {
+	return file_notify_v1_notify_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *TopicSubscriptionResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *TopicSubscriptionResponse) GetSuccessCount() int32 {
+	if x != nil {
+		return x.SuccessCount
+	}
+	return 0
+}
+
+func (x *TopicSubscriptionResponse) GetFailureCount() int32 {
+	if x != nil {
+		return x.FailureCount
+	}
+	return 0
+}
+
+func (x *TopicSubscriptionResponse) GetErrors() []*TopicSubscriptionError {
+	if x != nil {
+		return x.Errors
+	}
+	return nil
+}
+
 var File_notify_v1_notify_proto protoreflect.FileDescriptor
 
 const file_notify_v1_notify_proto_rawDesc = "" +
 	"\n" +
-	"\x16notify/v1/notify.proto\x12\tnotify.v1\x1a\x1bbuf/validate/validate.proto\x1a\x16common/v1/common.proto\"\xb2\x01\n" +
-	"\x13NotificationRequest\x12 \n" +
-	"\x06tokens\x18\x01 \x03(\tB\b\xbaH\x05\x92\x01\x02\b\x01R\x06tokens\x12!\n" +
+	"\x16notify/v1/notify.proto\x12\tnotify.v1\x1a\x1bbuf/validate/validate.proto\x1a\x16common/v1/common.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xa5\x05\n" +
+	"\x13NotificationRequest\x12\x16\n" +
+	"\x06tokens\x18\x01 \x03(\tR\x06tokens\x12!\n" +
 	"\atask_id\x18\x02 \x01(\tB\b\xbaH\x05r\x03\xb0\x01\x01R\x06taskId\x12@\n" +
 	"\ttask_type\x18\x03 \x01(\x0e2\x13.common.v1.TaskTypeB\x0e\xbaH\v\x82\x01\b\x18\x01\x18\x02\x18\x03\x18\x04R\btaskType\x12\x14\n" +
-	"\x05color\x18\x04 \x01(\tR\x05color\"r\n" +
+	"\x05color\x18\x04 \x01(\tR\x05color\x12;\n" +
+	"\x05topic\x18\x05 \x01(\tB%\xbaH\"r 2\x1e^(/topics/)?[a-zA-Z0-9-_.~%]+$R\x05topic\x12\x1c\n" +
+	"\tcondition\x18\x06 \x01(\tR\tcondition\x12F\n" +
+	"\rdelivery_mode\x18\a \x01(\x0e2\x17.notify.v1.DeliveryModeB\b\xbaH\x05\x82\x01\x02\x10\x01R\fdeliveryMode\x12\x17\n" +
+	"\adry_run\x18\b \x01(\bR\x06dryRun\x124\n" +
+	"\n" +
+	"recipients\x18\t \x03(\v2\x14.notify.v1.RecipientR\n" +
+	"recipients\x121\n" +
+	"\x0efallback_email\x18\n" +
+	" \x01(\tB\n" +
+	"\xbaH\a\xd8\x01\x01r\x02`\x01R\rfallbackEmail\x121\n" +
+	"\x0fidempotency_key\x18\v \x01(\tB\b\xbaH\x05r\x03\x18\x80\x02R\x0eidempotencyKey\x12?\n" +
+	"\rschedule_time\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\fscheduleTime\x123\n" +
+	"\asend_at\x18\r \x01(\v2\x1a.google.protobuf.TimestampR\x06sendAt:-\xbaH*\"(\n" +
+	"\x06tokens\n" +
+	"\x05topic\n" +
+	"\tcondition\n" +
+	"\n" +
+	"recipients\x10\x01\"h\n" +
+	"\tRecipient\x128\n" +
+	"\achannel\x18\x01 \x01(\x0e2\x12.notify.v1.ChannelB\n" +
+	"\xbaH\a\x82\x01\x04\x10\x01 \x00R\achannel\x12!\n" +
+	"\aaddress\x18\x02 \x01(\tB\a\xbaH\x04r\x02\x10\x01R\aaddress\"\xbf\x02\n" +
 	"\vTokenResult\x12\x14\n" +
 	"\x05token\x18\x01 \x01(\tR\x05token\x12\x18\n" +
 	"\asuccess\x18\x02 \x01(\bR\asuccess\x12\x1d\n" +
 	"\n" +
 	"message_id\x18\x03 \x01(\tR\tmessageId\x12\x14\n" +
-	"\x05error\x18\x04 \x01(\tR\x05error\"\xc2\x01\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\x12\x1a\n" +
+	"\battempts\x18\x05 \x01(\x05R\battempts\x123\n" +
+	"\n" +
+	"error_code\x18\x06 \x01(\x0e2\x14.notify.v1.ErrorCodeR\terrorCode\x12.\n" +
+	"\x13should_remove_token\x18\a \x01(\bR\x11shouldRemoveToken\x12,\n" +
+	"\achannel\x18\b \x01(\x0e2\x12.notify.v1.ChannelR\achannel\x12\x1c\n" +
+	"\tretryable\x18\t \x01(\bR\tretryable\"\xbb\x03\n" +
 	"\x14NotificationResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
 	"\x05total\x18\x02 \x01(\x05R\x05total\x12#\n" +
 	"\rsuccess_count\x18\x03 \x01(\x05R\fsuccessCount\x12#\n" +
 	"\rfailure_count\x18\x04 \x01(\x05R\ffailureCount\x120\n" +
-	"\aresults\x18\x05 \x03(\v2\x16.notify.v1.TokenResultR\aresults\"?\n" +
+	"\aresults\x18\x05 \x03(\v2\x16.notify.v1.TokenResultR\aresults\x121\n" +
+	"\x06status\x18\x06 \x01(\x0e2\x19.notify.v1.DeliveryStatusR\x06status\x12\x1c\n" +
+	"\tretryable\x18\a \x01(\bR\tretryable\x12\x17\n" +
+	"\adry_run\x18\b \x01(\bR\x06dryRun\x12#\n" +
+	"\rfallback_used\x18\t \x01(\bR\ffallbackUsed\x12%\n" +
+	"\x0efallback_error\x18\n" +
+	" \x01(\tR\rfallbackError\x12#\n" +
+	"\rdead_lettered\x18\v \x01(\bR\fdeadLettered\x12\x1c\n" +
+	"\tscheduled\x18\f \x01(\bR\tscheduled\"V\n" +
+	"\x18BatchNotificationRequest\x12:\n" +
+	"\brequests\x18\x01 \x03(\v2\x1e.notify.v1.NotificationRequestR\brequests\"\xb8\x01\n" +
+	"\x17BatchNotificationResult\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x05R\x05index\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\x05R\x06status\x12;\n" +
+	"\bresponse\x18\x03 \x01(\v2\x1f.notify.v1.NotificationResponseR\bresponse\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\x12\x1c\n" +
+	"\tretryable\x18\x05 \x01(\bR\tretryable\"\xb9\x01\n" +
+	"\x19BatchNotificationResponse\x12<\n" +
+	"\aresults\x18\x01 \x03(\v2\".notify.v1.BatchNotificationResultR\aresults\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\x05R\x05total\x12#\n" +
+	"\rsuccess_count\x18\x03 \x01(\x05R\fsuccessCount\x12#\n" +
+	"\rfailure_count\x18\x04 \x01(\x05R\ffailureCount\"\xcb\x02\n" +
+	"\x03Job\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12)\n" +
+	"\x05state\x18\x02 \x01(\x0e2\x13.notify.v1.JobStateR\x05state\x12\x14\n" +
+	"\x05total\x18\x03 \x01(\x05R\x05total\x12\x12\n" +
+	"\x04done\x18\x04 \x01(\x05R\x04done\x12\x16\n" +
+	"\x06status\x18\x05 \x01(\x05R\x06status\x12;\n" +
+	"\bresponse\x18\x06 \x01(\v2\x1f.notify.v1.NotificationResponseR\bresponse\x12\x14\n" +
+	"\x05error\x18\a \x01(\tR\x05error\x129\n" +
+	"\n" +
+	"created_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"\xc9\x01\n" +
+	"\x15ScheduledNotification\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x17\n" +
+	"\atask_id\x18\x02 \x01(\tR\x06taskId\x123\n" +
+	"\asend_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x06sendAt\x129\n" +
+	"\n" +
+	"created_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12\x17\n" +
+	"\adry_run\x18\x05 \x01(\bR\x06dryRun\"c\n" +
+	"\x19ScheduledNotificationList\x12F\n" +
+	"\rnotifications\x18\x01 \x03(\v2 .notify.v1.ScheduledNotificationR\rnotifications\"?\n" +
 	"\rErrorResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x14\n" +
-	"\x05error\x18\x02 \x01(\tR\x05errorB\xb8\x01\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\"|\n" +
+	"\x18TopicSubscriptionRequest\x12;\n" +
+	"\x05topic\x18\x01 \x01(\tB%\xbaH\"r 2\x1e^(/topics/)?[a-zA-Z0-9-_.~%]+$R\x05topic\x12#\n" +
+	"\x06tokens\x18\x02 \x03(\tB\v\xbaH\b\x92\x01\x05\b\x01\x10\xe8\aR\x06tokens\"\\\n" +
+	"\x16TopicSubscriptionError\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x05R\x05index\x12\x14\n" +
+	"\x05token\x18\x02 \x01(\tR\x05token\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\"\xba\x01\n" +
+	"\x19TopicSubscriptionResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12#\n" +
+	"\rsuccess_count\x18\x02 \x01(\x05R\fsuccessCount\x12#\n" +
+	"\rfailure_count\x18\x03 \x01(\x05R\ffailureCount\x129\n" +
+	"\x06errors\x18\x04 \x03(\v2!.notify.v1.TopicSubscriptionErrorR\x06errors*]\n" +
+	"\aChannel\x12\x17\n" +
+	"\x13CHANNEL_UNSPECIFIED\x10\x00\x12\x0f\n" +
+	"\vCHANNEL_FCM\x10\x01\x12\x13\n" +
+	"\x0fCHANNEL_WEBPUSH\x10\x02\x12\x13\n" +
+	"\x0fCHANNEL_WEBHOOK\x10\x03*j\n" +
+	"\fDeliveryMode\x12\x1d\n" +
+	"\x19DELIVERY_MODE_UNSPECIFIED\x10\x00\x12\x1e\n" +
+	"\x1aDELIVERY_MODE_NOTIFICATION\x10\x01\x12\x1b\n" +
+	"\x17DELIVERY_MODE_DATA_ONLY\x10\x02*\x96\x02\n" +
+	"\tErrorCode\x12\x1a\n" +
+	"\x16ERROR_CODE_UNSPECIFIED\x10\x00\x12\x16\n" +
+	"\x12ERROR_CODE_UNKNOWN\x10\x01\x12\x1f\n" +
+	"\x1bERROR_CODE_INVALID_ARGUMENT\x10\x02\x12\x1b\n" +
+	"\x17ERROR_CODE_UNREGISTERED\x10\x03\x12!\n" +
+	"\x1dERROR_CODE_SENDER_ID_MISMATCH\x10\x04\x12\x1d\n" +
+	"\x19ERROR_CODE_QUOTA_EXCEEDED\x10\x05\x12\x1a\n" +
+	"\x16ERROR_CODE_UNAVAILABLE\x10\x06\x12\x17\n" +
+	"\x13ERROR_CODE_INTERNAL\x10\a\x12 \n" +
+	"\x1cERROR_CODE_SUBSCRIPTION_GONE\x10\b*\x88\x01\n" +
+	"\x0eDeliveryStatus\x12\x1f\n" +
+	"\x1bDELIVERY_STATUS_UNSPECIFIED\x10\x00\x12\x1c\n" +
+	"\x18DELIVERY_STATUS_COMPLETE\x10\x01\x12\x1b\n" +
+	"\x17DELIVERY_STATUS_PARTIAL\x10\x02\x12\x1a\n" +
+	"\x16DELIVERY_STATUS_FAILED\x10\x03*\x81\x01\n" +
+	"\bJobState\x12\x19\n" +
+	"\x15JOB_STATE_UNSPECIFIED\x10\x00\x12\x14\n" +
+	"\x10JOB_STATE_QUEUED\x10\x01\x12\x15\n" +
+	"\x11JOB_STATE_RUNNING\x10\x02\x12\x17\n" +
+	"\x13JOB_STATE_SUCCEEDED\x10\x03\x12\x14\n" +
+	"\x10JOB_STATE_FAILED\x10\x042j\n" +
+	"\x13NotificationService\x12S\n" +
+	"\x10SendNotification\x12\x1e.notify.v1.NotificationRequest\x1a\x1f.notify.v1.NotificationResponseB\xb8\x01\n" +
 	"\rcom.notify.v1B\vNotifyProtoP\x01ZUgithub.com/KasumiMercury/primind-notification-invoker/internal/gen/notify/v1;notifyv1\xa2\x02\x03NXX\xaa\x02\tNotify.V1\xca\x02\tNotify\\V1\xe2\x02\x15Notify\\V1\\GPBMetadata\xea\x02\n" +
 	"Notify::V1b\x06proto3"
 
@@ -331,22 +1608,60 @@ func file_notify_v1_notify_proto_rawDescGZIP() []byte {
 	return file_notify_v1_notify_proto_rawDescData
 }
 
-var file_notify_v1_notify_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_notify_v1_notify_proto_enumTypes = make([]protoimpl.EnumInfo, 5)
+var file_notify_v1_notify_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
 var file_notify_v1_notify_proto_goTypes = []any{
-	(*NotificationRequest)(nil),  // 0: notify.v1.NotificationRequest
-	(*TokenResult)(nil),          // 1: notify.v1.TokenResult
-	(*NotificationResponse)(nil), // 2: notify.v1.NotificationResponse
-	(*ErrorResponse)(nil),        // 3: notify.v1.ErrorResponse
-	(v1.TaskType)(0),             // 4: common.v1.TaskType
+	(Channel)(0),                      // 0: notify.v1.Channel
+	(DeliveryMode)(0),                 // 1: notify.v1.DeliveryMode
+	(ErrorCode)(0),                    // 2: notify.v1.ErrorCode
+	(DeliveryStatus)(0),               // 3: notify.v1.DeliveryStatus
+	(JobState)(0),                     // 4: notify.v1.JobState
+	(*NotificationRequest)(nil),       // 5: notify.v1.NotificationRequest
+	(*Recipient)(nil),                 // 6: notify.v1.Recipient
+	(*TokenResult)(nil),               // 7: notify.v1.TokenResult
+	(*NotificationResponse)(nil),      // 8: notify.v1.NotificationResponse
+	(*BatchNotificationRequest)(nil),  // 9: notify.v1.BatchNotificationRequest
+	(*BatchNotificationResult)(nil),   // 10: notify.v1.BatchNotificationResult
+	(*BatchNotificationResponse)(nil), // 11: notify.v1.BatchNotificationResponse
+	(*Job)(nil),                       // 12: notify.v1.Job
+	(*ScheduledNotification)(nil),     // 13: notify.v1.ScheduledNotification
+	(*ScheduledNotificationList)(nil), // 14: notify.v1.ScheduledNotificationList
+	(*ErrorResponse)(nil),             // 15: notify.v1.ErrorResponse
+	(*TopicSubscriptionRequest)(nil),  // 16: notify.v1.TopicSubscriptionRequest
+	(*TopicSubscriptionError)(nil),    // 17: notify.v1.TopicSubscriptionError
+	(*TopicSubscriptionResponse)(nil), // 18: notify.v1.TopicSubscriptionResponse
+	(v1.TaskType)(0),                  // 19: common.v1.TaskType
+	(*timestamppb.Timestamp)(nil),     // 20: google.protobuf.Timestamp
 }
 var file_notify_v1_notify_proto_depIdxs = []int32{
-	4, // 0: notify.v1.NotificationRequest.task_type:type_name -> common.v1.TaskType
-	1, // 1: notify.v1.NotificationResponse.results:type_name -> notify.v1.TokenResult
-	2, // [2:2] is the sub-list for method output_type
-	2, // [2:2] is the sub-list for method input_type
-	2, // [2:2] is the sub-list for extension type_name
-	2, // [2:2] is the sub-list for extension extendee
-	0, // [0:2] is the sub-list for field type_name
+	19, // 0: notify.v1.NotificationRequest.task_type:type_name -> common.v1.TaskType
+	1,  // 1: notify.v1.NotificationRequest.delivery_mode:type_name -> notify.v1.DeliveryMode
+	6,  // 2: notify.v1.NotificationRequest.recipients:type_name -> notify.v1.Recipient
+	20, // 3: notify.v1.NotificationRequest.schedule_time:type_name -> google.protobuf.Timestamp
+	20, // 4: notify.v1.NotificationRequest.send_at:type_name -> google.protobuf.Timestamp
+	0,  // 5: notify.v1.Recipient.channel:type_name -> notify.v1.Channel
+	2,  // 6: notify.v1.TokenResult.error_code:type_name -> notify.v1.ErrorCode
+	0,  // 7: notify.v1.TokenResult.channel:type_name -> notify.v1.Channel
+	7,  // 8: notify.v1.NotificationResponse.results:type_name -> notify.v1.TokenResult
+	3,  // 9: notify.v1.NotificationResponse.status:type_name -> notify.v1.DeliveryStatus
+	5,  // 10: notify.v1.BatchNotificationRequest.requests:type_name -> notify.v1.NotificationRequest
+	8,  // 11: notify.v1.BatchNotificationResult.response:type_name -> notify.v1.NotificationResponse
+	10, // 12: notify.v1.BatchNotificationResponse.results:type_name -> notify.v1.BatchNotificationResult
+	4,  // 13: notify.v1.Job.state:type_name -> notify.v1.JobState
+	8,  // 14: notify.v1.Job.response:type_name -> notify.v1.NotificationResponse
+	20, // 15: notify.v1.Job.created_at:type_name -> google.protobuf.Timestamp
+	20, // 16: notify.v1.Job.updated_at:type_name -> google.protobuf.Timestamp
+	20, // 17: notify.v1.ScheduledNotification.send_at:type_name -> google.protobuf.Timestamp
+	20, // 18: notify.v1.ScheduledNotification.created_at:type_name -> google.protobuf.Timestamp
+	13, // 19: notify.v1.ScheduledNotificationList.notifications:type_name -> notify.v1.ScheduledNotification
+	17, // 20: notify.v1.TopicSubscriptionResponse.errors:type_name -> notify.v1.TopicSubscriptionError
+	5,  // 21: notify.v1.NotificationService.SendNotification:input_type -> notify.v1.NotificationRequest
+	8,  // 22: notify.v1.NotificationService.SendNotification:output_type -> notify.v1.NotificationResponse
+	22, // [22:23] is the sub-list for method output_type
+	21, // [21:22] is the sub-list for method input_type
+	21, // [21:21] is the sub-list for extension type_name
+	21, // [21:21] is the sub-list for extension extendee
+	0,  // [0:21] is the sub-list for field type_name
 }
 
 func init() { file_notify_v1_notify_proto_init() }
@@ -359,13 +1674,14 @@ func file_notify_v1_notify_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_notify_v1_notify_proto_rawDesc), len(file_notify_v1_notify_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   4,
+			NumEnums:      5,
+			NumMessages:   14,
 			NumExtensions: 0,
-			NumServices:   0,
+			NumServices:   1,
 		},
 		GoTypes:           file_notify_v1_notify_proto_goTypes,
 		DependencyIndexes: file_notify_v1_notify_proto_depIdxs,
+		EnumInfos:         file_notify_v1_notify_proto_enumTypes,
 		MessageInfos:      file_notify_v1_notify_proto_msgTypes,
 	}.Build()
 	File_notify_v1_notify_proto = out.File
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// AdminAuth returns a middleware that admits requests carrying token as a
+// bearer token. The admin endpoints change and reveal state of every user,
+// so they are closed altogether when token is empty.
+func AdminAuth(token string) func(http.Handler) http.Handler {
+	// Hashing both sides makes the comparison constant-time regardless of length.
+	want := sha256.Sum256([]byte(token))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token == "" {
+				slog.Warn("admin request rejected, admin token not configured", "path", r.URL.Path)
+				respondProtoError(w, http.StatusForbidden, "admin endpoints are disabled")
+				return
+			}
+
+			given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			got := sha256.Sum256([]byte(given))
+			if !ok || subtle.ConstantTimeCompare(got[:], want[:]) != 1 {
+				slog.Warn("admin request rejected, invalid token", "path", r.URL.Path)
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				respondProtoError(w, http.StatusUnauthorized, "unauthorized")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	notifyv1 "github.com/KasumiMercury/primind-notification-invoker/internal/gen/notify/v1"
+)
+
+const (
+	defaultBatchMaxItems    = 100
+	defaultBatchConcurrency = 8
+)
+
+// SendNotificationBatch handles /notify/batch. Each request of the batch is
+// validated and sent on its own, as /notify would, and gets its own result.
+// Items share the handler's batch concurrency and the FCM rate limit.
+//
+// The batch answers 200 when every item got a 2xx status, 503 when any item
+// is retryable and the batch may be retried, and 207 otherwise. A 503 has
+// the whole batch retried, so it is only answered when every item has an
+// idempotency key and the idempotency store is enabled: items that already
+// went through are then replayed instead of sent again. A 207 is final,
+// since Cloud Tasks acknowledges it; its retryable items are flagged for the
+// caller to send again. Only a malformed batch is rejected outright.
+func (h *NotificationHandler) SendNotificationBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		slog.Warn("method not allowed", "method", r.Method, "path", r.URL.Path)
+		respondProtoError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	accept := responseContentType(r.Header.Get("Accept"))
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		slog.Error("failed to read request body", "error", err)
+		respondErrorAs(w, http.StatusBadRequest, accept, "failed to read request body")
+		return
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	if !isProtobuf(contentType) {
+		contentType = jsonContentType
+	}
+
+	// The batch is not validated as a whole, since that would reject it for
+	// a single invalid item.
+	var batch notifyv1.BatchNotificationRequest
+	if err := decodeMessage(body, contentType, &batch); err != nil {
+		slog.Error("failed to decode request body", "error", err)
+		respondErrorAs(w, http.StatusBadRequest, accept, err.Error())
+		return
+	}
+	if n := len(batch.Requests); n == 0 || n > h.batchMaxItems {
+		slog.Error("invalid batch size", "size", n, "max_items", h.batchMaxItems)
+		respondErrorAs(w, http.StatusBadRequest, accept,
+			fmt.Sprintf("batch must hold between 1 and %d requests, got %d", h.batchMaxItems, n))
+		return
+	}
+
+	d, err := headerDelivery(r.Header, nil)
+	if err != nil {
+		slog.Error("invalid dry run header", "error", err)
+		respondErrorAs(w, http.StatusBadRequest, accept, err.Error())
+		return
+	}
+
+	// Each item has its own deadline, so the batch as a whole may outlast
+	// the server's write timeout.
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		slog.Warn("failed to clear the write deadline", "error", err)
+	}
+
+	resp, retryable, replayable, retryAfter := h.processBatch(r.Context(), batch.Requests, d)
+
+	slog.Info("batch processed",
+		"total", resp.Total,
+		"success_count", resp.SuccessCount,
+		"failure_count", resp.FailureCount,
+		"retryable", retryable,
+		"replayable", replayable,
+	)
+
+	status := http.StatusOK
+	switch {
+	case retryable && replayable:
+		status = http.StatusServiceUnavailable
+		if retryAfter > 0 {
+			w.Header().Set("Retry-After", retryAfterSeconds(retryAfter))
+		}
+	case resp.FailureCount > 0:
+		status = http.StatusMultiStatus
+	}
+	respondMessage(w, status, accept, resp)
+}
+
+// processBatch sends every request of a batch and collects their results in
+// request order. retryable reports whether any item is retryable, and
+// retryAfter is the longest Retry-After hint among them. replayable reports
+// whether every item would be replayed from the idempotency store if the
+// batch were retried.
+func (h *NotificationHandler) processBatch(ctx context.Context, reqs []*notifyv1.NotificationRequest, d delivery) (resp *notifyv1.BatchNotificationResponse, retryable, replayable bool, retryAfter time.Duration) {
+	outcomes := make([]*outcome, len(reqs))
+	replayable = h.idempotency != nil
+
+	var wg sync.WaitGroup
+	for i, req := range reqs {
+		item := d.item(i)
+		if idempotencyKey(req, item) == "" {
+			replayable = false
+		}
+
+		select {
+		case h.batchSlots <- struct{}{}:
+		case <-ctx.Done():
+			outcomes[i] = failedOutcome(http.StatusServiceUnavailable, "batch canceled: "+ctx.Err().Error())
+			continue
+		}
+
+		wg.Go(func() {
+			defer func() { <-h.batchSlots }()
+
+			itemCtx := ctx
+			if h.batchItemTimeout > 0 {
+				var cancel context.CancelFunc
+				itemCtx, cancel = context.WithTimeout(ctx, h.batchItemTimeout)
+				defer cancel()
+			}
+			outcomes[i] = h.process(itemCtx, req, item)
+		})
+	}
+	wg.Wait()
+
+	resp = &notifyv1.BatchNotificationResponse{
+		Results: make([]*notifyv1.BatchNotificationResult, len(outcomes)),
+		Total:   int32(len(outcomes)),
+	}
+	for i, o := range outcomes {
+		resp.Results[i] = &notifyv1.BatchNotificationResult{
+			Index:     int32(i),
+			Status:    int32(o.Status),
+			Response:  o.Response,
+			Error:     o.Error,
+			Retryable: retryableStatus(o.Status),
+		}
+		if o.Status < http.StatusBadRequest {
+			resp.SuccessCount++
+		} else {
+			resp.FailureCount++
+		}
+		if retryableStatus(o.Status) {
+			retryable = true
+			retryAfter = max(retryAfter, o.RetryAfter)
+		}
+	}
+
+	return resp, retryable, replayable, retryAfter
+}
+
+// item returns the delivery of the i-th request of a batch. Keys taken from
+// the batch's headers get the index appended, so that each item is
+// deduplicated and dead-lettered on its own.
+func (d delivery) item(i int) delivery {
+	suffix := "#" + strconv.Itoa(i)
+	if d.IdempotencyKey != "" {
+		d.IdempotencyKey += suffix
+	}
+	if d.Attempt.TaskName != "" {
+		d.Attempt.TaskName += suffix
+	}
+	d.LogAttrs = append(d.LogAttrs[:len(d.LogAttrs):len(d.LogAttrs)], "batch_index", i)
+
+	return d
+}
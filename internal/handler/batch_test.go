@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/channel"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/fcm"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/fcm/fcmtest"
+	notifyv1 "github.com/KasumiMercury/primind-notification-invoker/internal/gen/notify/v1"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/idempotency"
+	pjson "github.com/KasumiMercury/primind-notification-invoker/internal/proto"
+)
+
+// newBatchHandler returns a handler sending through sender, with the FCM
+// channel registered.
+func newBatchHandler(sender *fcmtest.Sender, opts Options) *NotificationHandler {
+	client := fcm.NewClientWithSender(sender, fcm.Config{})
+	opts.Channels = channel.NewRegistry()
+	opts.Channels.Register(domain.ChannelFCM, client)
+
+	return NewNotificationHandler(client, opts)
+}
+
+func postBatch(h *NotificationHandler, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/notify/batch", strings.NewReader(body))
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.SendNotificationBatch(rec, req)
+	return rec
+}
+
+func batchOf(items ...string) string {
+	return `{"requests":[` + strings.Join(items, ",") + `]}`
+}
+
+func TestSendNotificationBatch_ItemsAreIndependent(t *testing.T) {
+	sender := fcmtest.NewSender()
+	sender.FailToken("dead", &fcm.SendError{Code: domain.ErrorCodeUnregistered, Message: "unregistered"})
+	h := newTestHandler(sender)
+
+	rec := postBatch(h, batchOf(
+		idempotentBody,
+		`{"tokens":["b"],"task_id":"not-a-uuid","task_type":"TASK_TYPE_SHORT"}`,
+		`{"tokens":["dead"],"task_id":"`+testTaskID+`","task_type":"TASK_TYPE_NEAR"}`,
+	), nil)
+
+	if rec.Code != http.StatusMultiStatus {
+		t.Fatalf("expected status 207, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp notifyv1.BatchNotificationResponse
+	if err := pjson.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Total != 3 || resp.SuccessCount != 2 || resp.FailureCount != 1 {
+		t.Fatalf("unexpected counts: %v", &resp)
+	}
+
+	wantStatus := []int32{http.StatusOK, http.StatusBadRequest, http.StatusOK}
+	for i, result := range resp.Results {
+		if result.Index != int32(i) || result.Status != wantStatus[i] {
+			t.Errorf("result %d: expected status %d, got %v", i, wantStatus[i], result)
+		}
+	}
+	if resp.Results[1].Error == "" || resp.Results[1].Response != nil {
+		t.Errorf("expected an error for the invalid item, got %v", resp.Results[1])
+	}
+	if resp.Results[2].Response.GetFailureCount() != 1 {
+		t.Errorf("expected the permanent failure in the item response, got %v", resp.Results[2])
+	}
+	if len(sender.Messages()) != 2 {
+		t.Errorf("expected two sends, got %d", len(sender.Messages()))
+	}
+}
+
+func TestSendNotificationBatch_RetryableItem(t *testing.T) {
+	flaky := `{"tokens":["flaky"],"task_id":"` + testTaskID + `","task_type":"TASK_TYPE_NEAR"}`
+
+	tests := []struct {
+		name       string
+		opts       Options
+		headers    map[string]string
+		wantStatus int
+	}{
+		{
+			name:       "replayed items",
+			opts:       Options{Idempotency: idempotency.NewMemoryStore(idempotency.Config{})},
+			headers:    map[string]string{idempotencyKeyHeader: "batch-1"},
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "items without a key",
+			opts:       Options{Idempotency: idempotency.NewMemoryStore(idempotency.Config{})},
+			wantStatus: http.StatusMultiStatus,
+		},
+		{
+			name:       "no idempotency store",
+			headers:    map[string]string{idempotencyKeyHeader: "batch-1"},
+			wantStatus: http.StatusMultiStatus,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := fcmtest.NewSender()
+			sender.FailToken("flaky", &fcm.SendError{Code: domain.ErrorCodeUnavailable, Message: "unavailable"})
+
+			rec := postBatch(newBatchHandler(sender, tt.opts), batchOf(idempotentBody, flaky), tt.headers)
+			// A retried batch sends again every item that is not replayed.
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+
+			var resp notifyv1.BatchNotificationResponse
+			if err := pjson.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Results[0].Status != http.StatusOK || resp.Results[1].Status != http.StatusServiceUnavailable {
+				t.Errorf("unexpected item statuses: %v", &resp)
+			}
+			if resp.Results[0].Retryable || !resp.Results[1].Retryable {
+				t.Errorf("expected only the failed item to be flagged retryable: %v", &resp)
+			}
+		})
+	}
+}
+
+func TestSendNotificationBatch_AllSucceeded(t *testing.T) {
+	rec := postBatch(newTestHandler(fcmtest.NewSender()), batchOf(idempotentBody, idempotentBody), nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestSendNotificationBatch_BadRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "invalid json", body: `{"requests":`},
+		{name: "empty", body: batchOf()},
+		{name: "too many", body: batchOf(idempotentBody, idempotentBody, idempotentBody)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := fcmtest.NewSender()
+			rec := postBatch(newBatchHandler(sender, Options{BatchMaxItems: 2}), tt.body, nil)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d", rec.Code)
+			}
+			if len(sender.Messages()) != 0 {
+				t.Error("expected no messages to be sent")
+			}
+		})
+	}
+}
+
+func TestSendNotificationBatch_IdempotencyPerItem(t *testing.T) {
+	sender := fcmtest.NewSender()
+	h := newBatchHandler(sender, Options{Idempotency: idempotency.NewMemoryStore(idempotency.Config{}), BatchConcurrency: 1})
+	headers := map[string]string{idempotencyKeyHeader: "batch-1"}
+
+	postBatch(h, batchOf(idempotentBody, idempotentBody), headers)
+	if len(sender.Messages()) != 2 {
+		t.Fatalf("expected items sharing the header key to be sent on their own, got %d sends", len(sender.Messages()))
+	}
+
+	rec := postBatch(h, batchOf(idempotentBody, idempotentBody), headers)
+	if rec.Code != http.StatusOK || len(sender.Messages()) != 2 {
+		t.Errorf("expected the redelivered batch to be replayed, got status %d and %d sends", rec.Code, len(sender.Messages()))
+	}
+}
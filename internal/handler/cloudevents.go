@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.38.0"
+	"go.opentelemetry.io/otel/trace"
+
+	notifyv1 "github.com/KasumiMercury/primind-notification-invoker/internal/gen/notify/v1"
+)
+
+const (
+	// cloudEventsContentType marks a structured-mode CloudEvent.
+	cloudEventsContentType = "application/cloudevents+json"
+	cloudEventsSpecVersion = "1.0"
+
+	ceSpecVersionHeader = "ce-specversion"
+	ceIDHeader          = "ce-id"
+	ceSourceHeader      = "ce-source"
+	ceTypeHeader        = "ce-type"
+
+	// notificationRequestEventType is the ce-type of events whose data is a
+	// NotificationRequest.
+	notificationRequestEventType = "notify.v1.NotificationRequest"
+)
+
+// cloudEventDecoders decode the data of each supported ce-type.
+var cloudEventDecoders = map[string]func(data []byte, contentType string) (*notifyv1.NotificationRequest, error){
+	notificationRequestEventType: decodeNotificationRequest,
+}
+
+// cloudEvent is a CloudEvent in either content mode. In structured mode the
+// attributes and data share the JSON body; DataBase64 carries binary data.
+type cloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+	DataBase64      []byte          `json:"data_base64"`
+}
+
+// parseCloudEvent reads the CloudEvent of r, in binary mode (ce-* headers)
+// or structured mode (application/cloudevents+json). It returns nil for a
+// request that is not a CloudEvent.
+func parseCloudEvent(r *http.Request, body []byte) (*cloudEvent, error) {
+	var event cloudEvent
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	switch {
+	case mediaType == cloudEventsContentType:
+		if err := json.Unmarshal(body, &event); err != nil {
+			return nil, fmt.Errorf("invalid CloudEvent: %w", err)
+		}
+		if event.DataBase64 != nil {
+			event.Data = event.DataBase64
+		}
+	case r.Header.Get(ceSpecVersionHeader) != "":
+		event = cloudEvent{
+			SpecVersion:     r.Header.Get(ceSpecVersionHeader),
+			ID:              r.Header.Get(ceIDHeader),
+			Source:          r.Header.Get(ceSourceHeader),
+			Type:            r.Header.Get(ceTypeHeader),
+			DataContentType: r.Header.Get("Content-Type"),
+			Data:            body,
+		}
+	default:
+		return nil, nil
+	}
+
+	if event.SpecVersion != cloudEventsSpecVersion {
+		return nil, fmt.Errorf("unsupported CloudEvents spec version: %q", event.SpecVersion)
+	}
+	if event.ID == "" || event.Source == "" || event.Type == "" {
+		return nil, errors.New("invalid CloudEvent: id, source and type are required")
+	}
+
+	return &event, nil
+}
+
+// decode decodes the event data according to its ce-type.
+func (e *cloudEvent) decode() (*notifyv1.NotificationRequest, error) {
+	decode, ok := cloudEventDecoders[e.Type]
+	if !ok {
+		return nil, fmt.Errorf("unsupported event type: %q", e.Type)
+	}
+
+	return decode(e.Data, e.DataContentType)
+}
+
+// idempotencyKey returns the key of the event. Source and ID identify an
+// event, so redeliveries share them.
+func (e *cloudEvent) idempotencyKey() string {
+	return "cloudevent:" + e.Source + "#" + e.ID
+}
+
+func (e *cloudEvent) logAttrs() []any {
+	return []any{
+		"ce_id", e.ID,
+		"ce_source", e.Source,
+		"ce_type", e.Type,
+	}
+}
+
+// annotateSpan records the event attributes on the request span.
+func (e *cloudEvent) annotateSpan(ctx context.Context) {
+	trace.SpanFromContext(ctx).SetAttributes(
+		semconv.CloudEventsEventID(e.ID),
+		semconv.CloudEventsEventSource(e.Source),
+		semconv.CloudEventsEventType(e.Type),
+		semconv.CloudEventsEventSpecVersion(e.SpecVersion),
+	)
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/fcm/fcmtest"
+	commonv1 "github.com/KasumiMercury/primind-notification-invoker/internal/gen/common/v1"
+	notifyv1 "github.com/KasumiMercury/primind-notification-invoker/internal/gen/notify/v1"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/idempotency"
+)
+
+func binaryEventHeaders(id, eventType string) map[string]string {
+	return map[string]string{
+		ceSpecVersionHeader: "1.0",
+		ceIDHeader:          id,
+		ceSourceHeader:      "//primind/throttling",
+		ceTypeHeader:        eventType,
+	}
+}
+
+func structuredEvent(id, eventType, data string) string {
+	return `{"specversion":"1.0","id":"` + id + `","source":"//primind/throttling","type":"` + eventType + `",` + data + `}`
+}
+
+func TestSendNotification_CloudEvents(t *testing.T) {
+	binary, err := proto.Marshal(&notifyv1.NotificationRequest{
+		Tokens:   []string{"a"},
+		TaskId:   testTaskID,
+		TaskType: commonv1.TaskType_TASK_TYPE_SHORT,
+	})
+	if err != nil {
+		t.Fatalf("failed to encode request: %v", err)
+	}
+
+	protoHeaders := binaryEventHeaders("1", notificationRequestEventType)
+	protoHeaders["Content-Type"] = protobufContentType
+	jsonHeaders := binaryEventHeaders("1", notificationRequestEventType)
+	jsonHeaders["Content-Type"] = jsonContentType
+	structured := map[string]string{"Content-Type": cloudEventsContentType + "; charset=utf-8"}
+
+	tests := []struct {
+		name    string
+		body    string
+		headers map[string]string
+		status  int
+	}{
+		{name: "binary json", body: idempotentBody, headers: jsonHeaders, status: http.StatusOK},
+		{name: "binary protobuf", body: string(binary), headers: protoHeaders, status: http.StatusOK},
+		{
+			name:    "structured json",
+			body:    structuredEvent("1", notificationRequestEventType, `"datacontenttype":"application/json","data":`+idempotentBody),
+			headers: structured,
+			status:  http.StatusOK,
+		},
+		{
+			name: "structured protobuf",
+			body: structuredEvent("1", notificationRequestEventType,
+				`"datacontenttype":"application/x-protobuf","data_base64":"`+base64.StdEncoding.EncodeToString(binary)+`"`),
+			headers: structured,
+			status:  http.StatusOK,
+		},
+		{name: "unsupported type", body: idempotentBody, headers: binaryEventHeaders("1", "notify.v1.Unknown"), status: http.StatusBadRequest},
+		{name: "missing id", body: idempotentBody, headers: binaryEventHeaders("", notificationRequestEventType), status: http.StatusBadRequest},
+		{
+			name:    "unsupported spec version",
+			body:    strings.Replace(structuredEvent("1", notificationRequestEventType, `"data":`+idempotentBody), `"1.0"`, `"0.3"`, 1),
+			headers: structured,
+			status:  http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := fcmtest.NewSender()
+			rec := postWithHeaders(newTestHandler(sender), tt.body, tt.headers)
+
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			wantSends := 0
+			if tt.status == http.StatusOK {
+				wantSends = 1
+			}
+			if len(sender.Messages()) != wantSends {
+				t.Errorf("expected %d sends, got %d", wantSends, len(sender.Messages()))
+			}
+		})
+	}
+}
+
+func TestSendNotification_CloudEventRedeliveryIsDeduplicated(t *testing.T) {
+	sender := fcmtest.NewSender()
+	h := newIdempotentHandler(sender, idempotency.NewMemoryStore(idempotency.Config{}))
+
+	postWithHeaders(h, idempotentBody, binaryEventHeaders("event-1", notificationRequestEventType))
+	rec := postWithHeaders(h, idempotentBody, binaryEventHeaders("event-1", notificationRequestEventType))
+	if rec.Header().Get(replayedHeader) != "true" || len(sender.Messages()) != 1 {
+		t.Errorf("expected the redelivered event to be replayed, got %d sends", len(sender.Messages()))
+	}
+
+	postWithHeaders(h, idempotentBody, binaryEventHeaders("event-2", notificationRequestEventType))
+	if len(sender.Messages()) != 2 {
+		t.Errorf("expected another event ID to be sent, got %d sends", len(sender.Messages()))
+	}
+}
+
+func TestParseCloudEvent_PlainRequest(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/notify", nil)
+	req.Header.Set("Content-Type", jsonContentType)
+
+	event, err := parseCloudEvent(req, []byte(idempotentBody))
+	if err != nil || event != nil {
+		t.Errorf("expected no event for a plain request, got %v, %v", event, err)
+	}
+}
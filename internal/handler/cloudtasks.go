@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/deadletter"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/model"
+)
+
+const (
+	cloudTasksTaskNameHeader = "X-CloudTasks-TaskName"
+	// cloudTasksRetryCountHeader counts earlier attempts of the task, 0 on the first.
+	cloudTasksRetryCountHeader = "X-CloudTasks-TaskRetryCount"
+	// cloudTasksExecutionCountHeader counts earlier attempts that got a non-5xx response.
+	cloudTasksExecutionCountHeader = "X-CloudTasks-TaskExecutionCount"
+	// cloudTasksETAHeader is the schedule time of the task in seconds since the epoch.
+	cloudTasksETAHeader = "X-CloudTasks-TaskETA"
+)
+
+// taskAttempt describes the Cloud Tasks attempt a request belongs to.
+// TaskName is empty for requests that did not come from Cloud Tasks.
+type taskAttempt struct {
+	TaskName       string
+	RetryCount     int
+	ExecutionCount int
+	// MaxAttempts is the attempt limit of a delivery that sets its own,
+	// such as a scheduled send. Zero uses that of Cloud Tasks.
+	MaxAttempts int
+}
+
+// cloudTasksAttempt reads the Cloud Tasks headers. Missing or malformed
+// counts are treated as 0.
+func cloudTasksAttempt(header http.Header) taskAttempt {
+	return taskAttempt{
+		TaskName:       header.Get(cloudTasksTaskNameHeader),
+		RetryCount:     headerCount(header, cloudTasksRetryCountHeader),
+		ExecutionCount: headerCount(header, cloudTasksExecutionCountHeader),
+	}
+}
+
+func headerCount(header http.Header, name string) int {
+	n, err := strconv.Atoi(header.Get(name))
+	if err != nil || n < 0 {
+		return 0
+	}
+
+	return n
+}
+
+// Number returns the attempt number, counting from 1.
+func (a taskAttempt) Number() int {
+	return a.RetryCount + 1
+}
+
+// attemptLimit returns the number of attempts of a delivery, or 0 when its
+// retries are not limited here.
+func (h *NotificationHandler) attemptLimit(attempt taskAttempt) int {
+	return cmp.Or(attempt.MaxAttempts, h.maxAttempts)
+}
+
+// isLastAttempt reports whether a retryable outcome of this attempt should be
+// acknowledged and dead-lettered instead of handed back for a retry.
+func (h *NotificationHandler) isLastAttempt(attempt taskAttempt) bool {
+	limit := h.attemptLimit(attempt)
+	return h.deadLetter != nil && limit > 0 && attempt.TaskName != "" && attempt.Number() >= limit
+}
+
+// deadLetterTask writes a task that ran out of attempts to the dead-letter
+// sink. It reports whether the task may be acknowledged.
+func (h *NotificationHandler) deadLetterTask(ctx context.Context, attempt taskAttempt, params *model.NotificationParams, body []byte, result *model.BulkResult) bool {
+	err := h.deadLetter.Write(ctx, deadletter.Entry{
+		TaskName:       attempt.TaskName,
+		TaskID:         params.TaskID.String(),
+		TaskType:       params.TaskType.String(),
+		Reason:         deadletter.ReasonMaxAttempts,
+		Attempt:        attempt.Number(),
+		ExecutionCount: attempt.ExecutionCount,
+		Request:        body,
+		Result:         result,
+		Time:           time.Now(),
+	})
+	if err != nil {
+		slog.Error("failed to dead-letter task, leaving it retryable",
+			"task_name", attempt.TaskName,
+			"task_id", params.TaskID.String(),
+			"error", err,
+		)
+		return false
+	}
+
+	slog.Warn("giving up on task after max attempts",
+		"task_name", attempt.TaskName,
+		"task_id", params.TaskID.String(),
+		"attempt", attempt.Number(),
+		"max_attempts", h.attemptLimit(attempt),
+	)
+	return true
+}
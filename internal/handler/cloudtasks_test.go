@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/channel"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/deadletter"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/fcm"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/fcm/fcmtest"
+	notifyv1 "github.com/KasumiMercury/primind-notification-invoker/internal/gen/notify/v1"
+	pjson "github.com/KasumiMercury/primind-notification-invoker/internal/proto"
+)
+
+type fakeDeadLetter struct {
+	mu      sync.Mutex
+	entries []deadletter.Entry
+	err     error
+}
+
+func (f *fakeDeadLetter) Write(_ context.Context, entry deadletter.Entry) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return f.err
+	}
+	f.entries = append(f.entries, entry)
+	return nil
+}
+
+func newDeadLetterHandler(sender *fcmtest.Sender, sink *fakeDeadLetter, fallback FallbackSender) *NotificationHandler {
+	client := fcm.NewClientWithSender(sender, fcm.Config{})
+	channels := channel.NewRegistry()
+	channels.Register(domain.ChannelFCM, client)
+
+	return NewNotificationHandler(client, Options{Channels: channels, DeadLetter: sink, MaxAttempts: 3, Fallback: fallback})
+}
+
+func TestSendNotification_GiveUpAfterMaxAttempts(t *testing.T) {
+	tests := []struct {
+		name             string
+		taskName         string
+		retryCount       string
+		sinkErr          error
+		wantStatus       int
+		wantDeadLettered bool
+		wantEntries      int
+		wantRetrying     bool
+	}{
+		{name: "first attempt", taskName: "tasks/1", retryCount: "0", wantStatus: http.StatusServiceUnavailable, wantRetrying: true},
+		{name: "second attempt", taskName: "tasks/1", retryCount: "1", wantStatus: http.StatusServiceUnavailable, wantRetrying: true},
+		{name: "last attempt", taskName: "tasks/1", retryCount: "2", wantStatus: http.StatusOK, wantDeadLettered: true, wantEntries: 1},
+		{name: "past the last attempt", taskName: "tasks/1", retryCount: "7", wantStatus: http.StatusOK, wantDeadLettered: true, wantEntries: 1},
+		{name: "not a cloud task", retryCount: "7", wantStatus: http.StatusServiceUnavailable, wantRetrying: true},
+		{name: "malformed retry count", taskName: "tasks/1", retryCount: "many", wantStatus: http.StatusServiceUnavailable, wantRetrying: true},
+		{name: "sink fails", taskName: "tasks/1", retryCount: "2", sinkErr: errors.New("db down"), wantStatus: http.StatusServiceUnavailable, wantRetrying: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := fcmtest.NewSender()
+			sender.FailBatch(errors.New("connection reset"))
+			sink := &fakeDeadLetter{err: tt.sinkErr}
+			h := newDeadLetterHandler(sender, sink, nil)
+
+			rec := postWithHeaders(h, idempotentBody, map[string]string{
+				cloudTasksTaskNameHeader:       tt.taskName,
+				cloudTasksRetryCountHeader:     tt.retryCount,
+				cloudTasksExecutionCountHeader: "1",
+			})
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+
+			var resp notifyv1.NotificationResponse
+			if err := pjson.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.DeadLettered != tt.wantDeadLettered || resp.Retryable != tt.wantRetrying {
+				t.Errorf("unexpected response: %v", &resp)
+			}
+			if len(sink.entries) != tt.wantEntries {
+				t.Fatalf("expected %d dead letters, got %d", tt.wantEntries, len(sink.entries))
+			}
+
+			if tt.wantEntries > 0 {
+				entry := sink.entries[0]
+				wantAttempt, _ := strconv.Atoi(tt.retryCount)
+				if entry.TaskName != tt.taskName || entry.TaskID != testTaskID || entry.Attempt != wantAttempt+1 || entry.ExecutionCount != 1 {
+					t.Errorf("unexpected entry: %+v", entry)
+				}
+				if entry.Reason != deadletter.ReasonMaxAttempts || string(entry.Request) != idempotentBody || entry.Result == nil {
+					t.Errorf("expected the request and result to be kept, got %+v", entry)
+				}
+			}
+		})
+	}
+}
+
+func TestSendNotification_PermanentFailureIsNotDeadLettered(t *testing.T) {
+	sender := fcmtest.NewSender()
+	sender.FailToken("a", &fcm.SendError{Code: domain.ErrorCodeUnregistered, Message: "unregistered"})
+	sink := &fakeDeadLetter{}
+	h := newDeadLetterHandler(sender, sink, nil)
+
+	rec := postWithHeaders(h, idempotentBody, map[string]string{cloudTasksTaskNameHeader: "tasks/1", cloudTasksRetryCountHeader: "2"})
+	if rec.Code != http.StatusOK || len(sink.entries) != 0 {
+		t.Errorf("expected a plain acknowledgement, got %d with %d dead letters", rec.Code, len(sink.entries))
+	}
+}
+
+func TestSendNotification_GiveUpSendsFallback(t *testing.T) {
+	sender := fcmtest.NewSender()
+	sender.FailBatch(errors.New("connection reset"))
+	fallback := &fakeFallback{}
+	h := newDeadLetterHandler(sender, &fakeDeadLetter{}, fallback)
+
+	body := `{"tokens":["a"],"task_id":"` + testTaskID + `","task_type":"TASK_TYPE_SHORT","fallback_email":"user@example.com"}`
+	headers := map[string]string{cloudTasksTaskNameHeader: "tasks/1", cloudTasksRetryCountHeader: "1"}
+	postWithHeaders(h, body, headers)
+	if len(fallback.sent) != 0 {
+		t.Fatal("expected no fallback while retries remain")
+	}
+
+	headers[cloudTasksRetryCountHeader] = "2"
+	postWithHeaders(h, body, headers)
+	if len(fallback.sent) != 1 {
+		t.Errorf("expected the fallback on the last attempt, got %v", fallback.sent)
+	}
+}
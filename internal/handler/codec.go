@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+
+	notifyv1 "github.com/KasumiMercury/primind-notification-invoker/internal/gen/notify/v1"
+	pjson "github.com/KasumiMercury/primind-notification-invoker/internal/proto"
+)
+
+const (
+	jsonContentType     = "application/json"
+	protobufContentType = "application/x-protobuf"
+	// protoContentType is an alias of protobufContentType used by some clients.
+	protoContentType = "application/proto"
+)
+
+// isProtobuf reports whether contentType names binary protobuf.
+func isProtobuf(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == protobufContentType || mediaType == protoContentType
+}
+
+// decodeNotificationRequest decodes a NotificationRequest encoded as JSON or
+// binary protobuf, as decodeMessage does.
+func decodeNotificationRequest(data []byte, contentType string) (*notifyv1.NotificationRequest, error) {
+	var req notifyv1.NotificationRequest
+	if err := decodeMessage(data, contentType, &req); err != nil {
+		return nil, err
+	}
+
+	return &req, nil
+}
+
+// decodeMessage decodes m encoded as JSON or binary protobuf. contentType
+// selects the encoding; when it is empty, data that starts with '{' is JSON
+// and anything else is protobuf.
+func decodeMessage(data []byte, contentType string, m proto.Message) error {
+	protobuf := isProtobuf(contentType)
+	if contentType == "" {
+		trimmed := bytes.TrimSpace(data)
+		protobuf = len(trimmed) > 0 && trimmed[0] != '{'
+	}
+
+	if protobuf {
+		if err := proto.Unmarshal(data, m); err != nil {
+			return fmt.Errorf("invalid protobuf: %w", err)
+		}
+		return nil
+	}
+
+	if err := pjson.Unmarshal(data, m); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	return nil
+}
+
+// responseContentType picks the response encoding from an Accept header.
+// The supported type with the highest quality wins, the first listed on a
+// tie; JSON is the default, including when nothing supported is accepted.
+func responseContentType(accept string) string {
+	best, bestQ := jsonContentType, 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+
+		var candidate string
+		switch mediaType {
+		case protobufContentType, protoContentType:
+			candidate = mediaType
+		case jsonContentType, "application/*", "*/*":
+			candidate = jsonContentType
+		default:
+			continue
+		}
+
+		if q > bestQ {
+			best, bestQ = candidate, q
+		}
+	}
+
+	return best
+}
+
+// encodeMessage encodes m as contentType, which is JSON or binary protobuf.
+func encodeMessage(m proto.Message, contentType string) ([]byte, error) {
+	if isProtobuf(contentType) {
+		return proto.Marshal(m)
+	}
+
+	return pjson.Marshal(m)
+}
+
+// respondMessage writes m encoded as contentType.
+func respondMessage(w http.ResponseWriter, status int, contentType string, m proto.Message) {
+	respBytes, err := encodeMessage(m, contentType)
+	if err != nil {
+		slog.Error("failed to marshal response", "error", err)
+		respondProtoError(w, http.StatusInternalServerError, "failed to marshal response")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	if _, err := w.Write(respBytes); err != nil {
+		slog.Warn("failed to write response", "error", err)
+	}
+}
+
+// respondErrorAs writes an ErrorResponse encoded as contentType.
+func respondErrorAs(w http.ResponseWriter, status int, contentType, message string) {
+	respondMessage(w, status, contentType, &notifyv1.ErrorResponse{
+		Success: false,
+		Error:   message,
+	})
+}
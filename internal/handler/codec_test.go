@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/fcm/fcmtest"
+	commonv1 "github.com/KasumiMercury/primind-notification-invoker/internal/gen/common/v1"
+	notifyv1 "github.com/KasumiMercury/primind-notification-invoker/internal/gen/notify/v1"
+	pjson "github.com/KasumiMercury/primind-notification-invoker/internal/proto"
+)
+
+func TestResponseContentType(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+	}{
+		{accept: "", want: jsonContentType},
+		{accept: "*/*", want: jsonContentType},
+		{accept: "application/x-protobuf", want: protobufContentType},
+		{accept: "application/proto", want: protoContentType},
+		{accept: "application/json, application/x-protobuf", want: jsonContentType},
+		{accept: "application/json;q=0.5, application/x-protobuf", want: protobufContentType},
+		{accept: "application/x-protobuf;q=0, */*", want: jsonContentType},
+		{accept: "text/html", want: jsonContentType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			if got := responseContentType(tt.accept); got != tt.want {
+				t.Errorf("responseContentType(%q) = %q, want %q", tt.accept, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSendNotification_Protobuf(t *testing.T) {
+	binary, err := proto.Marshal(&notifyv1.NotificationRequest{
+		Tokens:   []string{"a", "b"},
+		TaskId:   testTaskID,
+		TaskType: commonv1.TaskType_TASK_TYPE_SHORT,
+	})
+	if err != nil {
+		t.Fatalf("failed to encode request: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		accept      string
+		status      int
+	}{
+		{name: "protobuf in, protobuf out", body: string(binary), contentType: protobufContentType, accept: protobufContentType, status: http.StatusOK},
+		{name: "proto alias in, json out", body: string(binary), contentType: protoContentType, status: http.StatusOK},
+		{name: "json in, protobuf out", body: idempotentBody, contentType: jsonContentType, accept: protoContentType, status: http.StatusOK},
+		{name: "invalid protobuf", body: "\xff\xff", contentType: protobufContentType, accept: protobufContentType, status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{"Content-Type": tt.contentType}
+			if tt.accept != "" {
+				headers["Accept"] = tt.accept
+			}
+			rec := postWithHeaders(newTestHandler(fcmtest.NewSender()), tt.body, headers)
+
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+
+			wantType := responseContentType(tt.accept)
+			if got := rec.Header().Get("Content-Type"); got != wantType {
+				t.Fatalf("expected content type %q, got %q", wantType, got)
+			}
+
+			var resp proto.Message = &notifyv1.NotificationResponse{}
+			if tt.status != http.StatusOK {
+				resp = &notifyv1.ErrorResponse{}
+			}
+			if isProtobuf(wantType) {
+				err = proto.Unmarshal(rec.Body.Bytes(), resp)
+			} else {
+				err = pjson.Unmarshal(rec.Body.Bytes(), resp)
+			}
+			if err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if r, ok := resp.(*notifyv1.NotificationResponse); ok && r.SuccessCount == 0 {
+				t.Errorf("unexpected response: %v", resp)
+			}
+		})
+	}
+}
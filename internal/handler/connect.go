@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/proto"
+
+	notifyv1 "github.com/KasumiMercury/primind-notification-invoker/internal/gen/notify/v1"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/gen/notify/v1/notifyv1connect"
+	pjson "github.com/KasumiMercury/primind-notification-invoker/internal/proto"
+)
+
+// NotificationService serves notify.v1.NotificationService over Connect,
+// gRPC and gRPC-Web. It shares its core, and so its behaviour, with /notify.
+type NotificationService struct {
+	h *NotificationHandler
+}
+
+var _ notifyv1connect.NotificationServiceHandler = (*NotificationService)(nil)
+
+func NewNotificationService(h *NotificationHandler) *NotificationService {
+	return &NotificationService{h: h}
+}
+
+// SendNotification implements notifyv1connect.NotificationServiceHandler.
+// The request headers are read as on /notify, including X-Dry-Run,
+// Idempotency-Key and the Cloud Tasks headers.
+func (s *NotificationService) SendNotification(ctx context.Context, req *connect.Request[notifyv1.NotificationRequest]) (*connect.Response[notifyv1.NotificationResponse], error) {
+	d, err := headerDelivery(req.Header(), nil)
+	if err != nil {
+		slog.Error("invalid dry run header", "error", err)
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
+
+	return connectOutcome(s.h.process(ctx, req.Msg, d))
+}
+
+// connectOutcome maps an outcome to a Connect response. Outcomes that are
+// not 2xx become errors, so that gRPC retry policies apply; a retryable
+// send keeps its NotificationResponse as an error detail.
+func connectOutcome(o *outcome) (*connect.Response[notifyv1.NotificationResponse], error) {
+	if o.Response == nil {
+		return nil, connect.NewError(connectCode(o.Status), errors.New(o.Error))
+	}
+
+	if o.Status >= http.StatusBadRequest {
+		cerr := connect.NewError(connectCode(o.Status), errors.New("notification not delivered, retry later"))
+		if detail, err := connect.NewErrorDetail(o.Response); err == nil {
+			cerr.AddDetail(detail)
+		}
+		if o.RetryAfter > 0 {
+			cerr.Meta().Set("Retry-After", retryAfterSeconds(o.RetryAfter))
+		}
+		return nil, cerr
+	}
+
+	resp := connect.NewResponse(o.Response)
+	if o.Replayed {
+		resp.Header().Set(replayedHeader, "true")
+	}
+	return resp, nil
+}
+
+// connectCode maps the HTTP status of an outcome to a Connect code.
+func connectCode(status int) connect.Code {
+	switch status {
+	case http.StatusBadRequest:
+		return connect.CodeInvalidArgument
+	case http.StatusConflict:
+		return connect.CodeAborted
+	case http.StatusTooManyRequests:
+		return connect.CodeResourceExhausted
+	case http.StatusServiceUnavailable:
+		return connect.CodeUnavailable
+	default:
+		return connect.CodeInternal
+	}
+}
+
+// ValidationInterceptor rejects requests that fail their protovalidate
+// rules with InvalidArgument before they reach the service.
+func ValidationInterceptor() connect.UnaryInterceptorFunc {
+	return func(next connect.UnaryFunc) connect.UnaryFunc {
+		return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			if msg, ok := req.Any().(proto.Message); ok && !req.Spec().IsClient {
+				if err := pjson.Validate(msg); err != nil {
+					slog.Error("validation error", "procedure", req.Spec().Procedure, "error", err)
+					return nil, connect.NewError(connect.CodeInvalidArgument, err)
+				}
+			}
+
+			return next(ctx, req)
+		}
+	}
+}
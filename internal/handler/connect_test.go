@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/fcm/fcmtest"
+	commonv1 "github.com/KasumiMercury/primind-notification-invoker/internal/gen/common/v1"
+	notifyv1 "github.com/KasumiMercury/primind-notification-invoker/internal/gen/notify/v1"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/gen/notify/v1/notifyv1connect"
+)
+
+func newConnectServer(t *testing.T, sender *fcmtest.Sender) *httptest.Server {
+	t.Helper()
+
+	path, h := notifyv1connect.NewNotificationServiceHandler(
+		NewNotificationService(newTestHandler(sender)),
+		connect.WithInterceptors(ValidationInterceptor()),
+	)
+	mux := http.NewServeMux()
+	mux.Handle(path, h)
+
+	srv := httptest.NewUnstartedServer(mux)
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func validConnectRequest() *notifyv1.NotificationRequest {
+	return &notifyv1.NotificationRequest{
+		Tokens:   []string{"a"},
+		TaskId:   testTaskID,
+		TaskType: commonv1.TaskType_TASK_TYPE_SHORT,
+	}
+}
+
+func TestNotificationService_Protocols(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []connect.ClientOption
+	}{
+		{name: "connect"},
+		{name: "grpc", opts: []connect.ClientOption{connect.WithGRPC()}},
+		{name: "grpc-web", opts: []connect.ClientOption{connect.WithGRPCWeb()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := fcmtest.NewSender()
+			srv := newConnectServer(t, sender)
+			client := notifyv1connect.NewNotificationServiceClient(srv.Client(), srv.URL, tt.opts...)
+
+			resp, err := client.SendNotification(context.Background(), connect.NewRequest(validConnectRequest()))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !resp.Msg.Success || resp.Msg.SuccessCount != 1 {
+				t.Errorf("unexpected response: %v", resp.Msg)
+			}
+			if len(sender.Messages()) != 1 {
+				t.Errorf("expected one send, got %d", len(sender.Messages()))
+			}
+		})
+	}
+}
+
+func TestNotificationService_Errors(t *testing.T) {
+	t.Run("invalid request", func(t *testing.T) {
+		sender := fcmtest.NewSender()
+		srv := newConnectServer(t, sender)
+		client := notifyv1connect.NewNotificationServiceClient(srv.Client(), srv.URL, connect.WithGRPC())
+
+		req := validConnectRequest()
+		req.TaskId = "not-a-uuid"
+		_, err := client.SendNotification(context.Background(), connect.NewRequest(req))
+		if connect.CodeOf(err) != connect.CodeInvalidArgument {
+			t.Fatalf("expected invalid argument, got %v", err)
+		}
+		if len(sender.Messages()) != 0 {
+			t.Error("expected no messages to be sent")
+		}
+	})
+
+	t.Run("retryable failure", func(t *testing.T) {
+		sender := fcmtest.NewSender()
+		sender.FailBatch(errors.New("connection reset"))
+		srv := newConnectServer(t, sender)
+		client := notifyv1connect.NewNotificationServiceClient(srv.Client(), srv.URL)
+
+		_, err := client.SendNotification(context.Background(), connect.NewRequest(validConnectRequest()))
+		if connect.CodeOf(err) != connect.CodeUnavailable {
+			t.Fatalf("expected unavailable, got %v", err)
+		}
+
+		var cerr *connect.Error
+		if !errors.As(err, &cerr) || len(cerr.Details()) != 1 {
+			t.Fatalf("expected the response as error detail, got %v", err)
+		}
+		detail, err := cerr.Details()[0].Value()
+		if err != nil {
+			t.Fatalf("failed to decode detail: %v", err)
+		}
+		if resp, ok := detail.(*notifyv1.NotificationResponse); !ok || !resp.Retryable {
+			t.Errorf("unexpected detail: %v", detail)
+		}
+	})
+}
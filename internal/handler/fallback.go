@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/channel"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/model"
+)
+
+var errFallbackDisabled = errors.New("email fallback is not enabled")
+
+// FallbackSender delivers a reminder outside the push channels. *email.Client implements it.
+type FallbackSender interface {
+	SendFallback(ctx context.Context, to string, reminder channel.Reminder) error
+}
+
+// sendFallback sends the reminder to the request's fallback email when no
+// recipient was reached. Retryable outcomes are skipped unless final is set,
+// since a retry may still reach a device. Dry runs never send.
+func (h *NotificationHandler) sendFallback(ctx context.Context, params *model.NotificationParams, result *model.BulkResult, final bool) (bool, error) {
+	if params.FallbackEmail == "" || result.SuccessCount > 0 || (result.Retryable() && !final) {
+		return false, nil
+	}
+
+	if params.DryRun {
+		slog.Info("skipping fallback email for dry run", "task_id", params.TaskID.String())
+		return false, nil
+	}
+
+	if h.fallback == nil {
+		slog.Warn("fallback email requested but not enabled", "task_id", params.TaskID.String())
+		return false, errFallbackDisabled
+	}
+
+	if err := h.fallback.SendFallback(ctx, params.FallbackEmail, reminderFor(params)); err != nil {
+		slog.Error("fallback email failed", "task_id", params.TaskID.String(), "error", err)
+		return false, err
+	}
+
+	slog.Info("fallback email sent", "task_id", params.TaskID.String())
+	return true, nil
+}
+
+// fallbackDue reports whether a retryable outcome of this attempt is final
+// and gets the fallback email now, independently of dead-lettering. That is
+// only the last attempt of a Cloud Tasks task or scheduled send with a
+// known limit; other deliveries are retried, and the email waits for an
+// outcome that is not retryable so that redeliveries do not repeat it.
+func (h *NotificationHandler) fallbackDue(attempt taskAttempt) bool {
+	limit := h.attemptLimit(attempt)
+	return attempt.TaskName != "" && limit > 0 && attempt.Number() >= limit
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/channel"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/fcm"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/fcm/fcmtest"
+	notifyv1 "github.com/KasumiMercury/primind-notification-invoker/internal/gen/notify/v1"
+	pjson "github.com/KasumiMercury/primind-notification-invoker/internal/proto"
+)
+
+type fakeFallback struct {
+	mu   sync.Mutex
+	sent []string
+	err  error
+}
+
+func (f *fakeFallback) SendFallback(_ context.Context, to string, _ channel.Reminder) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.err != nil {
+		return f.err
+	}
+	f.sent = append(f.sent, to)
+	return nil
+}
+
+func TestSendNotification_Fallback(t *testing.T) {
+	unregistered := &fcm.SendError{Code: domain.ErrorCodeUnregistered, Message: "unregistered"}
+
+	tests := []struct {
+		name         string
+		failures     map[string]error
+		batchErr     error
+		extra        string
+		fallbackErr  error
+		headers      map[string]string
+		maxAttempts  int
+		wantSent     bool
+		wantUsed     bool
+		wantErrorSet bool
+		wantStatus   int
+	}{
+		{name: "all tokens dead", failures: map[string]error{"a": unregistered, "b": unregistered}, wantSent: true, wantUsed: true},
+		{name: "one token reached", failures: map[string]error{"a": unregistered}},
+		{name: "retryable failure", batchErr: errors.New("connection reset")},
+		{name: "retryable failure on a Cloud Tasks retry", batchErr: errors.New("connection reset"), headers: taskHeaders(1), maxAttempts: 5},
+		{name: "retryable failure on the last Cloud Tasks attempt", batchErr: errors.New("connection reset"), headers: taskHeaders(4), maxAttempts: 5, wantSent: true, wantUsed: true},
+		{name: "retryable failure on a Cloud Tasks attempt without a limit", batchErr: errors.New("connection reset"), headers: taskHeaders(4)},
+		{name: "dry run", failures: map[string]error{"a": unregistered, "b": unregistered}, extra: `,"dry_run":true`},
+		{name: "fallback fails", failures: map[string]error{"a": unregistered, "b": unregistered}, fallbackErr: errors.New("smtp down"), wantErrorSet: true, wantStatus: http.StatusServiceUnavailable},
+		{name: "fallback fails on the last Cloud Tasks attempt", batchErr: errors.New("connection reset"), headers: taskHeaders(4), maxAttempts: 5, fallbackErr: errors.New("smtp down"), wantErrorSet: true, wantStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := fcmtest.NewSender()
+			for token, err := range tt.failures {
+				sender.FailToken(token, err)
+			}
+			sender.FailBatch(tt.batchErr)
+			fallback := &fakeFallback{err: tt.fallbackErr}
+			h := NewNotificationHandler(fcm.NewClientWithSender(sender, fcm.Config{}), Options{Fallback: fallback, MaxAttempts: tt.maxAttempts})
+
+			rec := postWithHeaders(h, `{"tokens":["a","b"],"task_id":"`+testTaskID+`","task_type":"TASK_TYPE_SHORT","fallback_email":"user@example.com"`+tt.extra+`}`, tt.headers)
+
+			var resp notifyv1.NotificationResponse
+			if err := pjson.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+
+			if got := len(fallback.sent) == 1; got != tt.wantSent {
+				t.Errorf("expected fallback sent=%v, got %v", tt.wantSent, fallback.sent)
+			}
+			if resp.FallbackUsed != tt.wantUsed || (resp.FallbackError != "") != tt.wantErrorSet {
+				t.Errorf("unexpected fallback in response: used=%v error=%q", resp.FallbackUsed, resp.FallbackError)
+			}
+			// A fallback that was due but failed is retried.
+			if tt.wantStatus != 0 && (rec.Code != tt.wantStatus || !resp.Retryable) {
+				t.Errorf("expected a retryable status %d, got %d retryable=%v", tt.wantStatus, rec.Code, resp.Retryable)
+			}
+		})
+	}
+}
+
+// taskHeaders are the Cloud Tasks headers of the attempt after retryCount retries.
+func taskHeaders(retryCount int) map[string]string {
+	return map[string]string{
+		cloudTasksTaskNameHeader:   "projects/p/locations/l/queues/q/tasks/1",
+		cloudTasksRetryCountHeader: strconv.Itoa(retryCount),
+	}
+}
+
+func TestSendNotification_FallbackNotEnabled(t *testing.T) {
+	sender := fcmtest.NewSender()
+	sender.FailToken("a", &fcm.SendError{Code: domain.ErrorCodeUnregistered, Message: "unregistered"})
+
+	rec := postNotify(newTestHandler(sender), `{"tokens":["a"],"task_id":"`+testTaskID+`","task_type":"TASK_TYPE_SHORT","fallback_email":"user@example.com"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var resp notifyv1.NotificationResponse
+	if err := pjson.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.FallbackUsed || resp.FallbackError == "" {
+		t.Errorf("expected the missing fallback to be reported, got used=%v error=%q", resp.FallbackUsed, resp.FallbackError)
+	}
+}
+
+func TestSendNotification_FallbackNotEnabledDryRun(t *testing.T) {
+	sender := fcmtest.NewSender()
+	sender.FailToken("a", &fcm.SendError{Code: domain.ErrorCodeUnregistered, Message: "unregistered"})
+
+	rec := postNotify(newTestHandler(sender), `{"tokens":["a"],"task_id":"`+testTaskID+`","task_type":"TASK_TYPE_SHORT","fallback_email":"user@example.com","dry_run":true}`)
+
+	var resp notifyv1.NotificationResponse
+	if err := pjson.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.FallbackUsed || resp.FallbackError != "" {
+		t.Errorf("expected a dry run to skip the fallback silently, got used=%v error=%q", resp.FallbackUsed, resp.FallbackError)
+	}
+}
@@ -0,0 +1,173 @@
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+
+	notifyv1 "github.com/KasumiMercury/primind-notification-invoker/internal/gen/notify/v1"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/idempotency"
+)
+
+const (
+	idempotencyKeyHeader = "Idempotency-Key"
+	// replayedHeader marks a response replayed from the idempotency store.
+	replayedHeader = "Idempotent-Replayed"
+)
+
+// idempotencyKey derives the key that identifies redeliveries of a request,
+// from the first of: the request's idempotency_key, the key of its delivery
+// (the Idempotency-Key header or a Pub/Sub message ID), the Cloud Tasks task
+// name, or the task ID plus the schedule time (from the request or the
+// delivery). It returns "" when none apply. Keys are hashed so that
+// arbitrary header values fit the store.
+func idempotencyKey(req *notifyv1.NotificationRequest, d delivery) string {
+	if d.ResolvedKey != "" {
+		return d.ResolvedKey
+	}
+
+	var source string
+	switch {
+	case req.IdempotencyKey != "":
+		source = "key:" + req.IdempotencyKey
+	case d.IdempotencyKey != "":
+		source = "key:" + d.IdempotencyKey
+	case d.Attempt.TaskName != "":
+		source = "task:" + d.Attempt.TaskName
+	default:
+		scheduleTime := ""
+		if req.ScheduleTime != nil {
+			scheduleTime = req.ScheduleTime.AsTime().UTC().Format(time.RFC3339Nano)
+		} else if !d.ScheduleTime.IsZero() {
+			scheduleTime = d.ScheduleTime.UTC().Format(time.RFC3339Nano)
+		}
+		if scheduleTime == "" {
+			return ""
+		}
+		source = "schedule:" + req.TaskId + "@" + scheduleTime
+	}
+
+	sum := sha256.Sum256([]byte(source))
+	return hex.EncodeToString(sum[:])
+}
+
+// requestHash fingerprints req, so that a different request reusing the
+// idempotency key of another is told from a redelivery. send_at is left out,
+// since a held request is sent without it.
+func requestHash(req *notifyv1.NotificationRequest) string {
+	req = proto.CloneOf(req)
+	req.SendAt = nil
+
+	body, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// parseTaskETA parses a Cloud Tasks ETA such as "1700000000.123456" without
+// the rounding of a float conversion.
+func parseTaskETA(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	secPart, fracPart, _ := strings.Cut(value, ".")
+	if len(fracPart) > 9 {
+		fracPart = fracPart[:9]
+	}
+	fracPart += strings.Repeat("0", 9-len(fracPart))
+
+	sec, err := strconv.ParseUint(secPart, 10, 63)
+	if err != nil {
+		return time.Time{}, false
+	}
+	nsec, err := strconv.ParseUint(fracPart, 10, 63)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.Unix(int64(sec), int64(nsec)), true
+}
+
+// claimIdempotency claims key for req. held is the claimed key, or "" when
+// there is nothing to release or complete later. A non-nil replay answers
+// the request instead of sending: the stored outcome of a completed
+// duplicate, 409 while the original is still in progress, or 422 when the
+// key was used for a different request.
+// Store errors fail open, since a duplicate reminder beats a lost one.
+func (h *NotificationHandler) claimIdempotency(ctx context.Context, key string, req *notifyv1.NotificationRequest) (held string, replay *outcome) {
+	if h.idempotency == nil || key == "" {
+		return "", nil
+	}
+
+	record, err := h.idempotency.Claim(ctx, key, requestHash(req))
+	switch {
+	case errors.Is(err, idempotency.ErrKeyReused):
+		slog.Warn("idempotency key reused for a different request", "idempotency_key", key)
+		return "", failedOutcome(http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, idempotency.ErrInProgress):
+		slog.Info("duplicate request in progress", "idempotency_key", key)
+		return "", failedOutcome(http.StatusConflict, err.Error())
+	case err != nil:
+		slog.Warn("idempotency store unavailable, sending without deduplication", "error", err)
+		return "", nil
+	case record != nil:
+		var resp notifyv1.NotificationResponse
+		if err := proto.Unmarshal(record.Body, &resp); err != nil {
+			slog.Warn("failed to decode stored response, sending again", "idempotency_key", key, "error", err)
+			return "", nil
+		}
+		slog.Info("replaying stored response", "idempotency_key", key, "status", record.StatusCode)
+		return "", &outcome{Status: record.StatusCode, Response: &resp, Replayed: true}
+	}
+
+	return key, nil
+}
+
+// completeIdempotency stores the outcome for a claimed key. Retryable
+// outcomes release the claim instead, so that the retry sends again. It
+// runs even if the caller has gone away, which is when a redelivery follows.
+func (h *NotificationHandler) completeIdempotency(ctx context.Context, key string, o *outcome) {
+	if key == "" {
+		return
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	if o.Response.Retryable {
+		h.releaseIdempotency(ctx, key)
+		return
+	}
+
+	body, err := proto.Marshal(o.Response)
+	if err != nil {
+		h.releaseIdempotency(ctx, key)
+		slog.Warn("failed to encode idempotent response", "idempotency_key", key, "error", err)
+		return
+	}
+
+	record := idempotency.Record{StatusCode: o.Status, ContentType: protobufContentType, Body: body}
+	if err := h.idempotency.Complete(ctx, key, record); err != nil {
+		slog.Warn("failed to store idempotent response", "idempotency_key", key, "error", err)
+	}
+}
+
+func (h *NotificationHandler) releaseIdempotency(ctx context.Context, key string) {
+	if key == "" {
+		return
+	}
+
+	if err := h.idempotency.Release(context.WithoutCancel(ctx), key); err != nil {
+		slog.Warn("failed to release idempotency key", "idempotency_key", key, "error", err)
+	}
+}
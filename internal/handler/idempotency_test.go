@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/channel"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/fcm"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/fcm/fcmtest"
+	notifyv1 "github.com/KasumiMercury/primind-notification-invoker/internal/gen/notify/v1"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/idempotency"
+	pjson "github.com/KasumiMercury/primind-notification-invoker/internal/proto"
+)
+
+const idempotentBody = `{"tokens":["a"],"task_id":"` + testTaskID + `","task_type":"TASK_TYPE_SHORT"}`
+
+func newIdempotentHandler(sender *fcmtest.Sender, store idempotency.Store) *NotificationHandler {
+	client := fcm.NewClientWithSender(sender, fcm.Config{})
+	channels := channel.NewRegistry()
+	channels.Register(domain.ChannelFCM, client)
+
+	return NewNotificationHandler(client, Options{Channels: channels, Idempotency: store})
+}
+
+func postWithHeaders(h *NotificationHandler, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/notify", strings.NewReader(body))
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.SendNotification(rec, req)
+	return rec
+}
+
+func TestSendNotification_IdempotentReplay(t *testing.T) {
+	sender := fcmtest.NewSender()
+	h := newIdempotentHandler(sender, idempotency.NewMemoryStore(idempotency.Config{}))
+	headers := map[string]string{cloudTasksTaskNameHeader: "projects/p/locations/l/queues/q/tasks/1"}
+
+	first := postWithHeaders(h, idempotentBody, headers)
+	second := postWithHeaders(h, idempotentBody, headers)
+
+	if first.Code != http.StatusOK || second.Code != http.StatusOK {
+		t.Fatalf("expected status 200 twice, got %d and %d", first.Code, second.Code)
+	}
+	if second.Body.String() != first.Body.String() || second.Header().Get(replayedHeader) != "true" {
+		t.Errorf("expected the original response to be replayed, got %s", second.Body.String())
+	}
+	if len(sender.Messages()) != 1 {
+		t.Errorf("expected a single send, got %d", len(sender.Messages()))
+	}
+
+	other := postWithHeaders(h, idempotentBody, map[string]string{cloudTasksTaskNameHeader: "projects/p/locations/l/queues/q/tasks/2"})
+	if other.Header().Get(replayedHeader) != "" || len(sender.Messages()) != 2 {
+		t.Error("expected another task to be sent")
+	}
+}
+
+func TestSendNotification_IdempotencyRetryableIsNotStored(t *testing.T) {
+	sender := fcmtest.NewSender()
+	sender.FailBatch(errors.New("connection reset"))
+	h := newIdempotentHandler(sender, idempotency.NewMemoryStore(idempotency.Config{}))
+	headers := map[string]string{idempotencyKeyHeader: "reminder-1"}
+
+	if rec := postWithHeaders(h, idempotentBody, headers); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d", rec.Code)
+	}
+
+	sender.FailBatch(nil)
+	rec := postWithHeaders(h, idempotentBody, headers)
+	if rec.Code != http.StatusOK || rec.Header().Get(replayedHeader) != "" {
+		t.Fatalf("expected the retry to be sent, got %d", rec.Code)
+	}
+	if len(sender.Messages()) != 2 {
+		t.Errorf("expected two sends, got %d", len(sender.Messages()))
+	}
+}
+
+func TestSendNotification_IdempotencyInProgress(t *testing.T) {
+	sender := fcmtest.NewSender()
+	store := idempotency.NewMemoryStore(idempotency.Config{})
+	h := newIdempotentHandler(sender, store)
+
+	key := idempotencyKey(&notifyv1.NotificationRequest{}, delivery{IdempotencyKey: "reminder-1"})
+	if _, err := store.Claim(context.Background(), key, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rec := postWithHeaders(h, idempotentBody, map[string]string{idempotencyKeyHeader: "reminder-1"})
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected status 409, got %d", rec.Code)
+	}
+	if len(sender.Messages()) != 0 {
+		t.Error("expected no send while the original is in progress")
+	}
+}
+
+func TestSendNotification_IdempotencyKeyReused(t *testing.T) {
+	sender := fcmtest.NewSender()
+	h := newIdempotentHandler(sender, idempotency.NewMemoryStore(idempotency.Config{}))
+	headers := map[string]string{idempotencyKeyHeader: "reminder-1"}
+
+	if rec := postWithHeaders(h, idempotentBody, headers); rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	rec := postWithHeaders(h, `{"tokens":["b"],"task_id":"`+testTaskID+`","task_type":"TASK_TYPE_SHORT"}`, headers)
+	if rec.Code != http.StatusUnprocessableEntity || rec.Header().Get(replayedHeader) != "" {
+		t.Fatalf("expected status 422 for another request with the same key, got %d", rec.Code)
+	}
+	if len(sender.Messages()) != 1 {
+		t.Errorf("expected a single send, got %d", len(sender.Messages()))
+	}
+}
+
+func TestSendNotification_IdempotencySkipsDryRun(t *testing.T) {
+	sender := fcmtest.NewSender()
+	h := newIdempotentHandler(sender, idempotency.NewMemoryStore(idempotency.Config{}))
+	headers := map[string]string{idempotencyKeyHeader: "reminder-1", dryRunHeader: "true"}
+
+	postWithHeaders(h, idempotentBody, headers)
+	postWithHeaders(h, idempotentBody, headers)
+	if len(sender.DryRunMessages()) != 2 {
+		t.Errorf("expected dry runs not to be deduplicated, got %d", len(sender.DryRunMessages()))
+	}
+
+	rec := postWithHeaders(h, idempotentBody, map[string]string{idempotencyKeyHeader: "reminder-1"})
+	if rec.Header().Get(replayedHeader) != "" || len(sender.Messages()) != 1 {
+		t.Error("expected a real send after dry runs with the same key")
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	key := func(body string, headers map[string]string) string {
+		req := httptest.NewRequest(http.MethodPost, "/notify", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		var msg notifyv1.NotificationRequest
+		if body != "" {
+			if err := pjson.Unmarshal([]byte(body), &msg); err != nil {
+				t.Fatalf("failed to decode %s: %v", body, err)
+			}
+		}
+		d, err := headerDelivery(req.Header, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return idempotencyKey(&msg, d)
+	}
+
+	taskName := map[string]string{cloudTasksTaskNameHeader: "tasks/1"}
+	scheduled := `{"task_id":"` + testTaskID + `","schedule_time":"2025-01-02T03:04:05.5Z"}`
+	eta := map[string]string{cloudTasksETAHeader: "1735787045.5"}
+
+	if key(`{"task_id":"`+testTaskID+`"}`, nil) != "" {
+		t.Error("expected no key without a key, task name or schedule time")
+	}
+	if key(`{"idempotency_key":"k"}`, taskName) != key("", map[string]string{idempotencyKeyHeader: "k"}) {
+		t.Error("expected the body key and the header key to match")
+	}
+	if key(`{"idempotency_key":"k"}`, taskName) == key("", taskName) {
+		t.Error("expected an explicit key to take precedence over the task name")
+	}
+	if key(scheduled, nil) == "" || key(scheduled, nil) != key(`{"task_id":"`+testTaskID+`"}`, eta) {
+		t.Error("expected schedule_time and the Cloud Tasks ETA to derive the same key")
+	}
+	if key(scheduled, nil) == key(`{"task_id":"`+testTaskID+`"}`, map[string]string{cloudTasksETAHeader: "1735787046"}) {
+		t.Error("expected another schedule time to derive another key")
+	}
+}
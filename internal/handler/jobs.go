@@ -0,0 +1,246 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	notifyv1 "github.com/KasumiMercury/primind-notification-invoker/internal/gen/notify/v1"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/jobs"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/model"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/progress"
+)
+
+const (
+	preferHeader            = "Prefer"
+	preferenceAppliedHeader = "Preference-Applied"
+	respondAsyncPreference  = "respond-async"
+
+	// progressInterval bounds how often the progress of a job is stored.
+	progressInterval = time.Second
+)
+
+// prefersAsync reports whether the request asks for an asynchronous
+// response (RFC 7240).
+func prefersAsync(header http.Header) bool {
+	for _, value := range header.Values(preferHeader) {
+		for preference := range strings.SplitSeq(value, ",") {
+			name, _, _ := strings.Cut(preference, ";")
+			name, _, _ = strings.Cut(name, "=")
+			if strings.EqualFold(strings.TrimSpace(name), respondAsyncPreference) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// submitJob validates req and queues its send on the job pool. Invalid
+// requests are rejected right away, as without Prefer: respond-async, and so
+// are scheduled ones, which would get no job to poll.
+//
+// Jobs are not retried: a job whose send is retryable ends failed with the
+// 503 or 429 status and response that /notify would have returned, and the
+// caller submits the request again.
+func (h *NotificationHandler) submitJob(w http.ResponseWriter, r *http.Request, req *notifyv1.NotificationRequest, d delivery, accept string) {
+	if req.SendAt != nil {
+		slog.Error("send_at requested with respond-async", "task_id", req.TaskId)
+		respondErrorAs(w, http.StatusBadRequest, accept, "send_at cannot be combined with Prefer: respond-async")
+		return
+	}
+
+	params, failed := h.prepare(req, d)
+	if failed != nil {
+		writeOutcome(w, failed, accept)
+		return
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		slog.Error("failed to generate job ID", "error", err)
+		respondErrorAs(w, http.StatusInternalServerError, accept, "failed to create job")
+		return
+	}
+
+	now := time.Now()
+	job := jobs.Job{
+		ID:        id.String(),
+		State:     jobs.StateQueued,
+		Total:     targetCount(params),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	ctx := r.Context()
+	if err := h.jobs.Create(ctx, job); err != nil {
+		slog.Error("failed to create job", "job_id", job.ID, "error", err)
+		respondErrorAs(w, http.StatusServiceUnavailable, accept, "failed to create job: "+err.Error())
+		return
+	}
+
+	if err := h.jobPool.Submit(ctx, func(ctx context.Context) {
+		h.runJob(ctx, job, req, d, params)
+	}); err != nil {
+		slog.Error("failed to queue job", "job_id", job.ID, "error", err)
+		job.State = jobs.StateFailed
+		job.Error = err.Error()
+		job.UpdatedAt = time.Now()
+		h.updateJob(ctx, job)
+
+		respondErrorAs(w, http.StatusServiceUnavailable, accept, "failed to queue job: "+err.Error())
+		return
+	}
+
+	slog.Info("notification job queued", "job_id", job.ID, "task_id", params.TaskID.String(), "total", job.Total)
+
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	w.Header().Set(preferenceAppliedHeader, respondAsyncPreference)
+	respondMessage(w, http.StatusAccepted, accept, jobProto(&job))
+}
+
+// runJob sends a queued job and records its progress and results.
+func (h *NotificationHandler) runJob(ctx context.Context, job jobs.Job, req *notifyv1.NotificationRequest, d delivery, params *model.NotificationParams) {
+	d.LogAttrs = append(d.LogAttrs[:len(d.LogAttrs):len(d.LogAttrs)], "job_id", job.ID)
+
+	job.State = jobs.StateRunning
+	job.UpdatedAt = time.Now()
+	h.updateJob(ctx, job)
+
+	// Senders report from several goroutines. The progress is stored at most
+	// once per progressInterval, by one reporter at a time so that the store
+	// sees the counts in order, and outside mu so that the others go on
+	// sending meanwhile.
+	var (
+		mu      sync.Mutex
+		storing bool
+		stored  time.Time
+	)
+	ctx = progress.WithReporter(ctx, func(n int) {
+		mu.Lock()
+		job.Done = min(job.Done+n, job.Total)
+		if storing || time.Since(stored) < progressInterval {
+			mu.Unlock()
+			return
+		}
+		storing = true
+		job.UpdatedAt = time.Now()
+		snapshot := job
+		mu.Unlock()
+
+		h.updateJob(ctx, snapshot)
+
+		mu.Lock()
+		storing = false
+		stored = time.Now()
+		mu.Unlock()
+	})
+
+	o := h.execute(ctx, req, d, params)
+
+	// Every report has returned once the send is done.
+	job.State = jobs.StateSucceeded
+	if o.Status >= http.StatusBadRequest {
+		job.State = jobs.StateFailed
+	}
+	job.StatusCode = o.Status
+	job.Error = o.Error
+	job.Done = job.Total
+	if o.Response != nil {
+		result, err := proto.Marshal(o.Response)
+		if err != nil {
+			slog.Warn("failed to encode job result", "job_id", job.ID, "error", err)
+		}
+		job.Result = result
+	}
+	job.UpdatedAt = time.Now()
+	// Record the result even when the pool gave up on the job.
+	h.updateJob(context.WithoutCancel(ctx), job)
+
+	slog.Info("notification job finished", "job_id", job.ID, "state", string(job.State), "status", job.StatusCode)
+}
+
+// updateJob stores job. A failed update only leaves the job behind, since
+// the send goes on regardless.
+func (h *NotificationHandler) updateJob(ctx context.Context, job jobs.Job) {
+	if err := h.jobs.Update(ctx, job); err != nil {
+		slog.Warn("failed to update job", "job_id", job.ID, "state", string(job.State), "error", err)
+	}
+}
+
+// GetJob handles GET /jobs/{id}, returning the progress of a job sent in the
+// background and, once finished, its per-token results.
+func (h *NotificationHandler) GetJob(w http.ResponseWriter, r *http.Request) {
+	accept := responseContentType(r.Header.Get("Accept"))
+
+	if h.jobs == nil {
+		respondErrorAs(w, http.StatusNotFound, accept, "job not found")
+		return
+	}
+
+	id := r.PathValue("id")
+	job, err := h.jobs.Get(r.Context(), id)
+	if errors.Is(err, jobs.ErrNotFound) {
+		respondErrorAs(w, http.StatusNotFound, accept, "job not found")
+		return
+	}
+	if err != nil {
+		slog.Error("failed to load job", "job_id", id, "error", err)
+		respondErrorAs(w, http.StatusServiceUnavailable, accept, "failed to load job")
+		return
+	}
+
+	respondMessage(w, http.StatusOK, accept, jobProto(job))
+}
+
+// targetCount is the number of targets a send reaches; a topic or
+// condition counts as one.
+func targetCount(params *model.NotificationParams) int {
+	switch {
+	case len(params.Recipients) > 0:
+		return len(params.Recipients)
+	case params.Topic != "" || params.Condition != "":
+		return 1
+	default:
+		return len(params.Tokens)
+	}
+}
+
+var jobStates = map[jobs.State]notifyv1.JobState{
+	jobs.StateQueued:    notifyv1.JobState_JOB_STATE_QUEUED,
+	jobs.StateRunning:   notifyv1.JobState_JOB_STATE_RUNNING,
+	jobs.StateSucceeded: notifyv1.JobState_JOB_STATE_SUCCEEDED,
+	jobs.StateFailed:    notifyv1.JobState_JOB_STATE_FAILED,
+}
+
+func jobProto(job *jobs.Job) *notifyv1.Job {
+	m := &notifyv1.Job{
+		Id:        job.ID,
+		State:     jobStates[job.State],
+		Total:     int32(job.Total),
+		Done:      int32(job.Done),
+		Status:    int32(job.StatusCode),
+		Error:     job.Error,
+		CreatedAt: timestamppb.New(job.CreatedAt),
+		UpdatedAt: timestamppb.New(job.UpdatedAt),
+	}
+
+	if len(job.Result) > 0 {
+		var resp notifyv1.NotificationResponse
+		if err := proto.Unmarshal(job.Result, &resp); err != nil {
+			slog.Warn("failed to decode job result", "job_id", job.ID, "error", err)
+		} else {
+			m.Response = &resp
+		}
+	}
+
+	return m
+}
@@ -0,0 +1,237 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/channel"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/fcm"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/fcm/fcmtest"
+	notifyv1 "github.com/KasumiMercury/primind-notification-invoker/internal/gen/notify/v1"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/jobs"
+	pjson "github.com/KasumiMercury/primind-notification-invoker/internal/proto"
+)
+
+const asyncBody = `{"tokens":["a","b"],"task_id":"` + testTaskID + `","task_type":"TASK_TYPE_SHORT"}`
+
+func newJobHandler(sender *fcmtest.Sender, store jobs.Store) (*NotificationHandler, *jobs.Pool) {
+	client := fcm.NewClientWithSender(sender, fcm.Config{})
+	channels := channel.NewRegistry()
+	channels.Register(domain.ChannelFCM, client)
+	pool := jobs.NewPool(1, 10)
+
+	return NewNotificationHandler(client, Options{Channels: channels, Jobs: store, JobPool: pool}), pool
+}
+
+func getJob(t *testing.T, h *NotificationHandler, id string) (*httptest.ResponseRecorder, *notifyv1.Job) {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodGet, "/jobs/"+id, nil)
+	req.SetPathValue("id", id)
+	rec := httptest.NewRecorder()
+	h.GetJob(rec, req)
+
+	var job notifyv1.Job
+	if rec.Code == http.StatusOK {
+		if err := pjson.Unmarshal(rec.Body.Bytes(), &job); err != nil {
+			t.Fatalf("failed to decode job: %v", err)
+		}
+	}
+	return rec, &job
+}
+
+func TestSendNotification_Async(t *testing.T) {
+	sender := fcmtest.NewSender()
+	h, pool := newJobHandler(sender, jobs.NewMemoryStore(jobs.Config{}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/notify", strings.NewReader(asyncBody))
+	req.Header.Set(preferHeader, "respond-async, wait=5")
+	rec := httptest.NewRecorder()
+	h.SendNotification(rec, req)
+	// The job must not depend on the client staying connected.
+	cancel()
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get(preferenceAppliedHeader); got != respondAsyncPreference {
+		t.Errorf("expected Preference-Applied: respond-async, got %q", got)
+	}
+
+	var accepted notifyv1.Job
+	if err := pjson.Unmarshal(rec.Body.Bytes(), &accepted); err != nil {
+		t.Fatalf("failed to decode job: %v", err)
+	}
+	if accepted.Id == "" || accepted.State != notifyv1.JobState_JOB_STATE_QUEUED || accepted.Total != 2 {
+		t.Errorf("expected a queued job of 2 tokens, got %v", &accepted)
+	}
+	if got := rec.Header().Get("Location"); got != "/jobs/"+accepted.Id {
+		t.Errorf("expected Location /jobs/%s, got %q", accepted.Id, got)
+	}
+
+	if err := pool.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rec, job := getJob(t, h, accepted.Id)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if job.State != notifyv1.JobState_JOB_STATE_SUCCEEDED || job.Done != 2 || job.Status != http.StatusOK {
+		t.Errorf("expected a succeeded job with 2 tokens done, got %v", job)
+	}
+	if job.Response == nil || len(job.Response.Results) != 2 || job.Response.SuccessCount != 2 {
+		t.Errorf("expected per-token results, got %v", job.Response)
+	}
+	if len(sender.Messages()) != 1 {
+		t.Errorf("expected one send, got %d", len(sender.Messages()))
+	}
+}
+
+// countingJobStore counts the updates of the jobs it stores.
+type countingJobStore struct {
+	jobs.Store
+	updates atomic.Int32
+}
+
+func (s *countingJobStore) Update(ctx context.Context, job jobs.Job) error {
+	s.updates.Add(1)
+	return s.Store.Update(ctx, job)
+}
+
+func TestSendNotification_AsyncProgressIsThrottled(t *testing.T) {
+	tokens := make([]string, 1500)
+	for i := range tokens {
+		tokens[i] = fmt.Sprintf("%q", fmt.Sprintf("token-%d", i))
+	}
+	body := `{"tokens":[` + strings.Join(tokens, ",") + `],"task_id":"` + testTaskID + `","task_type":"TASK_TYPE_SHORT"}`
+
+	store := &countingJobStore{Store: jobs.NewMemoryStore(jobs.Config{})}
+	h, pool := newJobHandler(fcmtest.NewSender(), store)
+
+	if rec := postWithHeaders(h, body, map[string]string{preferHeader: respondAsyncPreference}); rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if err := pool.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Running, the first of three batches, and the result.
+	if got := store.updates.Load(); got != 3 {
+		t.Errorf("expected 3 updates, got %d", got)
+	}
+}
+
+func TestSendNotification_AsyncFailedJob(t *testing.T) {
+	sender := fcmtest.NewSender()
+	sender.FailBatch(errors.New("connection reset"))
+	h, pool := newJobHandler(sender, jobs.NewMemoryStore(jobs.Config{}))
+
+	rec := postWithHeaders(h, asyncBody, map[string]string{preferHeader: respondAsyncPreference})
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var accepted notifyv1.Job
+	if err := pjson.Unmarshal(rec.Body.Bytes(), &accepted); err != nil {
+		t.Fatalf("failed to decode job: %v", err)
+	}
+
+	if err := pool.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, job := getJob(t, h, accepted.Id)
+	if job.State != notifyv1.JobState_JOB_STATE_FAILED || job.Status != http.StatusServiceUnavailable {
+		t.Errorf("expected a failed job with status 503, got %v", job)
+	}
+}
+
+func TestSendNotification_AsyncRejectsInvalidRequest(t *testing.T) {
+	sender := fcmtest.NewSender()
+	h, _ := newJobHandler(sender, jobs.NewMemoryStore(jobs.Config{}))
+
+	rec := postWithHeaders(h, `{"tokens":["a"],"task_id":"not-a-uuid"}`, map[string]string{preferHeader: respondAsyncPreference})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", rec.Code)
+	}
+}
+
+func TestSendNotification_AsyncRejectsSendAt(t *testing.T) {
+	h, _ := newJobHandler(fcmtest.NewSender(), jobs.NewMemoryStore(jobs.Config{}))
+
+	body := sendAtBody(time.Now().Add(time.Minute))
+	rec := postWithHeaders(h, body, map[string]string{preferHeader: respondAsyncPreference})
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestSendNotification_AsyncIgnoredForCloudTasks(t *testing.T) {
+	sender := fcmtest.NewSender()
+	sender.FailBatch(errors.New("connection reset"))
+	h, _ := newJobHandler(sender, jobs.NewMemoryStore(jobs.Config{}))
+
+	headers := taskHeaders(0)
+	headers[preferHeader] = respondAsyncPreference
+	rec := postWithHeaders(h, asyncBody, headers)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected the retryable failure to reach Cloud Tasks, got status %d", rec.Code)
+	}
+	if got := rec.Header().Get(preferenceAppliedHeader); got != "" {
+		t.Errorf("expected no Preference-Applied, got %q", got)
+	}
+}
+
+func TestSendNotification_AsyncWithoutJobs(t *testing.T) {
+	sender := fcmtest.NewSender()
+	rec := postWithHeaders(newTestHandler(sender), asyncBody, map[string]string{preferHeader: respondAsyncPreference})
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected the preference to be ignored, got status %d", rec.Code)
+	}
+}
+
+func TestGetJob_Unknown(t *testing.T) {
+	h, _ := newJobHandler(fcmtest.NewSender(), jobs.NewMemoryStore(jobs.Config{}))
+
+	if rec, _ := getJob(t, h, "missing"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", rec.Code)
+	}
+}
+
+func TestPrefersAsync(t *testing.T) {
+	tests := []struct {
+		prefer string
+		want   bool
+	}{
+		{prefer: "", want: false},
+		{prefer: "respond-async", want: true},
+		{prefer: "Respond-Async", want: true},
+		{prefer: "return=minimal, respond-async", want: true},
+		{prefer: "respond-async; foo=bar", want: true},
+		{prefer: "wait=10", want: false},
+		{prefer: "respond-asynchronously", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefer, func(t *testing.T) {
+			header := http.Header{}
+			if tt.prefer != "" {
+				header.Set(preferHeader, tt.prefer)
+			}
+			if got := prefersAsync(header); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
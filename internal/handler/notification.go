@@ -1,26 +1,117 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"time"
 
+	"github.com/KasumiMercury/primind-notification-invoker/internal/channel"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/deadletter"
 	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
 	"github.com/KasumiMercury/primind-notification-invoker/internal/fcm"
 	notifyv1 "github.com/KasumiMercury/primind-notification-invoker/internal/gen/notify/v1"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/idempotency"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/jobs"
 	"github.com/KasumiMercury/primind-notification-invoker/internal/model"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/observability/metrics"
 	pjson "github.com/KasumiMercury/primind-notification-invoker/internal/proto"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/scheduler"
 )
 
+// dryRunHeader forces a dry run regardless of the request body.
+const dryRunHeader = "X-Dry-Run"
+
 type NotificationHandler struct {
-	fcmClient *fcm.Client
+	fcmClient   *fcm.Client
+	channels    *channel.Registry
+	metrics     *metrics.NotificationMetrics
+	fallback    FallbackSender
+	idempotency idempotency.Store
+	deadLetter  deadletter.Sink
+	maxAttempts int
+
+	batchMaxItems int
+	// batchSlots bounds the batch items in flight across all batch requests.
+	batchSlots chan struct{}
+	// batchItemTimeout is the deadline of each batch item; zero disables it.
+	batchItemTimeout time.Duration
+
+	jobs    jobs.Store
+	jobPool *jobs.Pool
+
+	scheduler *scheduler.Scheduler
 }
 
-func NewNotificationHandler(client *fcm.Client) *NotificationHandler {
-	return &NotificationHandler{fcmClient: client}
+// Options holds the optional dependencies of a NotificationHandler. Nil fields disable the feature.
+type Options struct {
+	// Channels routes requests with per-recipient channels.
+	Channels *channel.Registry
+	Metrics  *metrics.NotificationMetrics
+	// Fallback receives reminders for requests with a fallback email that reached no recipient.
+	Fallback FallbackSender
+	// Idempotency stores responses so that redelivered requests are not sent again.
+	Idempotency idempotency.Store
+	// DeadLetter receives Cloud Tasks tasks that are still retryable on attempt
+	// MaxAttempts, and scheduled sends on the last attempt of the scheduler;
+	// they are acknowledged instead of retried. Giving up is disabled without
+	// DeadLetter, and for tasks without MaxAttempts.
+	DeadLetter  deadletter.Sink
+	MaxAttempts int
+	// BatchMaxItems caps the requests of a batch. Zero uses defaultBatchMaxItems.
+	BatchMaxItems int
+	// BatchConcurrency is the number of batch items sent concurrently, shared
+	// by all batch requests. Zero uses defaultBatchConcurrency.
+	BatchConcurrency int
+	// BatchItemTimeout is the deadline of each batch item, counted once the
+	// item has a slot. Zero disables it.
+	BatchItemTimeout time.Duration
+	// Jobs and JobPool let /notify send in the background when asked with
+	// Prefer: respond-async. Requests are sent synchronously when either is unset.
+	Jobs    jobs.Store
+	JobPool *jobs.Pool
+	// Scheduler holds requests with a future send_at until it is due; such
+	// requests are rejected when it is unset. Start it with StartScheduler.
+	Scheduler *scheduler.Scheduler
+}
+
+func NewNotificationHandler(client *fcm.Client, opts Options) *NotificationHandler {
+	if opts.BatchMaxItems <= 0 {
+		opts.BatchMaxItems = defaultBatchMaxItems
+	}
+	if opts.BatchConcurrency <= 0 {
+		opts.BatchConcurrency = defaultBatchConcurrency
+	}
+
+	return &NotificationHandler{
+		fcmClient:        client,
+		channels:         opts.Channels,
+		metrics:          opts.Metrics,
+		fallback:         opts.Fallback,
+		idempotency:      opts.Idempotency,
+		deadLetter:       opts.DeadLetter,
+		maxAttempts:      opts.MaxAttempts,
+		batchMaxItems:    opts.BatchMaxItems,
+		batchSlots:       make(chan struct{}, opts.BatchConcurrency),
+		batchItemTimeout: opts.BatchItemTimeout,
+		jobs:             opts.Jobs,
+		jobPool:          opts.JobPool,
+		scheduler:        opts.Scheduler,
+	}
 }
 
+// SendNotification handles /notify. The body is a NotificationRequest as
+// JSON or binary protobuf (Content-Type application/x-protobuf or
+// application/proto), or a CloudEvent in binary or structured mode whose
+// ce-type selects the schema of its data. The Accept header selects the
+// response encoding, JSON by default. With Prefer: respond-async the request
+// is validated, then sent in the background; the 202 response is the job to
+// poll at GET /jobs/{id}.
 func (h *NotificationHandler) SendNotification(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		slog.Warn("method not allowed", "method", r.Method, "path", r.URL.Path)
@@ -28,98 +119,392 @@ func (h *NotificationHandler) SendNotification(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	accept := responseContentType(r.Header.Get("Accept"))
+
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		slog.Error("failed to read request body", "error", err)
-		respondProtoError(w, http.StatusBadRequest, "failed to read request body")
+		respondErrorAs(w, http.StatusBadRequest, accept, "failed to read request body")
 		return
 	}
 
-	var req notifyv1.NotificationRequest
-	if err := pjson.Unmarshal(body, &req); err != nil {
-		slog.Error("failed to decode request body", "error", err)
-		respondProtoError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+	d, err := headerDelivery(r.Header, nil)
+	if err != nil {
+		slog.Error("invalid dry run header", "error", err)
+		respondErrorAs(w, http.StatusBadRequest, accept, err.Error())
 		return
 	}
 
-	if err := pjson.Validate(&req); err != nil {
-		slog.Error("validation error", "error", err)
-		respondProtoError(w, http.StatusBadRequest, "validation error: "+err.Error())
+	event, err := parseCloudEvent(r, body)
+	if err != nil {
+		slog.Error("invalid CloudEvent", "error", err)
+		respondErrorAs(w, http.StatusBadRequest, accept, err.Error())
+		return
+	}
+
+	var req *notifyv1.NotificationRequest
+	if event != nil {
+		event.annotateSpan(r.Context())
+		d.LogAttrs = event.logAttrs()
+		if d.IdempotencyKey == "" {
+			d.IdempotencyKey = event.idempotencyKey()
+		}
+
+		req, err = event.decode()
+		if err != nil {
+			slog.Error("failed to decode CloudEvent data", append(d.LogAttrs, "error", err)...)
+			respondErrorAs(w, http.StatusBadRequest, accept, err.Error())
+			return
+		}
+	} else {
+		contentType := r.Header.Get("Content-Type")
+		if !isProtobuf(contentType) {
+			contentType = jsonContentType
+			d.Body = body
+		}
+
+		req, err = decodeNotificationRequest(body, contentType)
+		if err != nil {
+			slog.Error("failed to decode request body", "error", err)
+			respondErrorAs(w, http.StatusBadRequest, accept, err.Error())
+			return
+		}
+	}
+
+	if h.jobs != nil && h.jobPool != nil && prefersAsync(r.Header) {
+		// A 202 acknowledges Cloud Tasks and CloudEvent deliveries, which would
+		// lose a retryable failure of the job, so they are answered in line.
+		if event == nil && d.Attempt.TaskName == "" {
+			h.submitJob(w, r, req, d, accept)
+			return
+		}
+		slog.Info("ignoring respond-async preference of a retried delivery", d.LogAttrs...)
+	}
+
+	writeOutcome(w, h.process(r.Context(), req, d), accept)
+}
+
+// delivery is what a transport knows about a notification request besides
+// the request itself.
+type delivery struct {
+	// DryRun forces a dry run regardless of the request.
+	DryRun bool
+	// IdempotencyKey is a key given outside the request, such as the
+	// Idempotency-Key header or a Pub/Sub message ID.
+	IdempotencyKey string
+	Attempt        taskAttempt
+	// ScheduleTime is the schedule time given outside the request, such as
+	// the Cloud Tasks ETA. It is zero when unknown.
+	ScheduleTime time.Time
+	// Body is the request as JSON, kept for dead-lettering. When nil, the
+	// decoded request is marshalled instead.
+	Body []byte
+	// LogAttrs are added to the log entry of the send.
+	LogAttrs []any
+	// ResolvedKey is the idempotency key resolved when the request was
+	// received, for sends that run later without its headers. It takes
+	// precedence over every other source.
+	ResolvedKey string
+}
+
+// headerDelivery reads the delivery of a request from its headers, which
+// are the same for /notify and NotificationService.
+func headerDelivery(header http.Header, body []byte) (delivery, error) {
+	d := delivery{
+		IdempotencyKey: header.Get(idempotencyKeyHeader),
+		Attempt:        cloudTasksAttempt(header),
+		Body:           body,
+	}
+	if eta, ok := parseTaskETA(header.Get(cloudTasksETAHeader)); ok {
+		d.ScheduleTime = eta
+	}
+
+	if value := header.Get(dryRunHeader); value != "" {
+		dryRun, err := strconv.ParseBool(value)
+		if err != nil {
+			return delivery{}, fmt.Errorf("invalid %s header: %q", dryRunHeader, value)
+		}
+		d.DryRun = dryRun
+	}
+
+	return d, nil
+}
+
+// outcome is the result of processing a notification request, before it is
+// written back in the encoding of its transport. Response is nil when the
+// request failed before sending; Error then says why.
+type outcome struct {
+	// Status is the HTTP status of the outcome.
+	Status     int
+	Response   *notifyv1.NotificationResponse
+	Error      string
+	RetryAfter time.Duration
+	// Replayed is set when Response was stored by an earlier delivery.
+	Replayed bool
+}
+
+func failedOutcome(status int, message string) *outcome {
+	return &outcome{Status: status, Error: message}
+}
+
+// writeOutcome writes o as a /notify response encoded as contentType.
+func writeOutcome(w http.ResponseWriter, o *outcome, contentType string) {
+	if o.Response == nil {
+		respondErrorAs(w, o.Status, contentType, o.Error)
 		return
 	}
 
+	if o.RetryAfter > 0 {
+		w.Header().Set("Retry-After", retryAfterSeconds(o.RetryAfter))
+	}
+	if o.Replayed {
+		w.Header().Set(replayedHeader, "true")
+	}
+	respondMessage(w, o.Status, contentType, o.Response)
+}
+
+// process validates and sends a notification request. It is shared by every
+// transport that accepts NotificationRequest.
+func (h *NotificationHandler) process(ctx context.Context, req *notifyv1.NotificationRequest, d delivery) *outcome {
+	params, failed := h.prepare(req, d)
+	if failed != nil {
+		return failed
+	}
+	if held := h.hold(ctx, req, d, params); held != nil {
+		return held
+	}
+
+	return h.execute(ctx, req, d, params)
+}
+
+// prepare validates a notification request and converts it to the
+// parameters of its send. A request that cannot be sent gets a failed
+// outcome instead.
+func (h *NotificationHandler) prepare(req *notifyv1.NotificationRequest, d delivery) (*model.NotificationParams, *outcome) {
+	if err := pjson.Validate(req); err != nil {
+		slog.Error("validation error", "error", err)
+		return nil, failedOutcome(http.StatusBadRequest, "validation error: "+err.Error())
+	}
+
 	taskType, err := domain.ProtoTaskTypeToDomain(req.TaskType)
 	if err != nil {
 		slog.Error("invalid task type", "error", err)
-		respondProtoError(w, http.StatusBadRequest, err.Error())
-		return
+		return nil, failedOutcome(http.StatusBadRequest, err.Error())
+	}
+
+	mode, err := domain.ProtoDeliveryModeToDomain(req.DeliveryMode)
+	if err != nil {
+		slog.Error("invalid delivery mode", "error", err)
+		return nil, failedOutcome(http.StatusBadRequest, err.Error())
+	}
+
+	recipients, err := domain.ProtoRecipientsToDomain(req.Recipients)
+	if err != nil {
+		slog.Error("invalid recipients", "error", err)
+		return nil, failedOutcome(http.StatusBadRequest, err.Error())
+	}
+	for _, recipient := range recipients {
+		if !h.channels.Has(recipient.Channel) {
+			slog.Error("channel not enabled", "channel", recipient.Channel.String())
+			return nil, failedOutcome(http.StatusBadRequest, "channel not enabled: "+recipient.Channel.String())
+		}
 	}
 
 	modelReq := model.NotificationRequest{
-		Tokens: req.Tokens,
-		TaskID: req.TaskId,
-		Color:  req.Color,
+		Tokens:     req.Tokens,
+		Topic:      req.Topic,
+		Condition:  req.Condition,
+		TaskID:     req.TaskId,
+		Color:      req.Color,
+		DryRun:     req.DryRun || d.DryRun,
+		Recipients: recipients,
+
+		FallbackEmail: req.FallbackEmail,
 	}
 
-	params, err := modelReq.ToDomain(taskType)
+	params, err := modelReq.ToDomain(taskType, mode)
 	if err != nil {
 		slog.Error("invalid request parameters", "error", err)
-		respondProtoError(w, http.StatusBadRequest, err.Error())
-		return
+		return nil, failedOutcome(http.StatusBadRequest, err.Error())
 	}
 
-	slog.Info("sending notification",
+	return params, nil
+}
+
+// execute sends a prepared notification request.
+func (h *NotificationHandler) execute(ctx context.Context, req *notifyv1.NotificationRequest, d delivery, params *model.NotificationParams) *outcome {
+	attempt := d.Attempt
+
+	slog.Info("sending notification", append([]any{
 		"task_id", params.TaskID.String(),
+		"task_name", attempt.TaskName,
+		"attempt", attempt.Number(),
+		"execution_count", attempt.ExecutionCount,
 		"task_type", params.TaskType.String(),
 		"token_count", len(params.Tokens),
+		"recipient_count", len(params.Recipients),
+		"topic", params.Topic.String(),
+		"condition", params.Condition.String(),
 		"color", params.Color,
-	)
+		"delivery_mode", params.Mode.String(),
+		"dry_run", params.DryRun,
+	}, d.LogAttrs...)...)
 
-	result, err := h.fcmClient.SendBulkNotification(r.Context(), params.Tokens, params.TaskID, params.TaskType, params.Color)
+	// Dry runs deliver nothing, so there is nothing to deduplicate.
+	var key string
+	if !params.DryRun {
+		var replay *outcome
+		if key, replay = h.claimIdempotency(ctx, idempotencyKey(req, d), req); replay != nil {
+			return replay
+		}
+	}
+
+	result, err := h.send(ctx, params)
 	if err != nil {
+		h.releaseIdempotency(ctx, key)
 		slog.Error("FCM bulk notification failed", "error", err)
-		respondProtoError(w, http.StatusInternalServerError, "FCM error: "+err.Error())
-		return
+		return failedOutcome(http.StatusServiceUnavailable, "FCM error: "+err.Error())
+	}
+
+	retryable := result.Retryable()
+	deadLettered := false
+	if retryable && !params.DryRun && h.isLastAttempt(attempt) {
+		body := d.Body
+		if body == nil {
+			if body, err = pjson.Marshal(req); err != nil {
+				slog.Warn("failed to encode request for dead-lettering", "error", err)
+			}
+		}
+		deadLettered = h.deadLetterTask(context.WithoutCancel(ctx), attempt, params, body, result)
+		retryable = !deadLettered
+	}
+
+	fallbackUsed, fallbackErr := h.sendFallback(ctx, params, result, deadLettered || h.fallbackDue(attempt))
+	if fallbackErr != nil && !errors.Is(fallbackErr, errFallbackDisabled) {
+		// The fallback email was due but not sent; a redelivery tries again.
+		retryable = true
 	}
 
 	slog.Info("notification sent",
 		"total", result.Total,
 		"success_count", result.SuccessCount,
 		"failure_count", result.FailureCount,
+		"status", result.Status.String(),
+		"retryable", retryable,
+		"dead_lettered", deadLettered,
+		"fallback_used", fallbackUsed,
+		"dry_run", params.DryRun,
 	)
 
+	if h.metrics != nil {
+		h.metrics.Record(ctx, result.Status.String(), params.DryRun, result.SuccessCount, result.FailureCount)
+	}
+
 	protoResults := make([]*notifyv1.TokenResult, len(result.Results))
 	for i, r := range result.Results {
 		protoResults[i] = &notifyv1.TokenResult{
-			Token:     r.Token,
-			Success:   r.Success,
-			MessageId: r.MessageID,
-			Error:     r.Error,
+			Token:             r.Token,
+			Success:           r.Success,
+			MessageId:         r.MessageID,
+			Error:             r.Error,
+			Attempts:          int32(r.Attempts),
+			ErrorCode:         domain.DomainErrorCodeToProto(r.ErrorCode),
+			ShouldRemoveToken: r.ShouldRemoveToken,
+			Channel:           domain.DomainChannelToProto(r.Channel),
+			Retryable:         r.NotSent,
 		}
 	}
 
-	resp := &notifyv1.NotificationResponse{
-		Success:      true,
-		Total:        int32(result.Total),
-		SuccessCount: int32(result.SuccessCount),
-		FailureCount: int32(result.FailureCount),
-		Results:      protoResults,
+	o := &outcome{
+		Status: deliveryHTTPStatus(result.Status, retryable),
+		Response: &notifyv1.NotificationResponse{
+			Success:      result.Status != domain.DeliveryStatusFailed,
+			Total:        int32(result.Total),
+			SuccessCount: int32(result.SuccessCount),
+			FailureCount: int32(result.FailureCount),
+			Results:      protoResults,
+			Status:       domain.DomainDeliveryStatusToProto(result.Status),
+			Retryable:    retryable,
+			DryRun:       params.DryRun,
+			FallbackUsed: fallbackUsed,
+			DeadLettered: deadLettered,
+		},
+	}
+	if fallbackErr != nil {
+		o.Response.FallbackError = fallbackErr.Error()
+	}
+	if retryable && result.RetryAfter > 0 {
+		// Rate limited: tell the caller when the budget allows another attempt.
+		o.Status = http.StatusTooManyRequests
+		o.RetryAfter = result.RetryAfter
 	}
 
-	respBytes, err := pjson.Marshal(resp)
-	if err != nil {
-		slog.Error("failed to marshal response", "error", err)
-		respondProtoError(w, http.StatusInternalServerError, "failed to marshal response")
-		return
+	h.completeIdempotency(ctx, key, o)
+
+	return o
+}
+
+// send delivers to the target selected in params. Recipients go through the
+// channel registry; tokens, topics and conditions go to FCM directly.
+func (h *NotificationHandler) send(ctx context.Context, params *model.NotificationParams) (*model.BulkResult, error) {
+	client := h.fcmClient
+	if params.DryRun {
+		client = client.DryRun()
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	if _, err := w.Write(respBytes); err != nil {
-		slog.Warn("failed to write response", "error", err)
+	switch {
+	case len(params.Recipients) > 0:
+		return h.channels.Deliver(ctx, params.Recipients, reminderFor(params)), nil
+	case params.Topic != "":
+		return client.SendToTopic(ctx, params.Topic, params.TaskID, params.TaskType, params.Color, params.Mode), nil
+	case params.Condition != "":
+		return client.SendToCondition(ctx, params.Condition, params.TaskID, params.TaskType, params.Color, params.Mode), nil
+	default:
+		return client.SendBulkNotification(ctx, params.Tokens, params.TaskID, params.TaskType, params.Color, params.Mode)
 	}
 }
 
+func reminderFor(params *model.NotificationParams) channel.Reminder {
+	return channel.Reminder{
+		TaskID:   params.TaskID,
+		TaskType: params.TaskType,
+		Color:    params.Color,
+		Mode:     params.Mode,
+		DryRun:   params.DryRun,
+	}
+}
+
+// deliveryHTTPStatus maps a bulk send outcome to an HTTP status. Only a
+// retryable outcome gets a non-2xx status, because the caller (Cloud Tasks)
+// retries on anything else and would re-notify tokens that were already reached.
+func deliveryHTTPStatus(status domain.DeliveryStatus, retryable bool) int {
+	switch {
+	case retryable:
+		return http.StatusServiceUnavailable
+	case status == domain.DeliveryStatusPartial:
+		return http.StatusMultiStatus
+	default:
+		return http.StatusOK
+	}
+}
+
+// retryableStatus reports whether an outcome with the given HTTP status is
+// worth another delivery: it was rate limited, is still in progress
+// elsewhere, or failed on the server side.
+func retryableStatus(status int) bool {
+	switch {
+	case status == http.StatusConflict, status == http.StatusTooManyRequests:
+		return true
+	default:
+		return status >= http.StatusInternalServerError
+	}
+}
+
+// retryAfterSeconds formats d as a Retry-After delay, rounded up to a whole second.
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64((d+time.Second-1)/time.Second), 10)
+}
+
 // Health returns a simple health check response for backward compatibility.
 func Health(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
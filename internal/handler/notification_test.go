@@ -0,0 +1,379 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/channel"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/fcm"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/fcm/fcmtest"
+	notifyv1 "github.com/KasumiMercury/primind-notification-invoker/internal/gen/notify/v1"
+	pjson "github.com/KasumiMercury/primind-notification-invoker/internal/proto"
+)
+
+const testTaskID = "0193a4b2-7c1d-7e8f-9a0b-1c2d3e4f5a6b"
+
+func newTestHandler(sender *fcmtest.Sender) *NotificationHandler {
+	client := fcm.NewClientWithSender(sender, fcm.Config{})
+	channels := channel.NewRegistry()
+	channels.Register(domain.ChannelFCM, client)
+
+	return NewNotificationHandler(client, Options{Channels: channels})
+}
+
+func postNotify(h *NotificationHandler, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/notify", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.SendNotification(rec, req)
+	return rec
+}
+
+func TestSendNotification_Success(t *testing.T) {
+	sender := fcmtest.NewSender()
+	sender.FailToken("dead-token", &fcm.SendError{Code: domain.ErrorCodeUnregistered, Message: "unregistered"})
+	h := newTestHandler(sender)
+
+	rec := postNotify(h, `{"tokens":["live-token","dead-token"],"task_id":"`+testTaskID+`","task_type":"TASK_TYPE_SHORT"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp notifyv1.NotificationResponse
+	if err := pjson.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if !resp.Success || resp.Total != 2 || resp.SuccessCount != 1 || resp.FailureCount != 1 {
+		t.Fatalf("unexpected response: %v", &resp)
+	}
+	if resp.Results[0].Token != "live-token" || !resp.Results[0].Success {
+		t.Errorf("unexpected first result: %v", resp.Results[0])
+	}
+	dead := resp.Results[1]
+	if dead.Token != "dead-token" || dead.Success || dead.Error != "unregistered" {
+		t.Errorf("unexpected second result: %v", dead)
+	}
+	if dead.ErrorCode != notifyv1.ErrorCode_ERROR_CODE_UNREGISTERED || !dead.ShouldRemoveToken {
+		t.Errorf("expected dead token to be classified for removal, got %v", dead)
+	}
+}
+
+func TestSendNotification_BadRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "invalid json", body: `{"tokens":`},
+		{name: "empty tokens", body: `{"tokens":[],"task_id":"` + testTaskID + `","task_type":"TASK_TYPE_SHORT"}`},
+		{name: "invalid task id", body: `{"tokens":["a"],"task_id":"not-a-uuid","task_type":"TASK_TYPE_SHORT"}`},
+		{name: "unspecified task type", body: `{"tokens":["a"],"task_id":"` + testTaskID + `"}`},
+		{name: "tokens and topic", body: `{"tokens":["a"],"topic":"team","task_id":"` + testTaskID + `","task_type":"TASK_TYPE_SHORT"}`},
+		{name: "undefined delivery mode", body: `{"tokens":["a"],"task_id":"` + testTaskID + `","task_type":"TASK_TYPE_SHORT","delivery_mode":9}`},
+		{name: "unspecified recipient channel", body: `{"recipients":[{"address":"a"}],"task_id":"` + testTaskID + `","task_type":"TASK_TYPE_SHORT"}`},
+		{name: "empty recipient address", body: `{"recipients":[{"channel":"CHANNEL_FCM"}],"task_id":"` + testTaskID + `","task_type":"TASK_TYPE_SHORT"}`},
+		{name: "invalid fallback email", body: `{"tokens":["a"],"task_id":"` + testTaskID + `","task_type":"TASK_TYPE_SHORT","fallback_email":"not-an-email"}`},
+		{name: "invalid topic", body: `{"topic":"not a topic","task_id":"` + testTaskID + `","task_type":"TASK_TYPE_SHORT"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := fcmtest.NewSender()
+			rec := postNotify(newTestHandler(sender), tt.body)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d", rec.Code)
+			}
+			if len(sender.Messages()) != 0 {
+				t.Error("expected no messages to be sent")
+			}
+		})
+	}
+}
+
+func TestSendNotification_MethodNotAllowed(t *testing.T) {
+	h := newTestHandler(fcmtest.NewSender())
+
+	req := httptest.NewRequest(http.MethodGet, "/notify", nil)
+	rec := httptest.NewRecorder()
+	h.SendNotification(rec, req)
+
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected status 405, got %d", rec.Code)
+	}
+}
+
+func TestSendNotification_FCMError(t *testing.T) {
+	sender := fcmtest.NewSender()
+	sender.FailBatch(errors.New("connection reset"))
+	rec := postNotify(newTestHandler(sender), `{"tokens":["a"],"task_id":"`+testTaskID+`","task_type":"TASK_TYPE_NEAR"}`)
+
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d", rec.Code)
+	}
+
+	var resp notifyv1.NotificationResponse
+	if err := pjson.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Success || resp.Status != notifyv1.DeliveryStatus_DELIVERY_STATUS_FAILED || !resp.Retryable {
+		t.Errorf("unexpected response: %v", &resp)
+	}
+}
+
+func TestSendNotification_PermanentFailuresAreNotRetried(t *testing.T) {
+	sender := fcmtest.NewSender()
+	sender.FailToken("a", &fcm.SendError{Code: domain.ErrorCodeUnregistered, Message: "unregistered"})
+	rec := postNotify(newTestHandler(sender), `{"tokens":["a"],"task_id":"`+testTaskID+`","task_type":"TASK_TYPE_NEAR"}`)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+}
+
+func TestDeliveryHTTPStatus(t *testing.T) {
+	tests := []struct {
+		status    domain.DeliveryStatus
+		retryable bool
+		want      int
+	}{
+		{status: domain.DeliveryStatusComplete, want: http.StatusOK},
+		{status: domain.DeliveryStatusComplete, retryable: true, want: http.StatusServiceUnavailable},
+		{status: domain.DeliveryStatusPartial, want: http.StatusMultiStatus},
+		{status: domain.DeliveryStatusFailed, retryable: true, want: http.StatusServiceUnavailable},
+		{status: domain.DeliveryStatusFailed, want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		if got := deliveryHTTPStatus(tt.status, tt.retryable); got != tt.want {
+			t.Errorf("deliveryHTTPStatus(%s, %v) = %d, want %d", tt.status, tt.retryable, got, tt.want)
+		}
+	}
+}
+
+func TestSendNotification_TopicAndCondition(t *testing.T) {
+	tests := []struct {
+		name          string
+		target        string
+		wantTopic     string
+		wantCondition string
+		wantLabel     string
+	}{
+		{name: "topic", target: `"topic":"/topics/team-a"`, wantTopic: "team-a", wantLabel: "/topics/team-a"},
+		{name: "condition", target: `"condition":"'team-a' in topics || 'team-b' in topics"`, wantCondition: "'team-a' in topics || 'team-b' in topics", wantLabel: "'team-a' in topics || 'team-b' in topics"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := fcmtest.NewSender()
+			rec := postNotify(newTestHandler(sender), `{`+tt.target+`,"task_id":"`+testTaskID+`","task_type":"TASK_TYPE_SCHEDULED"}`)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+
+			sent := sender.SentMessages()
+			if len(sent) != 1 || len(sender.Messages()) != 0 {
+				t.Fatalf("expected a single topic send, got %d sends and %d multicasts", len(sent), len(sender.Messages()))
+			}
+			if sent[0].Topic != tt.wantTopic || sent[0].Condition != tt.wantCondition {
+				t.Errorf("unexpected target: topic=%q condition=%q", sent[0].Topic, sent[0].Condition)
+			}
+
+			var resp notifyv1.NotificationResponse
+			if err := pjson.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Total != 1 || resp.Results[0].Token != tt.wantLabel || !resp.Results[0].Success {
+				t.Errorf("unexpected response: %v", &resp)
+			}
+		})
+	}
+}
+
+func TestSendNotification_DeliveryMode(t *testing.T) {
+	tests := []struct {
+		name             string
+		mode             string
+		wantNotification bool
+	}{
+		{name: "default", wantNotification: true},
+		{name: "notification", mode: `,"delivery_mode":"DELIVERY_MODE_NOTIFICATION"`, wantNotification: true},
+		{name: "data only", mode: `,"delivery_mode":"DELIVERY_MODE_DATA_ONLY"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := fcmtest.NewSender()
+			rec := postNotify(newTestHandler(sender), `{"tokens":["a"],"task_id":"`+testTaskID+`","task_type":"TASK_TYPE_SHORT"`+tt.mode+`}`)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+
+			message := sender.Messages()[0]
+			if got := message.Notification != nil; got != tt.wantNotification {
+				t.Errorf("expected notification payload %v, got %v", tt.wantNotification, got)
+			}
+		})
+	}
+}
+
+func TestSendNotification_DryRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		field      string
+		header     string
+		wantDryRun bool
+		wantStatus int
+	}{
+		{name: "real send", wantStatus: http.StatusOK},
+		{name: "request flag", field: `,"dry_run":true`, wantDryRun: true, wantStatus: http.StatusOK},
+		{name: "header", header: "true", wantDryRun: true, wantStatus: http.StatusOK},
+		{name: "header cannot disable request flag", field: `,"dry_run":true`, header: "false", wantDryRun: true, wantStatus: http.StatusOK},
+		{name: "invalid header", header: "maybe", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := fcmtest.NewSender()
+			body := `{"tokens":["a"],"task_id":"` + testTaskID + `","task_type":"TASK_TYPE_SHORT"` + tt.field + `}`
+			req := httptest.NewRequest(http.MethodPost, "/notify", strings.NewReader(body))
+			if tt.header != "" {
+				req.Header.Set("X-Dry-Run", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			newTestHandler(sender).SendNotification(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp notifyv1.NotificationResponse
+			if err := pjson.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.DryRun != tt.wantDryRun {
+				t.Errorf("expected dry_run=%v in response, got %v", tt.wantDryRun, resp.DryRun)
+			}
+
+			gotDryRun := len(sender.DryRunMessages()) == 1 && len(sender.Messages()) == 0
+			if gotDryRun != tt.wantDryRun {
+				t.Errorf("expected dry run send=%v, got %d sends and %d dry runs", tt.wantDryRun, len(sender.Messages()), len(sender.DryRunMessages()))
+			}
+		})
+	}
+}
+
+func TestSendNotification_Recipients(t *testing.T) {
+	sender := fcmtest.NewSender()
+	sender.FailToken("dead-token", &fcm.SendError{Code: domain.ErrorCodeUnregistered, Message: "unregistered"})
+
+	rec := postNotify(newTestHandler(sender), `{"recipients":[`+
+		`{"channel":"CHANNEL_FCM","address":"live-token"},`+
+		`{"channel":"CHANNEL_FCM","address":"dead-token"}`+
+		`],"task_id":"`+testTaskID+`","task_type":"TASK_TYPE_SHORT"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp notifyv1.NotificationResponse
+	if err := pjson.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Total != 2 || resp.SuccessCount != 1 {
+		t.Fatalf("unexpected response: %v", &resp)
+	}
+	for _, r := range resp.Results {
+		if r.Channel != notifyv1.Channel_CHANNEL_FCM {
+			t.Errorf("expected FCM channel in result, got %v", r)
+		}
+	}
+	if !resp.Results[1].ShouldRemoveToken {
+		t.Errorf("expected dead token to be classified for removal, got %v", resp.Results[1])
+	}
+}
+
+func TestSendNotification_RecipientChannelNotEnabled(t *testing.T) {
+	sender := fcmtest.NewSender()
+	h := NewNotificationHandler(fcm.NewClientWithSender(sender, fcm.Config{}), Options{Channels: channel.NewRegistry()})
+
+	rec := postNotify(h, `{"recipients":[{"channel":"CHANNEL_FCM","address":"a"}],"task_id":"`+testTaskID+`","task_type":"TASK_TYPE_SHORT"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rec.Code)
+	}
+	if len(sender.Messages()) != 0 {
+		t.Error("expected no messages to be sent")
+	}
+}
+
+func TestSendNotification_RateLimited(t *testing.T) {
+	sender := fcmtest.NewSender()
+	limiter := fcm.NewRateLimiter(fcm.RateLimitConfig{Rate: 1, MaxWait: time.Millisecond})
+	client := fcm.NewClientWithSender(sender, fcm.Config{RateLimiter: limiter})
+	h := NewNotificationHandler(client, Options{})
+
+	tokens := make([]string, 500)
+	for i := range tokens {
+		tokens[i] = "token-" + strconv.Itoa(i)
+	}
+	body, _ := json.Marshal(map[string]any{"tokens": tokens, "task_id": testTaskID, "task_type": "TASK_TYPE_SHORT"})
+	if rec := postNotify(h, string(body)); rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 within the burst, got %d", rec.Code)
+	}
+
+	rec := postNotify(h, `{"tokens":["a"],"task_id":"`+testTaskID+`","task_type":"TASK_TYPE_SHORT"}`)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("expected Retry-After 1, got %q", got)
+	}
+
+	var resp notifyv1.NotificationResponse
+	if err := pjson.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !resp.Retryable || resp.Results[0].ErrorCode != notifyv1.ErrorCode_ERROR_CODE_QUOTA_EXCEEDED {
+		t.Errorf("unexpected response: %v", &resp)
+	}
+}
+
+func TestSendNotification_PartlyRateLimited(t *testing.T) {
+	sender := fcmtest.NewSender()
+	limiter := fcm.NewRateLimiter(fcm.RateLimitConfig{Rate: 1, MaxWait: time.Millisecond})
+	client := fcm.NewClientWithSender(sender, fcm.Config{RateLimiter: limiter, Parallelism: 1})
+	h := NewNotificationHandler(client, Options{})
+
+	tokens := make([]string, 501)
+	for i := range tokens {
+		tokens[i] = "token-" + strconv.Itoa(i)
+	}
+	body, _ := json.Marshal(map[string]any{"tokens": tokens, "task_id": testTaskID, "task_type": "TASK_TYPE_SHORT"})
+
+	// The batch past the burst is never sent, but retrying the request would
+	// notify the first batch again: it is acknowledged with 207 and only the
+	// tokens never sent to are marked retryable.
+	rec := postNotify(h, string(body))
+	if rec.Code != http.StatusMultiStatus {
+		t.Fatalf("expected status 207, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp notifyv1.NotificationResponse
+	if err := pjson.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Retryable || resp.SuccessCount != 500 || resp.Status != notifyv1.DeliveryStatus_DELIVERY_STATUS_PARTIAL {
+		t.Errorf("unexpected response: retryable=%v success_count=%d status=%v", resp.Retryable, resp.SuccessCount, resp.Status)
+	}
+	if resp.Results[0].Retryable || !resp.Results[500].Retryable {
+		t.Errorf("expected only the token never sent to to be retryable, got %v and %v", resp.Results[0], resp.Results[500])
+	}
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+// pubsubContentTypeAttribute is the message attribute that names the encoding
+// of the message data, e.g. "application/x-protobuf".
+const pubsubContentTypeAttribute = "content-type"
+
+// pushEnvelope is the body of a Pub/Sub push request. Data is base64 in the
+// JSON, which encoding/json decodes into the byte slice.
+type pushEnvelope struct {
+	Message struct {
+		Data       []byte            `json:"data"`
+		Attributes map[string]string `json:"attributes"`
+		MessageID  string            `json:"messageId"`
+	} `json:"message"`
+	Subscription string `json:"subscription"`
+	// DeliveryAttempt is only set on subscriptions with a dead-letter topic.
+	DeliveryAttempt int `json:"deliveryAttempt"`
+}
+
+// ReceivePubSub handles Pub/Sub push deliveries whose data is a
+// NotificationRequest, as JSON or binary protobuf. The message ID keys
+// idempotency, so redeliveries are not sent again.
+//
+// Pub/Sub acknowledges any 2xx response and redelivers on anything else, so
+// only outcomes a retry may fix are nacked: retryable sends, 409 while a
+// duplicate is in progress, and server errors. Messages that can never be
+// sent are acknowledged, since redelivering them would not help.
+func (h *NotificationHandler) ReceivePubSub(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		slog.Warn("method not allowed", "method", r.Method, "path", r.URL.Path)
+		respondProtoError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		slog.Error("failed to read request body", "error", err)
+		respondProtoError(w, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+
+	var envelope pushEnvelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		slog.Error("failed to decode push envelope", "error", err)
+		writePubSubOutcome(w, failedOutcome(http.StatusBadRequest, "invalid push envelope: "+err.Error()))
+		return
+	}
+	msg := envelope.Message
+	if msg.MessageID == "" {
+		slog.Error("push envelope without message ID")
+		writePubSubOutcome(w, failedOutcome(http.StatusBadRequest, "invalid push envelope: missing messageId"))
+		return
+	}
+
+	logAttrs := []any{
+		"message_id", msg.MessageID,
+		"subscription", envelope.Subscription,
+		"delivery_attempt", envelope.DeliveryAttempt,
+	}
+
+	req, err := decodeNotificationRequest(msg.Data, msg.Attributes[pubsubContentTypeAttribute])
+	if err != nil {
+		slog.Error("failed to decode Pub/Sub message", append(logAttrs, "error", err)...)
+		writePubSubOutcome(w, failedOutcome(http.StatusBadRequest, err.Error()))
+		return
+	}
+
+	writePubSubOutcome(w, h.process(r.Context(), req, delivery{
+		IdempotencyKey: "pubsub:" + msg.MessageID,
+		LogAttrs:       logAttrs,
+	}))
+}
+
+// pubsubAck reports whether an outcome with the given HTTP status should be
+// acknowledged rather than redelivered.
+func pubsubAck(status int) bool {
+	return !retryableStatus(status)
+}
+
+// writePubSubOutcome answers a push delivery: 200 to acknowledge it, or the
+// outcome's own status to have it redelivered. The body is the usual /notify
+// response, for logs only.
+func writePubSubOutcome(w http.ResponseWriter, o *outcome) {
+	if pubsubAck(o.Status) {
+		if o.Status >= http.StatusBadRequest {
+			slog.Warn("acknowledging Pub/Sub message that cannot be sent", "status", o.Status, "error", o.Error)
+		}
+		o.Status = http.StatusOK
+		o.RetryAfter = 0
+	}
+
+	writeOutcome(w, o, jsonContentType)
+}
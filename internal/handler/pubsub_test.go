@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/fcm"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/fcm/fcmtest"
+	commonv1 "github.com/KasumiMercury/primind-notification-invoker/internal/gen/common/v1"
+	notifyv1 "github.com/KasumiMercury/primind-notification-invoker/internal/gen/notify/v1"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/idempotency"
+)
+
+func pushBody(t *testing.T, messageID string, data []byte, attributes map[string]string) string {
+	t.Helper()
+
+	envelope := map[string]any{
+		"message": map[string]any{
+			"data":       base64.StdEncoding.EncodeToString(data),
+			"attributes": attributes,
+			"messageId":  messageID,
+		},
+		"subscription": "projects/p/subscriptions/reminders",
+	}
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		t.Fatalf("failed to encode envelope: %v", err)
+	}
+	return string(body)
+}
+
+func postPubSub(h *NotificationHandler, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/pubsub/push", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ReceivePubSub(rec, req)
+	return rec
+}
+
+func TestReceivePubSub_Encodings(t *testing.T) {
+	binary, err := proto.Marshal(&notifyv1.NotificationRequest{
+		Tokens:   []string{"a"},
+		TaskId:   testTaskID,
+		TaskType: commonv1.TaskType_TASK_TYPE_SHORT,
+	})
+	if err != nil {
+		t.Fatalf("failed to encode request: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		data       []byte
+		attributes map[string]string
+	}{
+		{name: "json", data: []byte(idempotentBody)},
+		{name: "binary protobuf", data: binary, attributes: map[string]string{pubsubContentTypeAttribute: protobufContentType}},
+		{name: "binary protobuf without content type", data: binary},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := fcmtest.NewSender()
+			rec := postPubSub(newTestHandler(sender), pushBody(t, "1", tt.data, tt.attributes))
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			if len(sender.Messages()) != 1 {
+				t.Errorf("expected one send, got %d", len(sender.Messages()))
+			}
+		})
+	}
+}
+
+func TestReceivePubSub_RedeliveryIsDeduplicated(t *testing.T) {
+	sender := fcmtest.NewSender()
+	h := newIdempotentHandler(sender, idempotency.NewMemoryStore(idempotency.Config{}))
+
+	for range 2 {
+		rec := postPubSub(h, pushBody(t, "42", []byte(idempotentBody), nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rec.Code)
+		}
+	}
+	if len(sender.Messages()) != 1 {
+		t.Errorf("expected the redelivery not to be sent, got %d sends", len(sender.Messages()))
+	}
+
+	postPubSub(h, pushBody(t, "43", []byte(idempotentBody), nil))
+	if len(sender.Messages()) != 2 {
+		t.Errorf("expected another message ID to be sent, got %d sends", len(sender.Messages()))
+	}
+}
+
+func TestReceivePubSub_AckAndNack(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		fail   bool
+		status int
+	}{
+		{name: "retryable failure is nacked", data: idempotentBody, fail: true, status: http.StatusServiceUnavailable},
+		{name: "undecodable data is acknowledged", data: `{"tokens":`, status: http.StatusOK},
+		{name: "invalid request is acknowledged", data: `{"tokens":["a"],"task_id":"not-a-uuid"}`, status: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := fcmtest.NewSender()
+			if tt.fail {
+				sender.FailBatch(errors.New("connection reset"))
+			}
+			rec := postPubSub(newTestHandler(sender), pushBody(t, "1", []byte(tt.data), nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestReceivePubSub_InvalidEnvelope(t *testing.T) {
+	for _, body := range []string{`{"message":`, `{"message":{"data":""}}`} {
+		rec := postPubSub(newTestHandler(fcmtest.NewSender()), body)
+		// Redelivering a malformed envelope would not help, so it is acknowledged.
+		if rec.Code != http.StatusOK {
+			t.Errorf("expected status 200 for %s, got %d", body, rec.Code)
+		}
+	}
+}
+
+func TestReceivePubSub_FallbackSentOnce(t *testing.T) {
+	sender := fcmtest.NewSender()
+	fallback := &fakeFallback{}
+	h := NewNotificationHandler(fcm.NewClientWithSender(sender, fcm.Config{}), Options{Fallback: fallback, Idempotency: idempotency.NewMemoryStore(idempotency.Config{})})
+
+	data := `{"tokens":["a"],"task_id":"` + testTaskID + `","task_type":"TASK_TYPE_SHORT","fallback_email":"user@example.com"}`
+	body := pushBody(t, "1", []byte(data), nil)
+
+	// A retryable failure is redelivered without the email, since the
+	// redelivery may still reach the device.
+	sender.FailBatch(errors.New("connection reset"))
+	if rec := postPubSub(h, body); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status 503, got %d", rec.Code)
+	}
+	if len(fallback.sent) != 0 {
+		t.Fatalf("expected no fallback for a retryable failure, got %v", fallback.sent)
+	}
+
+	sender.FailBatch(nil)
+	sender.FailToken("a", &fcm.SendError{Code: domain.ErrorCodeUnregistered, Message: "unregistered"})
+	for range 2 {
+		if rec := postPubSub(h, body); rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rec.Code)
+		}
+	}
+	if len(fallback.sent) != 1 {
+		t.Errorf("expected exactly one fallback email, got %v", fallback.sent)
+	}
+}
@@ -0,0 +1,205 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	notifyv1 "github.com/KasumiMercury/primind-notification-invoker/internal/gen/notify/v1"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/model"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/scheduler"
+)
+
+// StartScheduler starts sending the requests held until their send_at,
+// including those saved before this instance started. It does nothing when
+// scheduling is disabled.
+func (h *NotificationHandler) StartScheduler(ctx context.Context) error {
+	if h.scheduler == nil {
+		return nil
+	}
+
+	return h.scheduler.Start(ctx, h.sendScheduled)
+}
+
+// hold schedules a request whose send_at is in the future. The request is
+// saved before the 202, so that the caller may forget it. It returns nil for
+// requests due now, which are sent right away.
+func (h *NotificationHandler) hold(ctx context.Context, req *notifyv1.NotificationRequest, d delivery, params *model.NotificationParams) *outcome {
+	if req.SendAt == nil {
+		return nil
+	}
+	now := time.Now()
+	sendAt := req.SendAt.AsTime()
+	if !sendAt.After(now) {
+		return nil
+	}
+
+	if h.scheduler == nil {
+		slog.Error("send_at given with scheduling disabled", "task_id", params.TaskID.String(), "send_at", sendAt)
+		return failedOutcome(http.StatusBadRequest, "send_at is in the future, but scheduled sends are disabled")
+	}
+
+	// A key that was used already is answered from the idempotency store
+	// rather than held: a request that was sent gets its response, another
+	// request reusing the key 422. The claim is only a lookup: the send
+	// claims the key when due.
+	key := idempotencyKey(req, d)
+	if !params.DryRun {
+		held, replay := h.claimIdempotency(ctx, key, req)
+		if replay != nil {
+			return replay
+		}
+		h.releaseIdempotency(ctx, held)
+	}
+
+	request, err := proto.Marshal(req)
+	if err != nil {
+		slog.Error("failed to encode request for scheduling", "error", err)
+		return failedOutcome(http.StatusInternalServerError, "failed to schedule notification")
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		slog.Error("failed to generate scheduled notification ID", "error", err)
+		return failedOutcome(http.StatusInternalServerError, "failed to schedule notification")
+	}
+
+	entry, err := h.scheduler.Schedule(ctx, scheduler.Entry{
+		ID:        id.String(),
+		TaskID:    params.TaskID.String(),
+		Key:       key,
+		DryRun:    params.DryRun,
+		SendAt:    sendAt,
+		CreatedAt: now,
+		Request:   request,
+	})
+	switch {
+	case errors.Is(err, scheduler.ErrBeyondHorizon):
+		slog.Error("send_at beyond the scheduling horizon", "task_id", params.TaskID.String(), "send_at", sendAt, "error", err)
+		return failedOutcome(http.StatusBadRequest, err.Error()+"; schedule it closer to its time")
+	case err != nil:
+		slog.Error("failed to schedule notification", "task_id", params.TaskID.String(), "error", err)
+		return failedOutcome(http.StatusServiceUnavailable, "failed to schedule notification: "+err.Error())
+	}
+
+	slog.Info("notification scheduled", append([]any{
+		"scheduled_id", entry.ID,
+		"task_id", entry.TaskID,
+		"send_at", entry.SendAt,
+		"dry_run", entry.DryRun,
+	}, d.LogAttrs...)...)
+
+	return &outcome{
+		Status: http.StatusAccepted,
+		Response: &notifyv1.NotificationResponse{
+			Success:   true,
+			Total:     int32(targetCount(params)),
+			DryRun:    params.DryRun,
+			Scheduled: true,
+		},
+	}
+}
+
+// sendScheduled sends a request the scheduler held until its send_at. It
+// keeps the idempotency key the request was received with, so that a
+// redelivery after the send is still deduplicated. A retryable outcome is
+// returned as an error for the scheduler to retry; the last attempt of the
+// scheduler is dead-lettered and gets the fallback email, like that of a
+// Cloud Tasks task.
+func (h *NotificationHandler) sendScheduled(ctx context.Context, entry scheduler.Entry) error {
+	var req notifyv1.NotificationRequest
+	if err := proto.Unmarshal(entry.Request, &req); err != nil {
+		slog.Error("failed to decode scheduled notification", "scheduled_id", entry.ID, "task_id", entry.TaskID, "error", err)
+		return nil
+	}
+	// Due now, also when a retry runs ahead of it.
+	req.SendAt = nil
+
+	o := h.process(ctx, &req, delivery{
+		DryRun:      entry.DryRun,
+		ResolvedKey: entry.Key,
+		Attempt:     taskAttempt{TaskName: "scheduled/" + entry.ID, RetryCount: entry.Attempts, MaxAttempts: h.scheduler.MaxAttempts()},
+		LogAttrs:    []any{"scheduled_id", entry.ID},
+	})
+
+	slog.Info("scheduled notification processed",
+		"scheduled_id", entry.ID,
+		"task_id", entry.TaskID,
+		"send_at", entry.SendAt,
+		"attempt", entry.Attempts+1,
+		"status", o.Status,
+		"error", o.Error,
+	)
+
+	if retryableStatus(o.Status) {
+		return fmt.Errorf("scheduled notification failed with status %d", o.Status)
+	}
+	return nil
+}
+
+// ListScheduled handles GET /admin/scheduled, listing the requests held
+// until their send_at.
+func (h *NotificationHandler) ListScheduled(w http.ResponseWriter, r *http.Request) {
+	accept := responseContentType(r.Header.Get("Accept"))
+
+	var entries []scheduler.Entry
+	if h.scheduler != nil {
+		var err error
+		if entries, err = h.scheduler.List(r.Context()); err != nil {
+			slog.Error("failed to list scheduled notifications", "error", err)
+			respondErrorAs(w, http.StatusServiceUnavailable, accept, "failed to list scheduled notifications")
+			return
+		}
+	}
+
+	respondMessage(w, http.StatusOK, accept, scheduledList(entries))
+}
+
+// CancelScheduled handles DELETE /admin/scheduled/{task_id}, dropping the
+// held requests of a task. It answers 404 when none is pending.
+func (h *NotificationHandler) CancelScheduled(w http.ResponseWriter, r *http.Request) {
+	accept := responseContentType(r.Header.Get("Accept"))
+	taskID := r.PathValue("task_id")
+
+	var canceled []scheduler.Entry
+	if h.scheduler != nil {
+		var err error
+		if canceled, err = h.scheduler.Cancel(r.Context(), taskID); err != nil {
+			slog.Error("failed to cancel scheduled notifications", "task_id", taskID, "error", err)
+			respondErrorAs(w, http.StatusServiceUnavailable, accept, "failed to cancel scheduled notifications")
+			return
+		}
+	}
+	if len(canceled) == 0 {
+		respondErrorAs(w, http.StatusNotFound, accept, "no scheduled notification for task "+taskID)
+		return
+	}
+
+	slog.Info("scheduled notifications canceled", "task_id", taskID, "count", len(canceled))
+
+	respondMessage(w, http.StatusOK, accept, scheduledList(canceled))
+}
+
+func scheduledList(entries []scheduler.Entry) *notifyv1.ScheduledNotificationList {
+	list := &notifyv1.ScheduledNotificationList{
+		Notifications: make([]*notifyv1.ScheduledNotification, len(entries)),
+	}
+	for i, e := range entries {
+		list.Notifications[i] = &notifyv1.ScheduledNotification{
+			Id:        e.ID,
+			TaskId:    e.TaskID,
+			SendAt:    timestamppb.New(e.SendAt),
+			CreatedAt: timestamppb.New(e.CreatedAt),
+			DryRun:    e.DryRun,
+		}
+	}
+
+	return list
+}
@@ -0,0 +1,267 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/channel"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/dbtest"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/fcm"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/fcm/fcmtest"
+	notifyv1 "github.com/KasumiMercury/primind-notification-invoker/internal/gen/notify/v1"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/idempotency"
+	pjson "github.com/KasumiMercury/primind-notification-invoker/internal/proto"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/scheduler"
+)
+
+func newScheduleHandler(t *testing.T, sender *fcmtest.Sender, opts Options) (*NotificationHandler, *scheduler.Scheduler) {
+	t.Helper()
+
+	store := scheduler.NewSQLStore(dbtest.Open(t))
+	if err := store.Migrate(context.Background()); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	s := scheduler.New(scheduler.Config{Horizon: time.Hour, Store: store, PollInterval: time.Hour})
+	client := fcm.NewClientWithSender(sender, fcm.Config{})
+	opts.Channels = channel.NewRegistry()
+	opts.Channels.Register(domain.ChannelFCM, client)
+	opts.Scheduler = s
+	h := NewNotificationHandler(client, opts)
+	if err := h.StartScheduler(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Shutdown(context.Background()) })
+	return h, s
+}
+
+// waitFor polls cond until it holds or a second has passed.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	for deadline := time.Now().Add(time.Second); !cond(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the scheduled send")
+		}
+	}
+}
+
+func sendAtBody(sendAt time.Time) string {
+	return `{"tokens":["a"],"task_id":"` + testTaskID + `","task_type":"TASK_TYPE_SCHEDULED","send_at":"` + sendAt.UTC().Format(time.RFC3339Nano) + `"}`
+}
+
+func listScheduled(t *testing.T, h *NotificationHandler) []*notifyv1.ScheduledNotification {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.ListScheduled(rec, httptest.NewRequest(http.MethodGet, "/admin/scheduled", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var list notifyv1.ScheduledNotificationList
+	if err := pjson.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatalf("failed to decode list: %v", err)
+	}
+	return list.Notifications
+}
+
+func cancelScheduled(h *NotificationHandler, taskID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodDelete, "/admin/scheduled/"+taskID, nil)
+	req.SetPathValue("task_id", taskID)
+	rec := httptest.NewRecorder()
+	h.CancelScheduled(rec, req)
+	return rec
+}
+
+func TestSendNotification_SendAtHeld(t *testing.T) {
+	sender := fcmtest.NewSender()
+	h, s := newScheduleHandler(t, sender, Options{})
+
+	rec := postNotify(h, sendAtBody(time.Now().Add(time.Minute)))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp notifyv1.NotificationResponse
+	if err := pjson.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !resp.Scheduled || resp.Total != 1 {
+		t.Errorf("expected a scheduled response for 1 token, got %v", &resp)
+	}
+	if len(sender.Messages()) != 0 {
+		t.Errorf("expected nothing to be sent before send_at, got %d sends", len(sender.Messages()))
+	}
+
+	pending := listScheduled(t, h)
+	if len(pending) != 1 || pending[0].TaskId != testTaskID {
+		t.Fatalf("expected the request to be pending, got %v", pending)
+	}
+
+	// Shutting down leaves held requests to the next instance.
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(sender.Messages()) != 0 {
+		t.Errorf("expected nothing to be sent on shutdown, got %d sends", len(sender.Messages()))
+	}
+	if pending := listScheduled(t, h); len(pending) != 1 {
+		t.Errorf("expected the request to stay saved, got %v", pending)
+	}
+}
+
+func TestSendNotification_SendAtRetried(t *testing.T) {
+	sender := fcmtest.NewSender()
+	sender.FailBatch(errors.New("connection reset"))
+	h, s := newScheduleHandler(t, sender, Options{})
+
+	if rec := postNotify(h, sendAtBody(time.Now().Add(20*time.Millisecond))); rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d", rec.Code)
+	}
+
+	var entries []scheduler.Entry
+	waitFor(t, func() bool {
+		entries, _ = s.List(context.Background())
+		return len(entries) == 1 && entries[0].Attempts == 1
+	})
+	if len(sender.Messages()) != 1 {
+		t.Errorf("expected one failed send, got %d", len(sender.Messages()))
+	}
+}
+
+func TestSendNotification_SendAt(t *testing.T) {
+	tests := []struct {
+		name      string
+		sendAt    time.Duration
+		scheduler bool
+		status    int
+		error     string
+	}{
+		{name: "past is sent now", sendAt: -time.Minute, scheduler: true, status: http.StatusOK},
+		{name: "beyond the horizon", sendAt: 2 * time.Hour, scheduler: true, status: http.StatusBadRequest, error: "horizon"},
+		{name: "scheduling disabled", sendAt: time.Minute, status: http.StatusBadRequest, error: "disabled"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := fcmtest.NewSender()
+			h := newTestHandler(sender)
+			if tt.scheduler {
+				h, _ = newScheduleHandler(t, sender, Options{})
+			}
+
+			rec := postNotify(h, sendAtBody(time.Now().Add(tt.sendAt)))
+			if rec.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, rec.Code, rec.Body.String())
+			}
+			if tt.error != "" && !strings.Contains(rec.Body.String(), tt.error) {
+				t.Errorf("expected an error mentioning %q, got %s", tt.error, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestCancelScheduled(t *testing.T) {
+	sender := fcmtest.NewSender()
+	h, s := newScheduleHandler(t, sender, Options{})
+
+	if rec := postNotify(h, sendAtBody(time.Now().Add(time.Minute))); rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d", rec.Code)
+	}
+
+	if rec := cancelScheduled(h, testTaskID); rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	if pending := listScheduled(t, h); len(pending) != 0 {
+		t.Errorf("expected nothing pending after cancel, got %v", pending)
+	}
+	if rec := cancelScheduled(h, testTaskID); rec.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 with nothing to cancel, got %d", rec.Code)
+	}
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(sender.Messages()) != 0 {
+		t.Errorf("expected a canceled request not to be sent, got %d sends", len(sender.Messages()))
+	}
+}
+
+func TestSendNotification_SendAtKeepsIdempotencyKey(t *testing.T) {
+	sender := fcmtest.NewSender()
+	h, s := newScheduleHandler(t, sender, Options{Idempotency: idempotency.NewMemoryStore(idempotency.Config{})})
+	headers := map[string]string{idempotencyKeyHeader: "reminder-1"}
+
+	for range 2 {
+		if rec := postWithHeaders(h, sendAtBody(time.Now().Add(100*time.Millisecond)), headers); rec.Code != http.StatusAccepted {
+			t.Fatalf("expected status 202, got %d", rec.Code)
+		}
+	}
+	if pending := listScheduled(t, h); len(pending) != 1 {
+		t.Fatalf("expected a redelivery not to be held twice, got %d pending", len(pending))
+	}
+
+	waitFor(t, func() bool { return len(listScheduled(t, h)) == 0 })
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rec := postWithHeaders(h, sendAtBody(time.Now()), headers)
+	if rec.Code != http.StatusOK || rec.Header().Get(replayedHeader) != "true" {
+		t.Errorf("expected the held send to be replayed, got status %d", rec.Code)
+	}
+	if len(sender.Messages()) != 1 {
+		t.Errorf("expected one send, got %d", len(sender.Messages()))
+	}
+}
+
+func TestSendNotification_SendAtKeyReused(t *testing.T) {
+	sender := fcmtest.NewSender()
+	h, _ := newScheduleHandler(t, sender, Options{Idempotency: idempotency.NewMemoryStore(idempotency.Config{})})
+	headers := map[string]string{idempotencyKeyHeader: "reminder-1"}
+
+	if rec := postWithHeaders(h, idempotentBody, headers); rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	rec := postWithHeaders(h, sendAtBody(time.Now().Add(time.Minute)), headers)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected status 422 for another request with the same key, got %d", rec.Code)
+	}
+	if pending := listScheduled(t, h); len(pending) != 0 {
+		t.Errorf("expected nothing to be held, got %v", pending)
+	}
+}
+
+func TestSendScheduled_LastAttemptIsDeadLettered(t *testing.T) {
+	sender := fcmtest.NewSender()
+	sender.FailBatch(errors.New("connection reset"))
+	sink := &fakeDeadLetter{}
+
+	// Cloud Tasks retries are not limited; the scheduler's are.
+	s := scheduler.New(scheduler.Config{Horizon: time.Hour, MaxAttempts: 1})
+	h := NewNotificationHandler(fcm.NewClientWithSender(sender, fcm.Config{}), Options{DeadLetter: sink, Scheduler: s})
+	if err := h.StartScheduler(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Shutdown(context.Background()) })
+
+	if rec := postNotify(h, sendAtBody(time.Now().Add(20*time.Millisecond))); rec.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d", rec.Code)
+	}
+
+	waitFor(t, func() bool {
+		sink.mu.Lock()
+		defer sink.mu.Unlock()
+		return len(sink.entries) == 1
+	})
+	if entry := sink.entries[0]; !strings.HasPrefix(entry.TaskName, "scheduled/") || entry.Attempt != 1 {
+		t.Errorf("expected the scheduled send to be dead-lettered on its first attempt, got %+v", entry)
+	}
+}
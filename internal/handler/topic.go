@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/fcm"
+	notifyv1 "github.com/KasumiMercury/primind-notification-invoker/internal/gen/notify/v1"
+	pjson "github.com/KasumiMercury/primind-notification-invoker/internal/proto"
+)
+
+type topicOperation func(ctx context.Context, tokens []domain.FCMToken, topic domain.Topic) (*fcm.TopicResult, error)
+
+// SubscribeToTopic subscribes FCM tokens to a topic.
+func (h *NotificationHandler) SubscribeToTopic(w http.ResponseWriter, r *http.Request) {
+	h.manageTopic(w, r, "subscribe", h.fcmClient.SubscribeToTopic)
+}
+
+// UnsubscribeFromTopic unsubscribes FCM tokens from a topic.
+func (h *NotificationHandler) UnsubscribeFromTopic(w http.ResponseWriter, r *http.Request) {
+	h.manageTopic(w, r, "unsubscribe", h.fcmClient.UnsubscribeFromTopic)
+}
+
+func (h *NotificationHandler) manageTopic(w http.ResponseWriter, r *http.Request, action string, op topicOperation) {
+	if r.Method != http.MethodPost {
+		slog.Warn("method not allowed", "method", r.Method, "path", r.URL.Path)
+		respondProtoError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		slog.Error("failed to read request body", "error", err)
+		respondProtoError(w, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+
+	var req notifyv1.TopicSubscriptionRequest
+	if err := pjson.Unmarshal(body, &req); err != nil {
+		slog.Error("failed to decode request body", "error", err)
+		respondProtoError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+
+	if err := pjson.Validate(&req); err != nil {
+		slog.Error("validation error", "error", err)
+		respondProtoError(w, http.StatusBadRequest, "validation error: "+err.Error())
+		return
+	}
+
+	topic, err := domain.NewTopic(req.Topic)
+	if err != nil {
+		slog.Error("invalid topic", "error", err)
+		respondProtoError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tokens, err := domain.NewFCMTokens(req.Tokens)
+	if err != nil {
+		slog.Error("invalid tokens", "error", err)
+		respondProtoError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := op(r.Context(), tokens, topic)
+	if err != nil {
+		slog.Error("FCM topic management failed", "action", action, "topic", topic.String(), "error", err)
+		respondProtoError(w, http.StatusBadGateway, "FCM error: "+err.Error())
+		return
+	}
+
+	slog.Info("topic subscriptions updated",
+		"action", action,
+		"topic", topic.String(),
+		"success_count", result.SuccessCount,
+		"failure_count", result.FailureCount,
+	)
+
+	protoErrors := make([]*notifyv1.TopicSubscriptionError, len(result.Errors))
+	for i, e := range result.Errors {
+		protoErrors[i] = &notifyv1.TopicSubscriptionError{
+			Index:  int32(e.Index),
+			Token:  e.Token,
+			Reason: e.Reason,
+		}
+	}
+
+	resp := &notifyv1.TopicSubscriptionResponse{
+		Success:      result.FailureCount == 0,
+		SuccessCount: int32(result.SuccessCount),
+		FailureCount: int32(result.FailureCount),
+		Errors:       protoErrors,
+	}
+
+	respBytes, err := pjson.Marshal(resp)
+	if err != nil {
+		slog.Error("failed to marshal response", "error", err)
+		respondProtoError(w, http.StatusInternalServerError, "failed to marshal response")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(respBytes); err != nil {
+		slog.Warn("failed to write response", "error", err)
+	}
+}
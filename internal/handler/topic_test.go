@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/fcm/fcmtest"
+	notifyv1 "github.com/KasumiMercury/primind-notification-invoker/internal/gen/notify/v1"
+	pjson "github.com/KasumiMercury/primind-notification-invoker/internal/proto"
+)
+
+func TestManageTopic(t *testing.T) {
+	sender := fcmtest.NewSender()
+	sender.FailToken("bad", errors.New("invalid-argument"))
+	h := newTestHandler(sender)
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/topics/subscribe", strings.NewReader(`{"topic":"team-a","tokens":["a","bad","b"]}`))
+	rec := httptest.NewRecorder()
+	h.SubscribeToTopic(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var resp notifyv1.TopicSubscriptionResponse
+	if err := pjson.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Success || resp.SuccessCount != 2 || resp.FailureCount != 1 {
+		t.Fatalf("unexpected response: %v", &resp)
+	}
+	if e := resp.Errors[0]; e.Index != 1 || e.Token != "bad" || e.Reason != "invalid-argument" {
+		t.Errorf("unexpected error entry: %v", e)
+	}
+	if got := sender.Subscribers("team-a"); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("unexpected subscribers: %v", got)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/admin/topics/unsubscribe", strings.NewReader(`{"topic":"/topics/team-a","tokens":["a"]}`))
+	rec = httptest.NewRecorder()
+	h.UnsubscribeFromTopic(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := sender.Subscribers("team-a"); !slices.Equal(got, []string{"b"}) {
+		t.Errorf("unexpected subscribers after unsubscribe: %v", got)
+	}
+}
+
+func TestManageTopic_BadRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "no tokens", body: `{"topic":"team-a","tokens":[]}`},
+		{name: "invalid topic", body: `{"topic":"team a","tokens":["a"]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/admin/topics/subscribe", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			newTestHandler(fcmtest.NewSender()).SubscribeToTopic(rec, req)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d", rec.Code)
+			}
+		})
+	}
+}
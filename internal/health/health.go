@@ -19,7 +19,9 @@ const (
 // CheckResult represents the health check result for a single dependency.
 type CheckResult struct {
 	Status Status `json:"status"`
-	Error  string `json:"error,omitempty"`
+	// State is the dependency's own state, such as a circuit breaker state.
+	State string `json:"state,omitempty"`
+	Error string `json:"error,omitempty"`
 }
 
 // HealthStatus represents the overall health status of the service.
@@ -29,8 +31,15 @@ type HealthStatus struct {
 	Checks  map[string]CheckResult `json:"checks,omitempty"`
 }
 
+// circuitOpen is the FCMClient circuit state in which sends fail fast.
+const circuitOpen = "open"
+
 // FCMClient is an interface for checking FCM client health.
-type FCMClient interface{}
+// *fcm.Client implements it.
+type FCMClient interface {
+	// CircuitState returns the circuit breaker state: "closed", "open" or "half_open".
+	CircuitState() string
+}
 
 // Checker performs health checks on service dependencies.
 type Checker struct {
@@ -54,20 +63,37 @@ func (c *Checker) Check(_ context.Context) *HealthStatus {
 		Checks:  make(map[string]CheckResult),
 	}
 
-	// FCM client check (nil check only as FCM doesn't provide ping)
-	if c.fcmClient != nil {
-		status.Checks["fcm"] = CheckResult{
-			Status: StatusHealthy,
-		}
-	} else {
+	status.Checks["fcm"] = c.checkFCM()
+	if status.Checks["fcm"].Status != StatusHealthy {
 		status.Status = StatusUnhealthy
-		status.Checks["fcm"] = CheckResult{
+	}
+
+	return status
+}
+
+// checkFCM reports the FCM circuit breaker, since FCM doesn't provide ping.
+// A half-open breaker stays healthy so the probe send can reach FCM.
+func (c *Checker) checkFCM() CheckResult {
+	if c.fcmClient == nil {
+		return CheckResult{
 			Status: StatusUnhealthy,
 			Error:  "FCM client not initialized",
 		}
 	}
 
-	return status
+	state := c.fcmClient.CircuitState()
+	if state == circuitOpen {
+		return CheckResult{
+			Status: StatusUnhealthy,
+			State:  state,
+			Error:  "FCM circuit breaker is open",
+		}
+	}
+
+	return CheckResult{
+		Status: StatusHealthy,
+		State:  state,
+	}
 }
 
 // IsHealthy returns true if all dependencies are healthy.
@@ -0,0 +1,56 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/grpchealth"
+)
+
+type stubFCMClient struct {
+	state string
+}
+
+func (s stubFCMClient) CircuitState() string {
+	return s.state
+}
+
+func TestChecker_CircuitState(t *testing.T) {
+	tests := []struct {
+		state      string
+		wantStatus Status
+		wantHTTP   int
+		wantGRPC   grpchealth.Status
+	}{
+		{state: "closed", wantStatus: StatusHealthy, wantHTTP: http.StatusOK, wantGRPC: grpchealth.StatusServing},
+		{state: "half_open", wantStatus: StatusHealthy, wantHTTP: http.StatusOK, wantGRPC: grpchealth.StatusServing},
+		{state: "open", wantStatus: StatusUnhealthy, wantHTTP: http.StatusServiceUnavailable, wantGRPC: grpchealth.StatusNotServing},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.state, func(t *testing.T) {
+			checker := NewChecker(stubFCMClient{state: tt.state}, "test")
+
+			status := checker.Check(context.Background())
+			if status.Status != tt.wantStatus || status.Checks["fcm"].State != tt.state {
+				t.Errorf("unexpected status: %+v", status)
+			}
+
+			rec := httptest.NewRecorder()
+			checker.ReadyHandler(rec, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+			if rec.Code != tt.wantHTTP {
+				t.Errorf("expected readiness %d, got %d", tt.wantHTTP, rec.Code)
+			}
+
+			resp, err := NewGRPCChecker(checker).Check(context.Background(), &grpchealth.CheckRequest{})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Status != tt.wantGRPC {
+				t.Errorf("expected gRPC status %v, got %v", tt.wantGRPC, resp.Status)
+			}
+		})
+	}
+}
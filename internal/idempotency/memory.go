@@ -0,0 +1,93 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/sweep"
+)
+
+// MemoryStore is a Store local to one instance. A duplicate delivered to
+// another instance is sent again, so several instances need a SQLStore.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*entry
+	cfg     Config
+	sweeps  sweep.Pacer
+	now     func() time.Time
+}
+
+type entry struct {
+	record    *Record // nil while claimed
+	hash      string
+	expiresAt time.Time
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+func NewMemoryStore(cfg Config) *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]*entry),
+		cfg:     cfg.withDefaults(),
+		now:     time.Now,
+	}
+}
+
+// Claim implements Store.
+func (s *MemoryStore) Claim(_ context.Context, key, hash string) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	if e, ok := s.entries[key]; ok && now.Before(e.expiresAt) {
+		switch {
+		case !sameRequest(e.hash, hash):
+			return nil, ErrKeyReused
+		case e.record == nil:
+			return nil, ErrInProgress
+		}
+		return e.record, nil
+	}
+
+	s.entries[key] = &entry{hash: hash, expiresAt: now.Add(s.cfg.Lease)}
+	return nil, nil
+}
+
+// Complete implements Store. The request hash of the claim is kept.
+func (s *MemoryStore) Complete(_ context.Context, key string, record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := &entry{record: &record, expiresAt: s.now().Add(s.cfg.TTL)}
+	if claimed, ok := s.entries[key]; ok {
+		e.hash = claimed.hash
+	}
+	s.entries[key] = e
+	return nil
+}
+
+// Release implements Store. Completed keys are kept.
+func (s *MemoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok && e.record == nil {
+		delete(s.entries, key)
+	}
+	return nil
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	if !s.sweeps.Due(now) {
+		return
+	}
+
+	for key, e := range s.entries {
+		if !now.Before(e.expiresAt) {
+			delete(s.entries, key)
+		}
+	}
+}
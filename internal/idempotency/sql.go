@@ -0,0 +1,145 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/sweep"
+)
+
+// SQLStore is a Store shared by every instance through a SQL database.
+// Call Migrate once before use to create its table.
+type SQLStore struct {
+	db     *gorm.DB
+	cfg    Config
+	now    func() time.Time
+	sweeps sweep.Pacer
+}
+
+// sqlRecord is a row of the idempotency_records table. A claimed key has
+// Completed=false until its response is stored.
+type sqlRecord struct {
+	IdempotencyKey string `gorm:"primaryKey;size:512"`
+	RequestHash    string `gorm:"size:64"`
+	Completed      bool
+	StatusCode     int
+	ContentType    string `gorm:"size:255"`
+	Body           []byte
+	ExpiresAt      time.Time `gorm:"index"`
+}
+
+func (sqlRecord) TableName() string {
+	return "idempotency_records"
+}
+
+var _ Store = (*SQLStore)(nil)
+
+func NewSQLStore(db *gorm.DB, cfg Config) *SQLStore {
+	return &SQLStore{
+		db:  db,
+		cfg: cfg.withDefaults(),
+		now: time.Now,
+	}
+}
+
+// Migrate creates or updates the idempotency_records table.
+func (s *SQLStore) Migrate(ctx context.Context) error {
+	return s.db.WithContext(ctx).AutoMigrate(&sqlRecord{})
+}
+
+// Claim implements Store. The claim is an insert that does nothing on
+// conflict, so concurrent duplicates race on the primary key.
+func (s *SQLStore) Claim(ctx context.Context, key, hash string) (*Record, error) {
+	db := s.db.WithContext(ctx)
+	now := s.now()
+	s.sweep(ctx, now)
+
+	claim := sqlRecord{IdempotencyKey: key, RequestHash: hash, ExpiresAt: now.Add(s.cfg.Lease)}
+	res := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&claim)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 1 {
+		return nil, nil
+	}
+
+	// The key exists; take it over if it has expired.
+	res = db.Model(&sqlRecord{}).
+		Where("idempotency_key = ? AND expires_at <= ?", key, now).
+		Updates(map[string]any{
+			"request_hash": hash,
+			"completed":    false,
+			"status_code":  0,
+			"content_type": "",
+			"body":         nil,
+			"expires_at":   now.Add(s.cfg.Lease),
+		})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.RowsAffected == 1 {
+		return nil, nil
+	}
+
+	var existing sqlRecord
+	if err := db.Where("idempotency_key = ?", key).Take(&existing).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Released between our insert and select; the caller may retry.
+			return nil, ErrInProgress
+		}
+		return nil, err
+	}
+	if !sameRequest(existing.RequestHash, hash) {
+		return nil, ErrKeyReused
+	}
+	if !existing.Completed {
+		return nil, ErrInProgress
+	}
+
+	return &Record{
+		StatusCode:  existing.StatusCode,
+		ContentType: existing.ContentType,
+		Body:        existing.Body,
+	}, nil
+}
+
+// Complete implements Store. The request hash of the claim is kept.
+func (s *SQLStore) Complete(ctx context.Context, key string, record Record) error {
+	row := sqlRecord{
+		IdempotencyKey: key,
+		Completed:      true,
+		StatusCode:     record.StatusCode,
+		ContentType:    record.ContentType,
+		Body:           record.Body,
+		ExpiresAt:      s.now().Add(s.cfg.TTL),
+	}
+
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "idempotency_key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"completed", "status_code", "content_type", "body", "expires_at"}),
+	}).Create(&row).Error
+}
+
+// Release implements Store. Completed keys are kept.
+func (s *SQLStore) Release(ctx context.Context, key string) error {
+	return s.db.WithContext(ctx).
+		Where("idempotency_key = ? AND completed = ?", key, false).
+		Delete(&sqlRecord{}).Error
+}
+
+// sweep deletes expired rows, at most once per sweep.Interval. A failed
+// sweep only leaves rows behind; Claim takes expired keys over either way.
+func (s *SQLStore) sweep(ctx context.Context, now time.Time) {
+	if !s.sweeps.Due(now) {
+		return
+	}
+
+	if err := s.db.WithContext(ctx).Where("expires_at <= ?", now).Delete(&sqlRecord{}).Error; err != nil {
+		slog.Warn("failed to delete expired idempotency records", "error", err)
+	}
+}
@@ -0,0 +1,69 @@
+// Package idempotency remembers the responses of completed requests so that
+// redelivered requests are answered without sending the reminder again.
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	defaultTTL   = 24 * time.Hour
+	defaultLease = 2 * time.Minute
+)
+
+var (
+	// ErrInProgress is returned by Claim while another request holds the key.
+	ErrInProgress = errors.New("a request with the same idempotency key is in progress")
+	// ErrKeyReused is returned by Claim when the key was claimed for a
+	// request with a different hash.
+	ErrKeyReused = errors.New("the idempotency key was used for a different request")
+)
+
+// Record is a stored response.
+type Record struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// Store keeps one entry per idempotency key. A key is either claimed by a
+// request in progress or completed with the response to replay.
+type Store interface {
+	// Claim reserves key for a request whose content hashes to hash. It
+	// returns ErrKeyReused when the key is held or completed for a request
+	// with another hash, the stored record when the key has completed,
+	// ErrInProgress while another request holds an unexpired claim, and
+	// (nil, nil) once the caller holds the claim. An empty hash matches any.
+	Claim(ctx context.Context, key, hash string) (*Record, error)
+	// Complete stores the response for a claimed key until the TTL expires.
+	Complete(ctx context.Context, key string, record Record) error
+	// Release drops a claim so that a later request can try again.
+	Release(ctx context.Context, key string) error
+}
+
+type Config struct {
+	// TTL is how long a completed response is replayed. Zero uses defaultTTL.
+	TTL time.Duration
+	// Lease is how long a claim blocks duplicates when its request never
+	// completes, for example because the instance died. Zero uses defaultLease.
+	Lease time.Duration
+}
+
+// sameRequest reports whether a request hashing to hash may use a key
+// claimed with stored. Keys claimed without a hash match any request.
+func sameRequest(stored, hash string) bool {
+	return stored == "" || hash == "" || stored == hash
+}
+
+func (c Config) withDefaults() Config {
+	if c.TTL <= 0 {
+		c.TTL = defaultTTL
+	}
+	if c.Lease <= 0 {
+		c.Lease = defaultLease
+	}
+
+	return c
+}
@@ -0,0 +1,147 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/dbtest"
+)
+
+// storeFactory creates an empty store whose clock is controlled by now.
+type storeFactory func(t *testing.T, cfg Config, now func() time.Time) Store
+
+func stores() map[string]storeFactory {
+	return map[string]storeFactory{
+		"memory": func(_ *testing.T, cfg Config, now func() time.Time) Store {
+			s := NewMemoryStore(cfg)
+			s.now = now
+			return s
+		},
+		"sql": func(t *testing.T, cfg Config, now func() time.Time) Store {
+			t.Helper()
+
+			s := NewSQLStore(dbtest.Open(t), cfg)
+			s.now = now
+			if err := s.Migrate(context.Background()); err != nil {
+				t.Fatalf("failed to migrate: %v", err)
+			}
+			return s
+		},
+	}
+}
+
+func TestStore_ClaimAndComplete(t *testing.T) {
+	for name, newStore := range stores() {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Unix(1_700_000_000, 0)
+			s := newStore(t, Config{TTL: time.Hour, Lease: time.Minute}, func() time.Time { return now })
+
+			if record, err := s.Claim(ctx, "a", "h1"); record != nil || err != nil {
+				t.Fatalf("expected the first claim to succeed, got %v, %v", record, err)
+			}
+			if _, err := s.Claim(ctx, "a", "h1"); !errors.Is(err, ErrInProgress) {
+				t.Fatalf("expected ErrInProgress for a held claim, got %v", err)
+			}
+
+			want := Record{StatusCode: 207, ContentType: "application/json", Body: []byte(`{"success":true}`)}
+			if err := s.Complete(ctx, "a", want); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			record, err := s.Claim(ctx, "a", "h1")
+			if err != nil || record == nil {
+				t.Fatalf("expected the stored record, got %v, %v", record, err)
+			}
+			if record.StatusCode != want.StatusCode || record.ContentType != want.ContentType || string(record.Body) != string(want.Body) {
+				t.Errorf("expected %+v, got %+v", want, record)
+			}
+
+			now = now.Add(time.Hour)
+			if record, err := s.Claim(ctx, "a", "h1"); record != nil || err != nil {
+				t.Errorf("expected an expired record to be claimable, got %v, %v", record, err)
+			}
+		})
+	}
+}
+
+func TestStore_Release(t *testing.T) {
+	for name, newStore := range stores() {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Unix(1_700_000_000, 0)
+			s := newStore(t, Config{TTL: time.Hour, Lease: time.Minute}, func() time.Time { return now })
+
+			if _, err := s.Claim(ctx, "a", "h1"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := s.Release(ctx, "a"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if record, err := s.Claim(ctx, "a", "h1"); record != nil || err != nil {
+				t.Fatalf("expected a released key to be claimable, got %v, %v", record, err)
+			}
+
+			if err := s.Complete(ctx, "a", Record{StatusCode: 200}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := s.Release(ctx, "a"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if record, err := s.Claim(ctx, "a", "h1"); err != nil || record == nil {
+				t.Errorf("expected release to keep a completed key, got %v, %v", record, err)
+			}
+		})
+	}
+}
+
+func TestStore_ExpiredLease(t *testing.T) {
+	for name, newStore := range stores() {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Unix(1_700_000_000, 0)
+			s := newStore(t, Config{TTL: time.Hour, Lease: time.Minute}, func() time.Time { return now })
+
+			if _, err := s.Claim(ctx, "a", "h1"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			now = now.Add(time.Minute)
+			if record, err := s.Claim(ctx, "a", "h1"); record != nil || err != nil {
+				t.Errorf("expected an abandoned claim to be taken over, got %v, %v", record, err)
+			}
+		})
+	}
+}
+
+func TestStore_KeyReused(t *testing.T) {
+	for name, newStore := range stores() {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Unix(1_700_000_000, 0)
+			s := newStore(t, Config{TTL: time.Hour, Lease: time.Minute}, func() time.Time { return now })
+
+			if _, err := s.Claim(ctx, "a", "h1"); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := s.Claim(ctx, "a", "h2"); !errors.Is(err, ErrKeyReused) {
+				t.Fatalf("expected ErrKeyReused for a held claim, got %v", err)
+			}
+
+			if err := s.Complete(ctx, "a", Record{StatusCode: 200}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, err := s.Claim(ctx, "a", "h2"); !errors.Is(err, ErrKeyReused) {
+				t.Errorf("expected ErrKeyReused for a completed key, got %v", err)
+			}
+			if record, err := s.Claim(ctx, "a", "h1"); err != nil || record == nil {
+				t.Errorf("expected the stored record for the same request, got %v, %v", record, err)
+			}
+			if record, err := s.Claim(ctx, "a", ""); err != nil || record == nil {
+				t.Errorf("expected an empty hash to match, got %v, %v", record, err)
+			}
+		})
+	}
+}
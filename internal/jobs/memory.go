@@ -0,0 +1,99 @@
+package jobs
+
+import (
+	"cmp"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/sweep"
+)
+
+// MemoryStore is a Store local to one instance. A poll answered by another
+// instance gets 404, and the jobs are lost when the instance exits.
+type MemoryStore struct {
+	mu     sync.Mutex
+	jobs   map[string]Job
+	cfg    Config
+	sweeps sweep.Pacer
+	now    func() time.Time
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+func NewMemoryStore(cfg Config) *MemoryStore {
+	cfg.TTL = cmp.Or(cfg.TTL, defaultTTL)
+	cfg.MaxEntries = cmp.Or(cfg.MaxEntries, defaultMaxEntries)
+
+	return &MemoryStore{
+		jobs: make(map[string]Job),
+		cfg:  cfg,
+		now:  time.Now,
+	}
+}
+
+// Create implements Store.
+func (s *MemoryStore) Create(_ context.Context, job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(s.now())
+	if len(s.jobs) >= s.cfg.MaxEntries && !s.evictFinished() {
+		return ErrFull
+	}
+	s.jobs[job.ID] = job
+	return nil
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(_ context.Context, id string) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok || !s.now().Before(job.UpdatedAt.Add(s.cfg.TTL)) {
+		return nil, ErrNotFound
+	}
+	return &job, nil
+}
+
+// Update implements Store.
+func (s *MemoryStore) Update(_ context.Context, job Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[job.ID]; !ok {
+		return ErrNotFound
+	}
+	s.jobs[job.ID] = job
+	return nil
+}
+
+func (s *MemoryStore) sweep(now time.Time) {
+	if !s.sweeps.Due(now) {
+		return
+	}
+
+	for id, job := range s.jobs {
+		if !now.Before(job.UpdatedAt.Add(s.cfg.TTL)) {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// evictFinished drops the finished job updated least recently. It reports
+// false when every job is still in progress.
+func (s *MemoryStore) evictFinished() bool {
+	var oldest *Job
+	for _, job := range s.jobs {
+		if job.State.Finished() && (oldest == nil || job.UpdatedAt.Before(oldest.UpdatedAt)) {
+			oldest = &job
+		}
+	}
+	if oldest == nil {
+		return false
+	}
+
+	delete(s.jobs, oldest.ID)
+	return true
+}
@@ -0,0 +1,100 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrQueueFull is returned by Submit when every worker is busy and the queue is full.
+	ErrQueueFull = errors.New("job queue is full")
+	// ErrClosed is returned by Submit once Shutdown has been called.
+	ErrClosed = errors.New("job pool is shut down")
+)
+
+// Pool runs jobs on a fixed number of workers. Jobs outlive the request that
+// submitted them: they are only canceled when Shutdown gives up waiting.
+type Pool struct {
+	queue chan func()
+	wg    sync.WaitGroup
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewPool starts workers that take jobs from a queue of queueSize.
+func NewPool(workers, queueSize int) *Pool {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool{
+		queue:  make(chan func(), queueSize),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	for range max(workers, 1) {
+		p.wg.Go(func() {
+			for run := range p.queue {
+				run()
+			}
+		})
+	}
+
+	return p
+}
+
+// Submit queues fn. It runs with the values of ctx, such as its trace and
+// logging attributes, but not its cancellation or deadline.
+func (p *Pool) Submit(ctx context.Context, fn func(ctx context.Context)) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return ErrClosed
+	}
+
+	run := func() {
+		jobCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		defer cancel()
+		stop := context.AfterFunc(p.ctx, cancel)
+		defer stop()
+
+		fn(jobCtx)
+	}
+
+	select {
+	case p.queue <- run:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Shutdown stops accepting jobs and waits for the queued and running ones
+// to finish. When ctx is done first, running jobs are canceled and Shutdown
+// returns the context's error without waiting for them.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		return ctx.Err()
+	}
+}
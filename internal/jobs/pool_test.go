@@ -0,0 +1,94 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPool_JobOutlivesSubmitter(t *testing.T) {
+	p := NewPool(1, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	release := make(chan struct{})
+	if err := p.Submit(ctx, func(ctx context.Context) {
+		<-release
+		done <- ctx.Err()
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cancel()
+	close(release)
+	if err := <-done; err != nil {
+		t.Errorf("expected the job not to be canceled with its submitter, got %v", err)
+	}
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := p.Submit(context.Background(), func(context.Context) {}); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed after shutdown, got %v", err)
+	}
+}
+
+func TestPool_QueueFull(t *testing.T) {
+	p := NewPool(1, 1)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	t.Cleanup(func() {
+		close(release)
+		_ = p.Shutdown(context.Background())
+	})
+
+	if err := p.Submit(context.Background(), func(context.Context) {
+		close(started)
+		<-release
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-started
+	if err := p.Submit(context.Background(), func(context.Context) { <-release }); err != nil {
+		t.Fatalf("expected the job to be queued, got %v", err)
+	}
+	if err := p.Submit(context.Background(), func(context.Context) {}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("expected ErrQueueFull, got %v", err)
+	}
+}
+
+func TestPool_ShutdownCancelsOnTimeout(t *testing.T) {
+	p := NewPool(1, 0)
+	canceled := make(chan struct{})
+	started := make(chan struct{})
+
+	// An unbuffered queue only accepts a job once a worker waits on it.
+	var err error
+	for range 100 {
+		if err = p.Submit(context.Background(), func(ctx context.Context) {
+			close(started)
+			<-ctx.Done()
+			close(canceled)
+		}); err == nil {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := p.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Error("expected the running job to be canceled")
+	}
+}
@@ -0,0 +1,128 @@
+package jobs
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/sweep"
+)
+
+// SQLStore keeps jobs in a SQL database, so that any instance can answer
+// the poll of a job. Call Migrate once before use.
+type SQLStore struct {
+	db     *gorm.DB
+	cfg    Config
+	now    func() time.Time
+	sweeps sweep.Pacer
+}
+
+// sqlJob is a row of the notification_jobs table.
+type sqlJob struct {
+	ID         string `gorm:"primaryKey;size:64"`
+	State      string `gorm:"size:16"`
+	Total      int
+	Done       int
+	StatusCode int
+	Result     []byte
+	Error      string
+	// The timestamps come from the Job, not from GORM.
+	CreatedAt time.Time `gorm:"autoCreateTime:false"`
+	UpdatedAt time.Time `gorm:"index;autoUpdateTime:false"`
+}
+
+func (sqlJob) TableName() string {
+	return "notification_jobs"
+}
+
+var _ Store = (*SQLStore)(nil)
+
+func NewSQLStore(db *gorm.DB, cfg Config) *SQLStore {
+	return &SQLStore{
+		db:  db,
+		cfg: Config{TTL: cmp.Or(cfg.TTL, defaultTTL)},
+		now: time.Now,
+	}
+}
+
+// Migrate creates or updates the notification_jobs table.
+func (s *SQLStore) Migrate(ctx context.Context) error {
+	return s.db.WithContext(ctx).AutoMigrate(&sqlJob{})
+}
+
+// Create implements Store.
+func (s *SQLStore) Create(ctx context.Context, job Job) error {
+	s.sweep(ctx, s.now())
+
+	row := toSQLJob(job)
+	return s.db.WithContext(ctx).Create(&row).Error
+}
+
+// Get implements Store.
+func (s *SQLStore) Get(ctx context.Context, id string) (*Job, error) {
+	var row sqlJob
+	err := s.db.WithContext(ctx).
+		Where("id = ? AND updated_at > ?", id, s.now().Add(-s.cfg.TTL)).
+		Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Job{
+		ID:         row.ID,
+		State:      State(row.State),
+		Total:      row.Total,
+		Done:       row.Done,
+		StatusCode: row.StatusCode,
+		Result:     row.Result,
+		Error:      row.Error,
+		CreatedAt:  row.CreatedAt,
+		UpdatedAt:  row.UpdatedAt,
+	}, nil
+}
+
+// Update implements Store.
+func (s *SQLStore) Update(ctx context.Context, job Job) error {
+	row := toSQLJob(job)
+	res := s.db.WithContext(ctx).Model(&sqlJob{}).Where("id = ?", job.ID).Select("*").Omit("id", "created_at").Updates(&row)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func toSQLJob(job Job) sqlJob {
+	return sqlJob{
+		ID:         job.ID,
+		State:      string(job.State),
+		Total:      job.Total,
+		Done:       job.Done,
+		StatusCode: job.StatusCode,
+		Result:     job.Result,
+		Error:      job.Error,
+		CreatedAt:  job.CreatedAt,
+		UpdatedAt:  job.UpdatedAt,
+	}
+}
+
+// sweep deletes the jobs past their TTL, at most once per sweep.Interval.
+// A failed sweep only leaves rows behind; Get ignores expired jobs either way.
+func (s *SQLStore) sweep(ctx context.Context, now time.Time) {
+	if !s.sweeps.Due(now) {
+		return
+	}
+
+	if err := s.db.WithContext(ctx).Where("updated_at <= ?", now.Add(-s.cfg.TTL)).Delete(&sqlJob{}).Error; err != nil {
+		slog.Warn("failed to delete expired jobs", "error", err)
+	}
+}
@@ -0,0 +1,72 @@
+// Package jobs tracks notifications sent in the background, so that callers
+// can poll their progress and results after /notify has answered.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	defaultTTL        = 24 * time.Hour
+	defaultMaxEntries = 1000
+)
+
+var (
+	// ErrNotFound is returned by Get for unknown or expired jobs.
+	ErrNotFound = errors.New("job not found")
+	// ErrFull is returned by Create when a MemoryStore holds MaxEntries
+	// unfinished jobs.
+	ErrFull = errors.New("too many jobs in progress")
+)
+
+// State is the lifecycle stage of a job.
+type State string
+
+const (
+	StateQueued    State = "queued"
+	StateRunning   State = "running"
+	StateSucceeded State = "succeeded"
+	StateFailed    State = "failed"
+)
+
+// Finished reports whether the job will not change any more.
+func (s State) Finished() bool {
+	return s == StateSucceeded || s == StateFailed
+}
+
+// Job is a notification sent in the background.
+type Job struct {
+	ID    string
+	State State
+	// Total is the number of targets to reach; Done counts those attempted so far.
+	Total int
+	Done  int
+	// StatusCode is the HTTP status /notify would have answered with, once finished.
+	StatusCode int
+	// Result is the encoded response with the per-target results, once finished.
+	Result []byte
+	// Error says why a job failed before sending.
+	Error     string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Store keeps jobs until the TTL has passed since their last update.
+type Store interface {
+	Create(ctx context.Context, job Job) error
+	// Get returns the job with the given ID, or ErrNotFound.
+	Get(ctx context.Context, id string) (*Job, error)
+	// Update replaces a job that was created earlier.
+	Update(ctx context.Context, job Job) error
+}
+
+type Config struct {
+	// TTL is how long a job is kept after its last update. Zero keeps it
+	// for a day.
+	TTL time.Duration
+	// MaxEntries bounds the jobs a MemoryStore keeps; the oldest finished
+	// job makes room for a new one. Zero keeps up to 1000.
+	MaxEntries int
+}
@@ -0,0 +1,113 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/dbtest"
+)
+
+// testStores returns a MemoryStore and a SQLStore, both empty and reading
+// the time from now.
+func testStores(t *testing.T, cfg Config, now func() time.Time) map[string]Store {
+	t.Helper()
+
+	memory := NewMemoryStore(cfg)
+	memory.now = now
+
+	sql := NewSQLStore(dbtest.Open(t), cfg)
+	sql.now = now
+	if err := sql.Migrate(context.Background()); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+
+	return map[string]Store{"memory": memory, "sql": sql}
+}
+
+func TestStore_CreateUpdateGet(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1_700_000_000, 0).UTC()
+
+	for name, s := range testStores(t, Config{TTL: time.Hour}, func() time.Time { return now }) {
+		t.Run(name, func(t *testing.T) {
+			now = time.Unix(1_700_000_000, 0).UTC()
+
+			job := Job{ID: "a", State: StateQueued, Total: 3, CreatedAt: now, UpdatedAt: now}
+			if err := s.Create(ctx, job); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			job.State = StateSucceeded
+			job.Done = 3
+			job.StatusCode = 200
+			job.Result = []byte{0x08, 0x01}
+			job.UpdatedAt = now.Add(time.Minute)
+			if err := s.Update(ctx, job); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got, err := s.Get(ctx, "a")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.State != StateSucceeded || got.Total != 3 || got.Done != 3 || got.StatusCode != 200 || string(got.Result) != string(job.Result) {
+				t.Errorf("expected %+v, got %+v", job, got)
+			}
+			if !got.CreatedAt.Equal(now) {
+				t.Errorf("expected the creation time to be kept, got %v", got.CreatedAt)
+			}
+
+			now = now.Add(time.Hour + time.Minute)
+			if _, err := s.Get(ctx, "a"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("expected ErrNotFound for an expired job, got %v", err)
+			}
+		})
+	}
+}
+
+func TestStore_Unknown(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1_700_000_000, 0).UTC()
+
+	for name, s := range testStores(t, Config{}, func() time.Time { return now }) {
+		t.Run(name, func(t *testing.T) {
+
+			if _, err := s.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("expected ErrNotFound from Get, got %v", err)
+			}
+			if err := s.Update(ctx, Job{ID: "missing", UpdatedAt: now}); !errors.Is(err, ErrNotFound) {
+				t.Errorf("expected ErrNotFound from Update, got %v", err)
+			}
+		})
+	}
+}
+
+func TestMemoryStore_MaxEntries(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	s := NewMemoryStore(Config{MaxEntries: 2})
+
+	done := Job{ID: "done", State: StateSucceeded, UpdatedAt: now}
+	running := Job{ID: "running", State: StateRunning, UpdatedAt: now.Add(-time.Minute)}
+	for _, job := range []Job{done, running} {
+		if err := s.Create(ctx, job); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if err := s.Create(ctx, Job{ID: "new", State: StateQueued, UpdatedAt: now}); err != nil {
+		t.Fatalf("expected the finished job to make room, got %v", err)
+	}
+	if _, err := s.Get(ctx, "done"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the finished job to be evicted, got %v", err)
+	}
+	if _, err := s.Get(ctx, "running"); err != nil {
+		t.Errorf("expected the running job to be kept, got %v", err)
+	}
+
+	if err := s.Create(ctx, Job{ID: "more", State: StateQueued, UpdatedAt: now}); !errors.Is(err, ErrFull) {
+		t.Errorf("expected ErrFull with every job in progress, got %v", err)
+	}
+}
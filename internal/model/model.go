@@ -1,39 +1,96 @@
 package model
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
 )
 
 type NotificationRequest struct {
-	Tokens []string `json:"tokens"`
-	TaskID string   `json:"task_id"`
-	Color  string   `json:"color"` // hex color code e.g. "#EF4444"
+	Tokens    []string `json:"tokens"`
+	Topic     string   `json:"topic,omitempty"`
+	Condition string   `json:"condition,omitempty"`
+	TaskID    string   `json:"task_id"`
+	Color     string   `json:"color"` // hex color code e.g. "#EF4444"
+	DryRun    bool     `json:"dry_run,omitempty"`
+	// Recipients are already validated by domain.NewRecipient.
+	Recipients    []domain.Recipient `json:"-"`
+	FallbackEmail string             `json:"fallback_email,omitempty"`
 }
 
+// NotificationParams targets exactly one of Tokens, Topic, Condition or Recipients.
 type NotificationParams struct {
-	Tokens   []domain.FCMToken
-	TaskID   domain.TaskID
-	TaskType domain.Type
-	Color    string
+	Tokens     []domain.FCMToken
+	Recipients []domain.Recipient
+	Topic      domain.Topic
+	Condition  domain.Condition
+	TaskID     domain.TaskID
+	TaskType   domain.Type
+	Color      string
+	Mode       domain.DeliveryMode
+	// DryRun validates the messages with FCM without delivering them.
+	DryRun bool
+	// FallbackEmail receives the reminder when no recipient was reached. Empty disables the fallback.
+	FallbackEmail string
 }
 
-func (r *NotificationRequest) ToDomain(taskType domain.Type) (*NotificationParams, error) {
-	tokens, err := domain.NewFCMTokens(r.Tokens)
-	if err != nil {
-		return nil, err
+func (r *NotificationRequest) ToDomain(taskType domain.Type, mode domain.DeliveryMode) (*NotificationParams, error) {
+	params := &NotificationParams{
+		TaskType: taskType,
+		Color:    r.Color,
+		Mode:     mode,
+		DryRun:   r.DryRun,
+
+		FallbackEmail: r.FallbackEmail,
+	}
+
+	targets := 0
+	if len(r.Tokens) > 0 {
+		targets++
+	}
+	if r.Topic != "" {
+		targets++
+	}
+	if r.Condition != "" {
+		targets++
+	}
+	if len(r.Recipients) > 0 {
+		targets++
+	}
+
+	switch {
+	case targets > 1:
+		return nil, fmt.Errorf("%w: only one of tokens, topic, condition or recipients can be set", domain.ErrInvalidTarget)
+	case len(r.Recipients) > 0:
+		params.Recipients = r.Recipients
+	case r.Topic != "":
+		topic, err := domain.NewTopic(r.Topic)
+		if err != nil {
+			return nil, err
+		}
+		params.Topic = topic
+	case r.Condition != "":
+		condition, err := domain.NewCondition(r.Condition)
+		if err != nil {
+			return nil, err
+		}
+		params.Condition = condition
+	default:
+		tokens, err := domain.NewFCMTokens(r.Tokens)
+		if err != nil {
+			return nil, err
+		}
+		params.Tokens = tokens
 	}
 
 	taskID, err := domain.NewTaskID(r.TaskID)
 	if err != nil {
 		return nil, err
 	}
+	params.TaskID = taskID
 
-	return &NotificationParams{
-		Tokens:   tokens,
-		TaskID:   taskID,
-		TaskType: taskType,
-		Color:    r.Color,
-	}, nil
+	return params, nil
 }
 
 type NotificationResponse struct {
@@ -45,10 +102,49 @@ type NotificationResponse struct {
 }
 
 type TokenResult struct {
-	Token     string `json:"token"`
-	Success   bool   `json:"success"`
-	MessageID string `json:"message_id,omitempty"`
-	Error     string `json:"error,omitempty"`
+	Token             string           `json:"token"`
+	Success           bool             `json:"success"`
+	MessageID         string           `json:"message_id,omitempty"`
+	Error             string           `json:"error,omitempty"`
+	ErrorCode         domain.ErrorCode `json:"error_code,omitempty"`
+	ShouldRemoveToken bool             `json:"should_remove_token"`
+	Attempts          int              `json:"attempts"`
+	Channel           domain.Channel   `json:"channel,omitempty"`
+	// NotSent is set when the recipient was turned away before sending, by
+	// the rate limiter or the circuit breaker, so that no attempt reached it.
+	NotSent bool `json:"-"`
+}
+
+// BulkResult is the outcome of sending a reminder to a set of recipients.
+// Results hold one entry per recipient, in request order.
+type BulkResult struct {
+	Total        int
+	SuccessCount int
+	FailureCount int
+	Status       domain.DeliveryStatus
+	Results      []TokenResult
+	// RetryAfter is how long to wait before sending again is worth it, when
+	// known. It is set when every recipient of a batch was turned away by a
+	// rate limit or quota.
+	RetryAfter time.Duration
+}
+
+// Retryable reports whether sending again may succeed and is needed: no
+// recipient was reached and one failed retryably. When some recipients were
+// reached, a retry would notify them twice, so recipients that were never
+// sent to are only marked NotSent.
+func (r *BulkResult) Retryable() bool {
+	if r.SuccessCount > 0 {
+		return false
+	}
+
+	for _, result := range r.Results {
+		if result.ErrorCode.IsRetryable() {
+			return true
+		}
+	}
+
+	return false
 }
 
 type ErrorResponse struct {
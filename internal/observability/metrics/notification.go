@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"context"
+	"strconv"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	notificationMeterName = "notification"
+)
+
+type NotificationMetrics struct {
+	requestCounter metric.Int64Counter
+	tokenCounter   metric.Int64Counter
+}
+
+func NewNotificationMetrics() (*NotificationMetrics, error) {
+	meter := otel.Meter(notificationMeterName)
+
+	requestCounter, err := meter.Int64Counter(
+		"notification_requests_total",
+		metric.WithDescription("Total number of notification requests by delivery status"),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tokenCounter, err := meter.Int64Counter(
+		"notification_tokens_total",
+		metric.WithDescription("Total number of notification recipients by outcome"),
+		metric.WithUnit("{token}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &NotificationMetrics{
+		requestCounter: requestCounter,
+		tokenCounter:   tokenCounter,
+	}, nil
+}
+
+// Record counts a finished notification request. Dry runs are recorded with
+// dry_run=true so they never mix with real deliveries.
+func (m *NotificationMetrics) Record(ctx context.Context, status string, dryRun bool, successCount, failureCount int) {
+	dryRunAttr := attribute.String("dry_run", strconv.FormatBool(dryRun))
+
+	m.requestCounter.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("status", status),
+		dryRunAttr,
+	))
+	m.tokenCounter.Add(ctx, int64(successCount), metric.WithAttributes(
+		attribute.String("outcome", "success"),
+		dryRunAttr,
+	))
+	m.tokenCounter.Add(ctx, int64(failureCount), metric.WithAttributes(
+		attribute.String("outcome", "failure"),
+		dryRunAttr,
+	))
+}
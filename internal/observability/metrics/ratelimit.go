@@ -0,0 +1,92 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	rateLimitMeterName = "fcm.ratelimit"
+)
+
+type RateLimitMetrics struct {
+	meter           metric.Meter
+	requestCounter  metric.Int64Counter
+	messageCounter  metric.Int64Counter
+	waitDuration    metric.Float64Histogram
+	tokensAvailable metric.Float64ObservableGauge
+}
+
+func NewRateLimitMetrics() (*RateLimitMetrics, error) {
+	meter := otel.Meter(rateLimitMeterName)
+
+	requestCounter, err := meter.Int64Counter(
+		"fcm_rate_limit_requests_total",
+		metric.WithDescription("Total number of FCM rate limiter acquisitions by outcome"),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	messageCounter, err := meter.Int64Counter(
+		"fcm_rate_limit_messages_total",
+		metric.WithDescription("Total number of FCM messages passed to the rate limiter by outcome"),
+		metric.WithUnit("{message}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	waitDuration, err := meter.Float64Histogram(
+		"fcm_rate_limit_wait_seconds",
+		metric.WithDescription("Time FCM sends waited for rate limit budget in seconds"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(
+			0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10,
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tokensAvailable, err := meter.Float64ObservableGauge(
+		"fcm_rate_limit_tokens_available",
+		metric.WithDescription("FCM messages that can be sent without waiting"),
+		metric.WithUnit("{message}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RateLimitMetrics{
+		meter:           meter,
+		requestCounter:  requestCounter,
+		messageCounter:  messageCounter,
+		waitDuration:    waitDuration,
+		tokensAvailable: tokensAvailable,
+	}, nil
+}
+
+// ObserveRateLimit records a limiter acquisition. It implements fcm.RateLimitObserver.
+func (m *RateLimitMetrics) ObserveRateLimit(ctx context.Context, outcome string, messages int, wait time.Duration) {
+	attrs := metric.WithAttributes(attribute.String("outcome", outcome))
+
+	m.requestCounter.Add(ctx, 1, attrs)
+	m.messageCounter.Add(ctx, int64(messages), attrs)
+	m.waitDuration.Record(ctx, wait.Seconds(), attrs)
+}
+
+// ObserveTokens reports the bucket level returned by tokens on every collection.
+func (m *RateLimitMetrics) ObserveTokens(tokens func() float64) error {
+	_, err := m.meter.RegisterCallback(func(_ context.Context, o metric.Observer) error {
+		o.ObserveFloat64(m.tokensAvailable, tokens())
+		return nil
+	}, m.tokensAvailable)
+
+	return err
+}
@@ -0,0 +1,61 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+const (
+	rpcMeterName = "rpc.server"
+)
+
+type RPCMetrics struct {
+	requestCounter  metric.Int64Counter
+	requestDuration metric.Float64Histogram
+}
+
+func NewRPCMetrics() (*RPCMetrics, error) {
+	meter := otel.Meter(rpcMeterName)
+
+	requestCounter, err := meter.Int64Counter(
+		"rpc_requests_total",
+		metric.WithDescription("Total number of Connect, gRPC and gRPC-Web requests"),
+		metric.WithUnit("{request}"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	requestDuration, err := meter.Float64Histogram(
+		"rpc_request_duration_seconds",
+		metric.WithDescription("Connect, gRPC and gRPC-Web request duration in seconds"),
+		metric.WithUnit("s"),
+		metric.WithExplicitBucketBoundaries(
+			0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10,
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RPCMetrics{
+		requestCounter:  requestCounter,
+		requestDuration: requestDuration,
+	}, nil
+}
+
+// Record records a finished call. code is the Connect code, "ok" on success.
+func (m *RPCMetrics) Record(ctx context.Context, procedure, protocol, code string, duration time.Duration) {
+	attrs := []attribute.KeyValue{
+		attribute.String("procedure", procedure),
+		attribute.String("protocol", protocol),
+		attribute.String("code", code),
+	}
+
+	m.requestCounter.Add(ctx, 1, metric.WithAttributes(attrs...))
+	m.requestDuration.Record(ctx, duration.Seconds(), metric.WithAttributes(attrs...))
+}
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.38.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/observability/logging"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/observability/metrics"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/observability/tracing"
+)
+
+// The Connect interceptors observe Connect, gRPC and gRPC-Web calls, which
+// HTTP skips. They only apply to the handler side of unary calls; order them
+// tracing, logging, metrics so that logs and metrics carry the span.
+
+// ConnectTracing starts a server span for each call, continuing the trace of the caller.
+func ConnectTracing(tracerName string) connect.UnaryInterceptorFunc {
+	tracer := otel.Tracer(tracerName)
+
+	return func(next connect.UnaryFunc) connect.UnaryFunc {
+		return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			if req.Spec().IsClient {
+				return next(ctx, req)
+			}
+
+			service, method := splitProcedure(req.Spec().Procedure)
+			ctx = tracing.ExtractFromHeader(ctx, req.Header())
+			ctx, span := tracer.Start(ctx, service+"/"+method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.RPCSystemConnectRPC,
+					semconv.RPCService(service),
+					semconv.RPCMethod(method),
+				),
+			)
+			defer span.End()
+
+			resp, err := next(ctx, req)
+			if err != nil {
+				span.SetAttributes(semconv.RPCConnectRPCErrorCodeKey.String(connect.CodeOf(err).String()))
+				span.SetStatus(codes.Error, err.Error())
+			}
+
+			return resp, err
+		}
+	}
+}
+
+// ConnectLogging attaches the request ID and module to the context and logs
+// each call as a job named after its procedure.
+func ConnectLogging(module logging.Module) connect.UnaryInterceptorFunc {
+	return func(next connect.UnaryFunc) connect.UnaryFunc {
+		return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			if req.Spec().IsClient {
+				return next(ctx, req)
+			}
+
+			start := time.Now()
+
+			requestID := logging.ValidateAndExtractRequestID(req.Header().Get("x-request-id"))
+			ctx = logging.WithRequestID(ctx, requestID)
+			if module != "" {
+				ctx = logging.WithModule(ctx, module)
+			}
+
+			procedure := req.Spec().Procedure
+			slog.LogAttrs(ctx, slog.LevelInfo, "job started",
+				slog.String("event", "job.start"),
+				slog.String("procedure", procedure),
+				slog.String("protocol", req.Peer().Protocol),
+				slog.String("remote_addr", req.Peer().Addr),
+				slog.String("job.name", procedure),
+				slog.String("job.id", requestID),
+			)
+
+			resp, err := next(ctx, req)
+			if err != nil {
+				var cerr *connect.Error
+				if errors.As(err, &cerr) {
+					cerr.Meta().Set("x-request-id", requestID)
+				}
+			} else if resp != nil {
+				resp.Header().Set("x-request-id", requestID)
+			}
+
+			slog.LogAttrs(ctx, slog.LevelInfo, "job finished",
+				slog.String("event", "job.finish"),
+				slog.String("procedure", procedure),
+				slog.String("protocol", req.Peer().Protocol),
+				slog.String("remote_addr", req.Peer().Addr),
+				slog.String("code", callCode(err)),
+				slog.Duration("duration", time.Since(start)),
+				slog.String("job.name", procedure),
+				slog.String("job.id", requestID),
+			)
+
+			return resp, err
+		}
+	}
+}
+
+// ConnectMetrics records the count and duration of each call.
+func ConnectMetrics(m *metrics.RPCMetrics) connect.UnaryInterceptorFunc {
+	return func(next connect.UnaryFunc) connect.UnaryFunc {
+		return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			if req.Spec().IsClient || m == nil {
+				return next(ctx, req)
+			}
+
+			start := time.Now()
+			resp, err := next(ctx, req)
+			m.Record(ctx, req.Spec().Procedure, req.Peer().Protocol, callCode(err), time.Since(start))
+
+			return resp, err
+		}
+	}
+}
+
+// splitProcedure splits "/notify.v1.NotificationService/SendNotification"
+// into its service and method.
+func splitProcedure(procedure string) (service, method string) {
+	service, method, _ = strings.Cut(strings.TrimPrefix(procedure, "/"), "/")
+	return service, method
+}
+
+// callCode returns the Connect code of a call's error, "ok" on success.
+func callCode(err error) string {
+	if err == nil {
+		return "ok"
+	}
+
+	return connect.CodeOf(err).String()
+}
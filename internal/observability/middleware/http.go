@@ -22,7 +22,9 @@ type HTTPConfig struct {
 	Worker         bool
 	// JobNameResolver returns a job name for worker-style logging
 	JobNameResolver func(*http.Request) string
-	TracerName      string
+	// JobIDResolver returns an ID for the job, such as an event ID; the request ID is used when it returns empty
+	JobIDResolver func(*http.Request) string
+	TracerName    string
 	// SpanNameResolver returns a span name for the request
 	SpanNameResolver func(*http.Request) string
 	// HTTPMetrics records HTTP request metrics
@@ -109,6 +111,7 @@ func HTTP(next http.Handler, cfg HTTPConfig) http.Handler {
 		finishEvent := "http.request.finish"
 		finishMessage := "request completed"
 		jobName := ""
+		jobID := requestID
 		if cfg.Worker {
 			finishEvent = "job.finish"
 			finishMessage = "job finished"
@@ -118,6 +121,11 @@ func HTTP(next http.Handler, cfg HTTPConfig) http.Handler {
 			if jobName == "" {
 				jobName = r.URL.Path
 			}
+			if cfg.JobIDResolver != nil {
+				if id := cfg.JobIDResolver(r); id != "" {
+					jobID = id
+				}
+			}
 
 			startAttrs := []slog.Attr{
 				slog.String("event", "job.start"),
@@ -125,7 +133,7 @@ func HTTP(next http.Handler, cfg HTTPConfig) http.Handler {
 				slog.String("path", r.URL.Path),
 				slog.String("remote_addr", r.RemoteAddr),
 				slog.String("job.name", jobName),
-				slog.String("job.id", requestID),
+				slog.String("job.id", jobID),
 			}
 			slog.LogAttrs(ctx, slog.LevelInfo, "job started", startAttrs...)
 		}
@@ -155,7 +163,7 @@ func HTTP(next http.Handler, cfg HTTPConfig) http.Handler {
 		if cfg.Worker {
 			finishAttrs = append(finishAttrs,
 				slog.String("job.name", jobName),
-				slog.String("job.id", requestID),
+				slog.String("job.id", jobID),
 			)
 		}
 		slog.LogAttrs(ctx, slog.LevelInfo, finishMessage, finishAttrs...)
@@ -11,3 +11,7 @@ import (
 func ExtractFromHTTPRequest(ctx context.Context, r *http.Request) context.Context {
 	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(r.Header))
 }
+
+func ExtractFromHeader(ctx context.Context, header http.Header) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(header))
+}
@@ -0,0 +1,24 @@
+// Package progress lets long-running sends report how many targets they have
+// finished, through the context, to whoever is tracking them.
+package progress
+
+import "context"
+
+type contextKey struct{}
+
+// Func receives the number of targets finished since the last report. It may
+// be called from several goroutines at once.
+type Func func(n int)
+
+// WithReporter returns a context whose sends report to fn. A nil fn stops
+// reports, for senders that report on behalf of the senders they call.
+func WithReporter(ctx context.Context, fn Func) context.Context {
+	return context.WithValue(ctx, contextKey{}, fn)
+}
+
+// Report adds n finished targets to the reporter of ctx, if any.
+func Report(ctx context.Context, n int) {
+	if fn, ok := ctx.Value(contextKey{}).(Func); ok && fn != nil {
+		fn(n)
+	}
+}
@@ -0,0 +1,20 @@
+package progress
+
+import (
+	"context"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	total := 0
+	ctx := WithReporter(context.Background(), func(n int) { total += n })
+
+	Report(ctx, 2)
+	Report(ctx, 3)
+	Report(context.Background(), 5)
+	Report(WithReporter(ctx, nil), 7)
+
+	if total != 5 {
+		t.Errorf("expected 5 reported targets, got %d", total)
+	}
+}
@@ -0,0 +1,88 @@
+// Package retry provides the backoff policy shared by every delivery channel.
+package retry
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Policy bounds how often a delivery that failed with a retryable error is re-sent.
+type Policy struct {
+	// MaxAttempts is the total number of sends per recipient, including the first. 1 disables retries.
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+	}
+}
+
+// Backoff returns the wait before the given retry (1 = first retry) using full jitter.
+// A server-provided Retry-After hint takes precedence when it is longer, up
+// to MaxBackoff, so that a large hint cannot hold the request past its deadline.
+func (p Policy) Backoff(retry int, hint time.Duration) time.Duration {
+	ceiling := p.InitialBackoff
+	for i := 1; i < retry && ceiling < p.MaxBackoff; i++ {
+		ceiling *= 2
+	}
+	if ceiling > p.MaxBackoff {
+		ceiling = p.MaxBackoff
+	}
+
+	wait := time.Duration(0)
+	if ceiling > 0 {
+		wait = time.Duration(rand.Int64N(int64(ceiling) + 1))
+	}
+	if hint > p.MaxBackoff && p.MaxBackoff > 0 {
+		hint = p.MaxBackoff
+	}
+	if hint > wait {
+		wait = hint
+	}
+
+	return wait
+}
+
+// SleepWithinDeadline waits for d unless that would outlast the context deadline.
+// It reports false when the wait was skipped or interrupted.
+func SleepWithinDeadline(ctx context.Context, d time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return false
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// ParseRetryAfter parses a Retry-After header value in either delay-seconds
+// or HTTP-date form. It returns 0 for empty, invalid or past values.
+func ParseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+
+	return 0
+}
@@ -0,0 +1,60 @@
+package retry
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{value: "", want: 0},
+		{value: "3", want: 3 * time.Second},
+		{value: "-1", want: 0},
+		{value: now.Add(10 * time.Second).Format(http.TimeFormat), want: 10 * time.Second},
+		{value: "garbage", want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := ParseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("ParseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	p := Policy{MaxAttempts: 5, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+
+	for retry, ceiling := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 4: 300 * time.Millisecond} {
+		for range 20 {
+			if got := p.Backoff(retry, 0); got < 0 || got > ceiling {
+				t.Fatalf("Backoff(%d) = %v, want within [0, %v]", retry, got, ceiling)
+			}
+		}
+	}
+
+	if got := p.Backoff(1, 250*time.Millisecond); got != 250*time.Millisecond {
+		t.Errorf("expected a longer Retry-After hint to win, got %v", got)
+	}
+	if got := p.Backoff(1, time.Minute); got != p.MaxBackoff {
+		t.Errorf("expected the Retry-After hint to be capped at %v, got %v", p.MaxBackoff, got)
+	}
+}
+
+func TestSleepWithinDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if SleepWithinDeadline(ctx, time.Second) {
+		t.Error("expected a wait past the deadline to be skipped")
+	}
+	if !SleepWithinDeadline(context.Background(), time.Millisecond) {
+		t.Error("expected a short wait to complete")
+	}
+}
@@ -0,0 +1,129 @@
+package scheduler
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a Store local to one instance. Other instances do not see
+// its entries, and pending ones are lost when the instance exits, which
+// suits tests and throwaway runs only.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]Entry
+	// claimed holds the end of the lease of each entry being sent.
+	claimed map[string]time.Time
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]Entry),
+		claimed: make(map[string]time.Time),
+	}
+}
+
+// Add implements Store.
+func (m *MemoryStore) Add(_ context.Context, entry Entry) (Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if entry.Key != "" {
+		for _, e := range m.entries {
+			if e.Key == entry.Key {
+				return e, nil
+			}
+		}
+	}
+	m.entries[entry.ID] = entry
+
+	return entry, nil
+}
+
+// Due implements Store.
+func (m *MemoryStore) Due(_ context.Context, before time.Time) ([]Entry, error) {
+	return m.sorted(func(e Entry) bool { return e.SendAt.Before(before) }), nil
+}
+
+// List implements Store.
+func (m *MemoryStore) List(context.Context) ([]Entry, error) {
+	return m.sorted(func(Entry) bool { return true }), nil
+}
+
+func (m *MemoryStore) sorted(keep func(Entry) bool) []Entry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entries []Entry
+	for _, e := range m.entries {
+		if keep(e) {
+			entries = append(entries, e)
+		}
+	}
+	slices.SortFunc(entries, func(a, b Entry) int {
+		return cmp.Or(a.SendAt.Compare(b.SendAt), cmp.Compare(a.ID, b.ID))
+	})
+
+	return entries
+}
+
+// Claim implements Store.
+func (m *MemoryStore) Claim(_ context.Context, id string, now, until time.Time) (Entry, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[id]
+	if !ok || e.SendAt.After(now) || !m.claimed[id].Before(now) {
+		return Entry{}, false, nil
+	}
+	m.claimed[id] = until
+
+	return e, true, nil
+}
+
+// Retry implements Store.
+func (m *MemoryStore) Retry(_ context.Context, id string, sendAt time.Time, attempts int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e, ok := m.entries[id]; ok {
+		e.SendAt = sendAt
+		e.Attempts = attempts
+		m.entries[id] = e
+		delete(m.claimed, id)
+	}
+
+	return nil
+}
+
+// Remove implements Store.
+func (m *MemoryStore) Remove(_ context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.entries, id)
+	delete(m.claimed, id)
+
+	return nil
+}
+
+// RemoveTask implements Store.
+func (m *MemoryStore) RemoveTask(_ context.Context, taskID string) ([]Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var removed []Entry
+	for id, e := range m.entries {
+		if e.TaskID == taskID {
+			delete(m.entries, id)
+			delete(m.claimed, id)
+			removed = append(removed, e)
+		}
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,366 @@
+// Package scheduler holds notifications until their send time. Entries are
+// saved to a store before Schedule returns. With a store shared by every
+// instance, such as a SQLStore, each is sent by the instance that claims it
+// once due, so that restarts, scale-ins and crashes lose none. The
+// MemoryStore used by default loses its entries with the instance. The
+// scheduler is meant for sends due shortly; further ones belong in Cloud
+// Tasks, so it refuses them past its horizon.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/retry"
+)
+
+const (
+	defaultPollInterval = 30 * time.Second
+	defaultLease        = 5 * time.Minute
+)
+
+var (
+	// ErrBeyondHorizon is returned by Schedule for entries due after the horizon.
+	ErrBeyondHorizon = errors.New("send_at is beyond the scheduling horizon")
+	// ErrClosed is returned by Schedule before Start and after Shutdown.
+	ErrClosed = errors.New("scheduler is not running")
+)
+
+// Entry is a notification held until SendAt.
+type Entry struct {
+	ID     string
+	TaskID string
+	// Key deduplicates entries: scheduling an entry whose key is already
+	// saved returns the saved entry. Empty keys are not deduplicated.
+	Key       string
+	DryRun    bool
+	SendAt    time.Time
+	CreatedAt time.Time
+	// Attempts counts the earlier sends of the entry that failed retryably.
+	Attempts int
+	// Request is the encoded request to send.
+	Request []byte
+}
+
+// Func sends an entry once it is due. An error has the entry sent again
+// later, until it runs out of attempts.
+type Func func(ctx context.Context, entry Entry) error
+
+// Store keeps the pending entries of every instance.
+type Store interface {
+	// Add saves entry. When an entry with the same non-empty key is already
+	// saved, Add returns that entry instead.
+	Add(ctx context.Context, entry Entry) (Entry, error)
+	// Due returns the entries due before t by send time, claimed or not.
+	Due(ctx context.Context, before time.Time) ([]Entry, error)
+	// List returns every saved entry by send time.
+	List(ctx context.Context) ([]Entry, error)
+	// Claim leases an entry due at now for sending until until and returns
+	// it as saved. It reports false when the entry is gone, is not due at
+	// now, or another lease is still running at now.
+	Claim(ctx context.Context, id string, now, until time.Time) (Entry, bool, error)
+	// Retry moves an entry to sendAt after attempts failed sends and ends
+	// its lease. It does nothing when the entry is gone.
+	Retry(ctx context.Context, id string, sendAt time.Time, attempts int) error
+	// Remove deletes an entry.
+	Remove(ctx context.Context, id string) error
+	// RemoveTask deletes the entries of a task and returns them.
+	RemoveTask(ctx context.Context, taskID string) ([]Entry, error)
+}
+
+type Config struct {
+	// Horizon is how far ahead entries may be scheduled.
+	Horizon time.Duration
+	// Store keeps the entries. Nil uses a MemoryStore.
+	Store Store
+	// PollInterval is how often the store is checked for entries due soon,
+	// such as those of other instances. Zero uses defaultPollInterval.
+	PollInterval time.Duration
+	// Lease is how long a claimed entry is left to its instance before
+	// another may send it. Zero uses defaultLease.
+	Lease time.Duration
+	// MaxAttempts is the number of sends of an entry that keeps failing
+	// before it is given up. Zero uses retryPolicy.MaxAttempts.
+	MaxAttempts int
+}
+
+// retryPolicy spaces the sends of an entry. Retries wait for a poll, so
+// backoffs shorter than PollInterval are rounded up to it in practice.
+var retryPolicy = retry.Policy{
+	MaxAttempts:    5,
+	InitialBackoff: 30 * time.Second,
+	MaxBackoff:     5 * time.Minute,
+}
+
+// Scheduler sends entries when they are due.
+type Scheduler struct {
+	cfg Config
+	now func() time.Time
+
+	// ctx is passed to sends; it is canceled when Shutdown gives up on them.
+	ctx    context.Context
+	cancel context.CancelFunc
+	stop   chan struct{}
+	wg     sync.WaitGroup
+
+	mu  sync.Mutex
+	run Func
+	// timers holds the entries armed on this instance, until their send
+	// is over.
+	timers map[string]*time.Timer
+	closed bool
+}
+
+func New(cfg Config) *Scheduler {
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryStore()
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultPollInterval
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = defaultLease
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = retryPolicy.MaxAttempts
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Scheduler{
+		cfg:    cfg,
+		now:    time.Now,
+		ctx:    ctx,
+		cancel: cancel,
+		stop:   make(chan struct{}),
+		timers: make(map[string]*time.Timer),
+	}
+}
+
+// Horizon returns how far ahead entries may be scheduled.
+func (s *Scheduler) Horizon() time.Duration {
+	return s.cfg.Horizon
+}
+
+// MaxAttempts returns the number of sends of an entry before it is given up.
+func (s *Scheduler) MaxAttempts() int {
+	return s.cfg.MaxAttempts
+}
+
+// Start sends entries with run from now on, including those saved before
+// and those scheduled by other instances. Overdue entries are sent at once.
+func (s *Scheduler) Start(ctx context.Context, run Func) error {
+	s.mu.Lock()
+	s.run = run
+	s.mu.Unlock()
+
+	if err := s.poll(ctx); err != nil {
+		return fmt.Errorf("failed to load scheduled notifications: %w", err)
+	}
+
+	s.wg.Go(func() {
+		ticker := time.NewTicker(s.cfg.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				if err := s.poll(s.ctx); err != nil {
+					slog.Error("failed to load scheduled notifications", "error", err)
+				}
+			}
+		}
+	})
+
+	return nil
+}
+
+// Schedule saves entry and holds it until its SendAt. When an entry with
+// the same key is already saved, it is returned instead and entry is dropped.
+func (s *Scheduler) Schedule(ctx context.Context, entry Entry) (Entry, error) {
+	s.mu.Lock()
+	closed := s.run == nil || s.closed
+	s.mu.Unlock()
+
+	if closed {
+		return Entry{}, ErrClosed
+	}
+	if ahead := entry.SendAt.Sub(s.now()); ahead > s.cfg.Horizon {
+		return Entry{}, fmt.Errorf("%w: due in %s, horizon is %s", ErrBeyondHorizon, ahead.Round(time.Second), s.cfg.Horizon)
+	}
+
+	saved, err := s.cfg.Store.Add(ctx, entry)
+	if err != nil {
+		return Entry{}, fmt.Errorf("failed to save scheduled notification: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.arm(saved)
+	return saved, nil
+}
+
+// poll arms the entries due before the next poll.
+func (s *Scheduler) poll(ctx context.Context) error {
+	entries, err := s.cfg.Store.Due(ctx, s.now().Add(s.cfg.PollInterval))
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, entry := range entries {
+		s.arm(entry)
+	}
+
+	return nil
+}
+
+// arm sets a timer for entry unless it has one. s.mu must be held.
+func (s *Scheduler) arm(entry Entry) {
+	if s.closed {
+		return
+	}
+	if _, ok := s.timers[entry.ID]; ok {
+		return
+	}
+
+	s.timers[entry.ID] = time.AfterFunc(entry.SendAt.Sub(s.now()), func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		if s.closed {
+			return
+		}
+		s.wg.Go(func() { s.send(entry) })
+	})
+}
+
+// send sends entry if this instance claims it, and then removes it or has
+// it retried. The entry is sent as claimed, since it may have been retried
+// since it was armed.
+func (s *Scheduler) send(armed Entry) {
+	// Until the send is over, the entry is not armed again by a poll.
+	defer func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		delete(s.timers, armed.ID)
+	}()
+
+	now := s.now()
+	entry, claimed, err := s.cfg.Store.Claim(s.ctx, armed.ID, now, now.Add(s.cfg.Lease))
+	if err != nil {
+		slog.Error("failed to claim scheduled notification", "scheduled_id", armed.ID, "error", err)
+		return
+	}
+	// Canceled, sent by another instance, or retried later; a poll arms
+	// the entry again in time for a retry.
+	if !claimed {
+		return
+	}
+
+	err = s.run(s.ctx, entry)
+	switch {
+	case err == nil:
+	case s.ctx.Err() != nil:
+		// Shutdown gave up on the send; the lease runs out and another
+		// instance sends the entry again.
+		slog.Warn("scheduled notification interrupted", "scheduled_id", entry.ID, "task_id", entry.TaskID, "error", err)
+		return
+	case entry.Attempts+1 < s.cfg.MaxAttempts:
+		attempts := entry.Attempts + 1
+		sendAt := s.now().Add(retryPolicy.Backoff(attempts, 0))
+		if err := s.cfg.Store.Retry(s.ctx, entry.ID, sendAt, attempts); err != nil {
+			slog.Error("failed to reschedule notification", "scheduled_id", entry.ID, "task_id", entry.TaskID, "error", err)
+			return
+		}
+
+		slog.Warn("scheduled notification will be retried",
+			"scheduled_id", entry.ID,
+			"task_id", entry.TaskID,
+			"attempt", attempts,
+			"send_at", sendAt,
+			"error", err,
+		)
+		return
+	default:
+		slog.Error("giving up on scheduled notification",
+			"scheduled_id", entry.ID,
+			"task_id", entry.TaskID,
+			"attempts", entry.Attempts+1,
+			"error", err,
+		)
+	}
+
+	if err := s.cfg.Store.Remove(s.ctx, entry.ID); err != nil {
+		slog.Error("failed to remove sent scheduled notification", "scheduled_id", entry.ID, "task_id", entry.TaskID, "error", err)
+	}
+}
+
+// List returns the saved entries by send time.
+func (s *Scheduler) List(ctx context.Context) ([]Entry, error) {
+	return s.cfg.Store.List(ctx)
+}
+
+// Cancel drops the entries of a task and returns them. Entries that are
+// already being sent are not retried.
+func (s *Scheduler) Cancel(ctx context.Context, taskID string) ([]Entry, error) {
+	canceled, err := s.cfg.Store.RemoveTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, entry := range canceled {
+		if timer, ok := s.timers[entry.ID]; ok && timer.Stop() {
+			delete(s.timers, entry.ID)
+		}
+	}
+
+	return canceled, nil
+}
+
+// Shutdown stops scheduling and waits for the sends in progress. Pending
+// entries stay in the store for the other instances, or the next start, and
+// are lost with a MemoryStore. When ctx is done first, the
+// sends are canceled, to be sent again once their lease runs out, and
+// Shutdown returns the context's error.
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	for _, timer := range s.timers {
+		timer.Stop()
+	}
+	s.mu.Unlock()
+
+	close(s.stop)
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		return ctx.Err()
+	}
+}
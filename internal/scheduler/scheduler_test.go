@@ -0,0 +1,315 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder is a Func that records the entries it sends, failing with err.
+type recorder struct {
+	mu   sync.Mutex
+	sent []Entry
+	err  error
+	done chan string
+}
+
+func newRecorder() *recorder {
+	return &recorder{done: make(chan string, 10)}
+}
+
+func (r *recorder) run(_ context.Context, entry Entry) error {
+	r.mu.Lock()
+	r.sent = append(r.sent, entry)
+	err := r.err
+	r.mu.Unlock()
+	r.done <- entry.ID
+	return err
+}
+
+func (r *recorder) ids() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := make([]string, len(r.sent))
+	for i, e := range r.sent {
+		ids[i] = e.ID
+	}
+	return ids
+}
+
+// wait returns the ID of the next sent entry.
+func (r *recorder) wait(t *testing.T) string {
+	t.Helper()
+
+	select {
+	case id := <-r.done:
+		return id
+	case <-time.After(time.Second):
+		t.Fatal("expected an entry to be sent")
+		return ""
+	}
+}
+
+func (m *MemoryStore) get(id string) (Entry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[id]
+	return e, ok
+}
+
+func newStarted(t *testing.T, cfg Config, r *recorder) *Scheduler {
+	t.Helper()
+
+	if cfg.Horizon == 0 {
+		cfg.Horizon = time.Hour
+	}
+	if cfg.PollInterval == 0 {
+		cfg.PollInterval = time.Hour
+	}
+	s := New(cfg)
+	if err := s.Start(context.Background(), r.run); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Shutdown(context.Background()) })
+	return s
+}
+
+func TestScheduler_SendsWhenDue(t *testing.T) {
+	store := NewMemoryStore()
+	r := newRecorder()
+	s := newStarted(t, Config{Store: store}, r)
+
+	if _, err := s.Schedule(context.Background(), Entry{ID: "a", SendAt: time.Now().Add(10 * time.Millisecond)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pending, _ := s.List(context.Background()); len(pending) != 1 {
+		t.Fatalf("expected one pending entry, got %d", len(pending))
+	}
+
+	if id := r.wait(t); id != "a" {
+		t.Errorf("expected entry a to be sent, got %s", id)
+	}
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := store.get("a"); ok {
+		t.Error("expected the sent entry to be removed")
+	}
+}
+
+func TestScheduler_Schedule(t *testing.T) {
+	s := newStarted(t, Config{Store: NewMemoryStore()}, newRecorder())
+	ctx := context.Background()
+
+	later := time.Now().Add(time.Minute)
+	if _, err := s.Schedule(ctx, Entry{ID: "a", TaskID: "t", Key: "k", SendAt: later}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := s.Schedule(ctx, Entry{ID: "b", TaskID: "t", Key: "k", SendAt: later})
+	if err != nil || got.ID != "a" {
+		t.Errorf("expected the saved entry for a duplicate key, got %v, %v", got.ID, err)
+	}
+
+	if _, err := s.Schedule(ctx, Entry{ID: "c", SendAt: time.Now().Add(2 * time.Hour)}); !errors.Is(err, ErrBeyondHorizon) {
+		t.Errorf("expected ErrBeyondHorizon, got %v", err)
+	}
+
+	if pending, _ := s.List(ctx); len(pending) != 1 {
+		t.Errorf("expected one pending entry, got %d", len(pending))
+	}
+}
+
+func TestScheduler_NotStarted(t *testing.T) {
+	s := New(Config{Horizon: time.Hour, Store: NewMemoryStore()})
+	if _, err := s.Schedule(context.Background(), Entry{ID: "a", SendAt: time.Now()}); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestScheduler_Cancel(t *testing.T) {
+	r := newRecorder()
+	s := newStarted(t, Config{Store: NewMemoryStore()}, r)
+	ctx := context.Background()
+
+	soon := time.Now().Add(20 * time.Millisecond)
+	for _, e := range []Entry{{ID: "a", TaskID: "t1", SendAt: soon}, {ID: "b", TaskID: "t1", SendAt: soon}, {ID: "c", TaskID: "t2", SendAt: soon}} {
+		if _, err := s.Schedule(ctx, e); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if canceled, err := s.Cancel(ctx, "t1"); err != nil || len(canceled) != 2 {
+		t.Fatalf("expected two canceled entries, got %d, %v", len(canceled), err)
+	}
+	if canceled, _ := s.Cancel(ctx, "t1"); len(canceled) != 0 {
+		t.Errorf("expected nothing left to cancel, got %d", len(canceled))
+	}
+
+	r.wait(t)
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ids := r.ids(); len(ids) != 1 || ids[0] != "c" {
+		t.Errorf("expected only entry c to be sent, got %v", ids)
+	}
+}
+
+func TestScheduler_ShutdownKeepsPending(t *testing.T) {
+	store := NewMemoryStore()
+	r := newRecorder()
+	s := newStarted(t, Config{Store: store}, r)
+
+	if _, err := s.Schedule(context.Background(), Entry{ID: "a", SendAt: time.Now().Add(time.Minute)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ids := r.ids(); len(ids) != 0 {
+		t.Errorf("expected nothing to be sent on shutdown, got %v", ids)
+	}
+	if _, err := s.Schedule(context.Background(), Entry{ID: "b", SendAt: time.Now()}); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected ErrClosed after shutdown, got %v", err)
+	}
+
+	// An overdue entry is sent as soon as the next instance starts.
+	if err := store.Retry(context.Background(), "a", time.Now().Add(-time.Second), 0); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	newStarted(t, Config{Store: store}, r)
+	if id := r.wait(t); id != "a" {
+		t.Errorf("expected entry a to be sent, got %s", id)
+	}
+}
+
+func TestScheduler_ShutdownLeavesPending(t *testing.T) {
+	r := newRecorder()
+	s := newStarted(t, Config{}, r)
+	ctx := context.Background()
+
+	if _, err := s.Schedule(ctx, Entry{ID: "a", SendAt: time.Now().Add(time.Minute)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Nothing is sent ahead of its time.
+	if ids := r.ids(); len(ids) != 0 {
+		t.Errorf("expected nothing to be sent on shutdown, got %v", ids)
+	}
+	if pending, _ := s.List(ctx); len(pending) != 1 {
+		t.Errorf("expected entry a to be left pending, got %+v", pending)
+	}
+}
+
+func TestScheduler_SentOnceAcrossInstances(t *testing.T) {
+	store := NewMemoryStore()
+	r := newRecorder()
+	first := newStarted(t, Config{Store: store, PollInterval: 5 * time.Millisecond}, r)
+	newStarted(t, Config{Store: store, PollInterval: 5 * time.Millisecond}, r)
+
+	if _, err := first.Schedule(context.Background(), Entry{ID: "a", SendAt: time.Now().Add(20 * time.Millisecond)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r.wait(t)
+	time.Sleep(50 * time.Millisecond)
+	if ids := r.ids(); len(ids) != 1 {
+		t.Errorf("expected a single send, got %v", ids)
+	}
+}
+
+func TestScheduler_ExpiredLeaseIsSentAgain(t *testing.T) {
+	store := NewMemoryStore()
+	if _, err := store.Add(context.Background(), Entry{ID: "a", SendAt: time.Now().Add(-time.Minute)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// An instance claimed the entry and went away before its lease ended.
+	if _, ok, _ := store.Claim(context.Background(), "a", time.Now().Add(-time.Minute), time.Now().Add(-time.Second)); !ok {
+		t.Fatal("expected the claim to succeed")
+	}
+
+	r := newRecorder()
+	newStarted(t, Config{Store: store}, r)
+	if id := r.wait(t); id != "a" {
+		t.Errorf("expected entry a to be sent, got %s", id)
+	}
+}
+
+func TestScheduler_StaleTimer(t *testing.T) {
+	store := NewMemoryStore()
+	r := newRecorder()
+	s := newStarted(t, Config{Store: store}, r)
+	ctx := context.Background()
+
+	soon := time.Now().Add(20 * time.Millisecond)
+	for _, id := range []string{"a", "b"} {
+		if _, err := s.Schedule(ctx, Entry{ID: id, SendAt: soon}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	// Another instance retried both entries after they were armed here:
+	// a is not due again yet, and b is sent with its saved attempts.
+	if err := store.Retry(ctx, "a", time.Now().Add(time.Hour), 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := store.Retry(ctx, "b", soon, 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if id := r.wait(t); id != "b" {
+		t.Fatalf("expected entry b to be sent, got %s", id)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if err := s.Shutdown(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.sent) != 1 || r.sent[0].Attempts != 2 {
+		t.Errorf("expected only entry b to be sent, as saved, got %+v", r.sent)
+	}
+	if e, ok := store.get("a"); !ok || e.Attempts != 1 {
+		t.Errorf("expected entry a to wait for its retry, got %+v, %v", e, ok)
+	}
+}
+
+func TestScheduler_Retry(t *testing.T) {
+	store := NewMemoryStore()
+	r := newRecorder()
+	r.err = errors.New("unavailable")
+	s := newStarted(t, Config{Store: store, MaxAttempts: 2}, r)
+
+	if _, err := s.Schedule(context.Background(), Entry{ID: "a", SendAt: time.Now()}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	r.wait(t)
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	e, ok := store.get("a")
+	if !ok || e.Attempts != 1 {
+		t.Fatalf("expected the entry to be kept for a retry, got %+v, %v", e, ok)
+	}
+
+	// The last attempt gives up on the entry.
+	if err := store.Retry(context.Background(), "a", time.Now(), 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s = newStarted(t, Config{Store: store, MaxAttempts: 2}, r)
+	r.wait(t)
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := store.get("a"); ok {
+		t.Error("expected the entry to be removed after the last attempt")
+	}
+}
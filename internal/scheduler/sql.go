@@ -0,0 +1,187 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SQLStore keeps the entries in a SQL database, where they outlive the
+// instance that scheduled them and are sent by whichever instance claims
+// them first. Call Migrate once before use.
+type SQLStore struct {
+	db *gorm.DB
+}
+
+// sqlEntry is a row of the scheduled_notifications table.
+type sqlEntry struct {
+	ID     string `gorm:"primaryKey;size:64"`
+	TaskID string `gorm:"size:64;index"`
+	// Key is null for entries without one, which the unique index ignores.
+	Key       *string `gorm:"size:128;uniqueIndex"`
+	DryRun    bool
+	SendAt    time.Time `gorm:"index"`
+	CreatedAt time.Time `gorm:"autoCreateTime:false"`
+	Attempts  int
+	// ClaimedUntil is the end of the lease of the instance sending the
+	// entry; the zero time when it is not being sent.
+	ClaimedUntil time.Time
+	Request      []byte
+}
+
+func (sqlEntry) TableName() string {
+	return "scheduled_notifications"
+}
+
+func (row *sqlEntry) entry() Entry {
+	e := Entry{
+		ID:        row.ID,
+		TaskID:    row.TaskID,
+		DryRun:    row.DryRun,
+		SendAt:    row.SendAt,
+		CreatedAt: row.CreatedAt,
+		Attempts:  row.Attempts,
+		Request:   row.Request,
+	}
+	if row.Key != nil {
+		e.Key = *row.Key
+	}
+
+	return e
+}
+
+var _ Store = (*SQLStore)(nil)
+
+func NewSQLStore(db *gorm.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+// Migrate creates or updates the scheduled_notifications table.
+func (s *SQLStore) Migrate(ctx context.Context) error {
+	return s.db.WithContext(ctx).AutoMigrate(&sqlEntry{})
+}
+
+// Add implements Store. The unique key index deduplicates entries that
+// instances add at the same time.
+func (s *SQLStore) Add(ctx context.Context, entry Entry) (Entry, error) {
+	row := sqlEntry{
+		ID:        entry.ID,
+		TaskID:    entry.TaskID,
+		DryRun:    entry.DryRun,
+		SendAt:    entry.SendAt,
+		CreatedAt: entry.CreatedAt,
+		Attempts:  entry.Attempts,
+		Request:   entry.Request,
+	}
+	if entry.Key != "" {
+		row.Key = &entry.Key
+	}
+
+	err := s.db.WithContext(ctx).Create(&row).Error
+	if err != nil && row.Key != nil {
+		var saved sqlEntry
+		if s.db.WithContext(ctx).Where(&sqlEntry{Key: row.Key}).Take(&saved).Error == nil {
+			return saved.entry(), nil
+		}
+	}
+	if err != nil {
+		return Entry{}, err
+	}
+
+	return entry, nil
+}
+
+// Due implements Store.
+func (s *SQLStore) Due(ctx context.Context, before time.Time) ([]Entry, error) {
+	return s.find(s.db.WithContext(ctx).Where("send_at < ?", before))
+}
+
+// List implements Store.
+func (s *SQLStore) List(ctx context.Context) ([]Entry, error) {
+	return s.find(s.db.WithContext(ctx))
+}
+
+func (s *SQLStore) find(query *gorm.DB) ([]Entry, error) {
+	var rows []sqlEntry
+	if err := query.Order("send_at, id").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, len(rows))
+	for i := range rows {
+		entries[i] = rows[i].entry()
+	}
+
+	return entries, nil
+}
+
+// Claim implements Store. Only one of the instances claiming an entry at
+// the same time updates its row.
+func (s *SQLStore) Claim(ctx context.Context, id string, now, until time.Time) (Entry, bool, error) {
+	res := s.db.WithContext(ctx).Model(&sqlEntry{}).
+		Where("id = ? AND send_at <= ? AND claimed_until < ?", id, now, now).
+		Update("claimed_until", until)
+	if res.Error != nil {
+		return Entry{}, false, res.Error
+	}
+	if res.RowsAffected == 0 {
+		return Entry{}, false, nil
+	}
+
+	var row sqlEntry
+	err := s.db.WithContext(ctx).Where("id = ?", id).Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// Canceled since it was claimed.
+		return Entry{}, false, nil
+	}
+	if err != nil {
+		return Entry{}, false, err
+	}
+
+	return row.entry(), true, nil
+}
+
+// Retry implements Store.
+func (s *SQLStore) Retry(ctx context.Context, id string, sendAt time.Time, attempts int) error {
+	return s.db.WithContext(ctx).Model(&sqlEntry{}).
+		Where("id = ?", id).
+		Updates(map[string]any{
+			"send_at":       sendAt,
+			"attempts":      attempts,
+			"claimed_until": time.Time{},
+		}).Error
+}
+
+// Remove implements Store.
+func (s *SQLStore) Remove(ctx context.Context, id string) error {
+	return s.db.WithContext(ctx).Delete(&sqlEntry{}, "id = ?", id).Error
+}
+
+// RemoveTask implements Store.
+func (s *SQLStore) RemoveTask(ctx context.Context, taskID string) ([]Entry, error) {
+	var removed []Entry
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		entries, err := s.find(tx.Where("task_id = ?", taskID))
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			res := tx.Delete(&sqlEntry{}, "id = ?", entry.ID)
+			if res.Error != nil {
+				return res.Error
+			}
+			// Another instance removed the row first.
+			if res.RowsAffected > 0 {
+				removed = append(removed, entry)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,121 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/dbtest"
+)
+
+func newTestSQLStore(t *testing.T) *SQLStore {
+	t.Helper()
+
+	s := NewSQLStore(dbtest.Open(t))
+	if err := s.Migrate(context.Background()); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	return s
+}
+
+func TestSQLStore_AddAndList(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSQLStore(t)
+
+	sendAt := time.Unix(1_700_000_000, 0).UTC()
+	want := []Entry{
+		{ID: "b", TaskID: "t", Key: "k", DryRun: true, SendAt: sendAt.Add(time.Minute), CreatedAt: sendAt, Request: []byte{0x0a, 0x01, 0x61}},
+		{ID: "a", TaskID: "t", SendAt: sendAt, CreatedAt: sendAt},
+		{ID: "c", TaskID: "t", SendAt: sendAt.Add(2 * time.Minute), CreatedAt: sendAt},
+	}
+	for _, e := range want {
+		if got, err := s.Add(ctx, e); err != nil || got.ID != e.ID {
+			t.Fatalf("expected entry %s to be added, got %v, %v", e.ID, got.ID, err)
+		}
+	}
+
+	if got, err := s.Add(ctx, Entry{ID: "d", Key: "k", SendAt: sendAt}); err != nil || got.ID != "b" {
+		t.Errorf("expected the saved entry for a duplicate key, got %v, %v", got.ID, err)
+	}
+
+	got, err := s.List(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 3 || got[0].ID != "a" || got[1].ID != "b" || got[2].ID != "c" {
+		t.Fatalf("expected entries a, b and c by send time, got %+v", got)
+	}
+	if b := got[1]; b.Key != "k" || !b.DryRun || !b.SendAt.Equal(want[0].SendAt) || string(b.Request) != string(want[0].Request) {
+		t.Errorf("expected %+v, got %+v", want[0], b)
+	}
+
+	due, err := s.Due(ctx, sendAt.Add(90*time.Second))
+	if err != nil || len(due) != 2 {
+		t.Errorf("expected entries a and b to be due, got %+v, %v", due, err)
+	}
+}
+
+func TestSQLStore_ClaimAndRetry(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSQLStore(t)
+
+	now := time.Unix(1_700_000_000, 0).UTC()
+	if _, err := s.Add(ctx, Entry{ID: "a", TaskID: "t", SendAt: now}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, ok, _ := s.Claim(ctx, "a", now.Add(-time.Second), now.Add(time.Minute)); ok {
+		t.Error("expected a claim before the send time to fail")
+	}
+	if e, ok, err := s.Claim(ctx, "a", now, now.Add(time.Minute)); err != nil || !ok || e.ID != "a" {
+		t.Fatalf("expected the first claim to succeed, got %+v, %v, %v", e, ok, err)
+	}
+	if _, ok, _ := s.Claim(ctx, "a", now.Add(time.Second), now.Add(time.Minute)); ok {
+		t.Error("expected a claim during the lease to fail")
+	}
+	if _, ok, _ := s.Claim(ctx, "a", now.Add(2*time.Minute), now.Add(3*time.Minute)); !ok {
+		t.Error("expected a claim after the lease to succeed")
+	}
+
+	if err := s.Retry(ctx, "a", now.Add(time.Hour), 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, _ := s.List(ctx)
+	if len(got) != 1 || got[0].Attempts != 1 || !got[0].SendAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("expected the entry to be moved for a retry, got %+v", got)
+	}
+	// A timer armed for the first send must not claim the retry early.
+	if _, ok, _ := s.Claim(ctx, "a", now.Add(4*time.Minute), now.Add(5*time.Minute)); ok {
+		t.Error("expected a claim before the retry time to fail")
+	}
+	e, ok, _ := s.Claim(ctx, "a", now.Add(time.Hour), now.Add(time.Hour+time.Minute))
+	if !ok || e.Attempts != 1 || !e.SendAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("expected the retried entry to be claimed as saved, got %+v, %v", e, ok)
+	}
+
+	if err := s.Remove(ctx, "a"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok, _ := s.Claim(ctx, "a", now.Add(2*time.Hour), now.Add(3*time.Hour)); ok {
+		t.Error("expected a removed entry not to be claimed")
+	}
+}
+
+func TestSQLStore_RemoveTask(t *testing.T) {
+	ctx := context.Background()
+	s := newTestSQLStore(t)
+
+	now := time.Unix(1_700_000_000, 0).UTC()
+	for _, e := range []Entry{{ID: "a", TaskID: "t1", SendAt: now}, {ID: "b", TaskID: "t1", SendAt: now}, {ID: "c", TaskID: "t2", SendAt: now}} {
+		if _, err := s.Add(ctx, e); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if removed, err := s.RemoveTask(ctx, "t1"); err != nil || len(removed) != 2 {
+		t.Fatalf("expected two removed entries, got %+v, %v", removed, err)
+	}
+	if got, _ := s.List(ctx); len(got) != 1 || got[0].ID != "c" {
+		t.Errorf("expected only entry c to be left, got %+v", got)
+	}
+}
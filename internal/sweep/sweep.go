@@ -0,0 +1,32 @@
+// Package sweep paces the deletion of expired entries, which the stores of
+// this service run on their write path rather than from a goroutine of
+// their own.
+package sweep
+
+import (
+	"sync"
+	"time"
+)
+
+// Interval is the least time between two sweeps of a store.
+const Interval = time.Minute
+
+// Pacer lets a sweep run at most once per Interval. The zero value is ready
+// to use, and it is safe for concurrent use.
+type Pacer struct {
+	mu   sync.Mutex
+	last time.Time
+}
+
+// Due reports whether a sweep should run at now. It counts the sweep as run
+// when it does.
+func (p *Pacer) Due(now time.Time) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if now.Sub(p.last) < Interval {
+		return false
+	}
+	p.last = now
+	return true
+}
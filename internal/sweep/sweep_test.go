@@ -0,0 +1,21 @@
+package sweep
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPacer_Due(t *testing.T) {
+	var p Pacer
+	now := time.Unix(1_700_000_000, 0)
+
+	if !p.Due(now) {
+		t.Fatal("expected the first sweep to be due")
+	}
+	if p.Due(now.Add(Interval - time.Second)) {
+		t.Error("expected no sweep within the interval")
+	}
+	if !p.Due(now.Add(Interval)) {
+		t.Error("expected a sweep once the interval has passed")
+	}
+}
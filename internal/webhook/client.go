@@ -0,0 +1,254 @@
+// Package webhook delivers reminders to chat incoming webhooks. The payload
+// is accepted by both Slack and Discord.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/channel"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/model"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/retry"
+)
+
+// defaultParallelism bounds the webhooks posted to at once.
+const defaultParallelism = 8
+
+// DefaultAllowedHosts are the incoming webhook hosts of Slack and Discord.
+var DefaultAllowedHosts = []string{"hooks.slack.com", "discord.com", "discordapp.com"}
+
+type Config struct {
+	Renderer channel.Renderer
+	// Retry bounds re-sends of retryable failures. Zero value uses retry.DefaultPolicy.
+	Retry retry.Policy
+	// AllowedHosts restricts webhook URLs to these hosts. Empty uses DefaultAllowedHosts.
+	AllowedHosts []string
+	// HTTPClient sends webhook requests. Nil uses a client with channel.DefaultTimeout.
+	// Redirects are never followed, since they could leave AllowedHosts.
+	HTTPClient *http.Client
+	// Parallelism is the number of webhooks posted to at once. Zero uses defaultParallelism.
+	Parallelism int
+}
+
+type Client struct {
+	renderer     channel.Renderer
+	retry        retry.Policy
+	allowedHosts []string
+	httpClient   *http.Client
+	parallelism  int
+}
+
+var _ channel.Channel = (*Client)(nil)
+
+func NewClient(cfg Config) *Client {
+	if cfg.Retry.MaxAttempts == 0 {
+		cfg.Retry = retry.DefaultPolicy()
+	}
+	httpClient := http.Client{Timeout: channel.DefaultTimeout}
+	if cfg.HTTPClient != nil {
+		httpClient = *cfg.HTTPClient
+	}
+	httpClient.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	if cfg.Parallelism <= 0 {
+		cfg.Parallelism = defaultParallelism
+	}
+	if len(cfg.AllowedHosts) == 0 {
+		cfg.AllowedHosts = DefaultAllowedHosts
+	}
+
+	return &Client{
+		renderer:     cfg.Renderer,
+		retry:        cfg.Retry,
+		allowedHosts: cfg.AllowedHosts,
+		httpClient:   &httpClient,
+		parallelism:  cfg.Parallelism,
+	}
+}
+
+// payload is a chat message. Slack reads text and Discord reads content;
+// each ignores the other's field.
+type payload struct {
+	Text    string `json:"text"`
+	Content string `json:"content"`
+}
+
+// Deliver implements channel.Channel. Each address is a webhook URL. Dry
+// runs validate the URLs but post nothing.
+func (c *Client) Deliver(ctx context.Context, addresses []string, reminder channel.Reminder) (*model.BulkResult, error) {
+	text := render(c.renderer.Content(reminder))
+	body, err := json.Marshal(payload{Text: text, Content: text})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]model.TokenResult, len(addresses))
+	slots := make(chan struct{}, c.parallelism)
+	var wg sync.WaitGroup
+	for i, address := range addresses {
+		slots <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-slots }()
+			results[i] = c.post(ctx, address, body, reminder.DryRun)
+		})
+	}
+	wg.Wait()
+
+	successCount := 0
+	for _, result := range results {
+		if result.Success {
+			successCount++
+		}
+	}
+
+	return &model.BulkResult{
+		Total:        len(addresses),
+		SuccessCount: successCount,
+		FailureCount: len(addresses) - successCount,
+		Status:       domain.DeliveryStatusComplete,
+		Results:      results,
+	}, nil
+}
+
+func render(content channel.Content) string {
+	lines := []string{content.Title, content.Body}
+	if content.Link != "" {
+		lines = append(lines, content.Link)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Redact implements channel.Redacter: the path of a webhook URL is its
+// secret, so results name it by its origin.
+func (c *Client) Redact(address string) string {
+	return channel.Origin(address)
+}
+
+// post sends body to a webhook, retrying retryable failures within the policy.
+func (c *Client) post(ctx context.Context, address string, body []byte, dryRun bool) model.TokenResult {
+	result := model.TokenResult{Token: c.Redact(address)}
+
+	if err := c.validateURL(address); err != nil {
+		result.Attempts = 1
+		result.Error = err.Error()
+		result.ErrorCode = domain.ErrorCodeInvalidArgument
+		return result
+	}
+	if dryRun {
+		result.Attempts = 1
+		result.Success = true
+		return result
+	}
+
+	for attempt := 1; ; attempt++ {
+		result.Attempts = attempt
+
+		code, hint, err := c.postOnce(ctx, address, body)
+		if err == nil {
+			result.Success = true
+			result.Error = ""
+			result.ErrorCode = domain.ErrorCodeNone
+			return result
+		}
+
+		result.Error = err.Error()
+		result.ErrorCode = code
+		result.ShouldRemoveToken = code.ShouldRemoveToken()
+
+		slog.Warn("chat webhook failed",
+			"host", hostOf(address),
+			"attempt", attempt,
+			"error_code", code.String(),
+			"error", err.Error(),
+		)
+
+		if !code.IsRetryable() || attempt >= c.retry.MaxAttempts {
+			return result
+		}
+		if !retry.SleepWithinDeadline(ctx, c.retry.Backoff(attempt, hint)) {
+			return result
+		}
+	}
+}
+
+func (c *Client) postOnce(ctx context.Context, address string, body []byte) (domain.ErrorCode, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, address, bytes.NewReader(body))
+	if err != nil {
+		return domain.ErrorCodeInvalidArgument, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// Drop the URL from the error: its path is the webhook secret.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return domain.ErrorCodeUnavailable, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return domain.ErrorCodeNone, 0, nil
+	}
+
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	hint := retry.ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+
+	return classifyStatus(resp.StatusCode), hint, fmt.Errorf("webhook returned %d: %s", resp.StatusCode, bytes.TrimSpace(message))
+}
+
+func (c *Client) validateURL(address string) error {
+	u, err := url.Parse(address)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("webhook URL must be an https URL")
+	}
+	if !slices.Contains(c.allowedHosts, u.Hostname()) {
+		return fmt.Errorf("webhook host %s is not allowed", u.Hostname())
+	}
+
+	return nil
+}
+
+// hostOf returns the host of a webhook URL for logging; the path holds the secret.
+func hostOf(address string) string {
+	u, err := url.Parse(address)
+	if err != nil {
+		return ""
+	}
+
+	return u.Host
+}
+
+// classifyStatus maps a webhook error status to an error code.
+func classifyStatus(status int) domain.ErrorCode {
+	switch {
+	case status == http.StatusNotFound || status == http.StatusGone:
+		// The webhook was deleted or its channel archived.
+		return domain.ErrorCodeUnregistered
+	case status == http.StatusTooManyRequests:
+		return domain.ErrorCodeQuotaExceeded
+	case status >= 500:
+		return domain.ErrorCodeUnavailable
+	case status >= 400:
+		return domain.ErrorCodeInvalidArgument
+	default:
+		return domain.ErrorCodeUnknown
+	}
+}
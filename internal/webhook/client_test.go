@@ -0,0 +1,239 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/channel"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/channel/channeltest"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/retry"
+)
+
+// chatServer answers each path with the configured statuses in turn and
+// then with 200, recording the number of requests per path.
+type chatServer struct {
+	mu       sync.Mutex
+	statuses map[string][]int
+	calls    map[string]int
+	payloads []payload
+}
+
+func newChatServer(t *testing.T, statuses map[string][]int) (*httptest.Server, *chatServer) {
+	t.Helper()
+
+	cs := &chatServer{statuses: statuses, calls: make(map[string]int)}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		cs.mu.Lock()
+		defer cs.mu.Unlock()
+
+		var p payload
+		_ = json.Unmarshal(body, &p)
+		cs.payloads = append(cs.payloads, p)
+
+		call := cs.calls[r.URL.Path]
+		cs.calls[r.URL.Path]++
+		if queued := cs.statuses[r.URL.Path]; call < len(queued) {
+			if queued[call] == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "0")
+			}
+			w.WriteHeader(queued[call])
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, cs
+}
+
+func (cs *chatServer) callCount(path string) int {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	return cs.calls[path]
+}
+
+func newTestClient(server *httptest.Server, allowedHosts ...string) *Client {
+	if len(allowedHosts) == 0 {
+		allowedHosts = []string{"127.0.0.1"}
+	}
+
+	return NewClient(Config{
+		Renderer:     channeltest.Renderer{},
+		Retry:        retry.Policy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond},
+		AllowedHosts: allowedHosts,
+		HTTPClient:   server.Client(),
+	})
+}
+
+func TestDeliver(t *testing.T) {
+	server, cs := newChatServer(t, nil)
+	client := newTestClient(server)
+
+	result, err := client.Deliver(context.Background(), []string{server.URL + "/services/a"}, channel.Reminder{TaskType: domain.TypeShort})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.SuccessCount != 1 || result.Results[0].Attempts != 1 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	want := channeltest.Content.Title + "\n" + channeltest.Content.Body + "\n" + channeltest.Content.Link
+	if len(cs.payloads) != 1 || cs.payloads[0].Text != want || cs.payloads[0].Content != want {
+		t.Errorf("unexpected payloads: %+v", cs.payloads)
+	}
+}
+
+func TestDeliver_Retries(t *testing.T) {
+	server, cs := newChatServer(t, map[string][]int{
+		"/flaky":   {http.StatusServiceUnavailable, http.StatusTooManyRequests},
+		"/down":    {500, 500, 500},
+		"/deleted": {http.StatusNotFound},
+		"/bad":     {http.StatusBadRequest},
+	})
+	client := newTestClient(server)
+
+	tests := []struct {
+		path         string
+		wantSuccess  bool
+		wantAttempts int
+		wantCode     domain.ErrorCode
+		wantRemove   bool
+	}{
+		{path: "/flaky", wantSuccess: true, wantAttempts: 3},
+		{path: "/down", wantAttempts: 3, wantCode: domain.ErrorCodeUnavailable},
+		{path: "/deleted", wantAttempts: 1, wantCode: domain.ErrorCodeUnregistered, wantRemove: true},
+		{path: "/bad", wantAttempts: 1, wantCode: domain.ErrorCodeInvalidArgument},
+	}
+
+	addresses := make([]string, len(tests))
+	for i, tt := range tests {
+		addresses[i] = server.URL + tt.path
+	}
+
+	result, err := client.Deliver(context.Background(), addresses, channel.Reminder{TaskType: domain.TypeNear})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i, tt := range tests {
+		r := result.Results[i]
+		if r.Token != server.URL || r.Success != tt.wantSuccess || r.Attempts != tt.wantAttempts || r.ErrorCode != tt.wantCode || r.ShouldRemoveToken != tt.wantRemove {
+			t.Errorf("%s: unexpected result %+v", tt.path, r)
+		}
+		if got := cs.callCount(tt.path); got != tt.wantAttempts {
+			t.Errorf("%s: expected %d requests, got %d", tt.path, tt.wantAttempts, got)
+		}
+	}
+	if result.SuccessCount != 1 || result.FailureCount != 3 {
+		t.Errorf("unexpected counts: %+v", result)
+	}
+}
+
+func TestDeliver_InvalidURL(t *testing.T) {
+	server, cs := newChatServer(t, nil)
+	serverURL, _ := url.Parse(server.URL)
+	client := newTestClient(server, "hooks.slack.com")
+
+	addresses := []string{"http://hooks.slack.com/services/a", server.URL + "/services/a", "://bad"}
+	result, err := client.Deliver(context.Background(), addresses, channel.Reminder{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i, r := range result.Results {
+		if r.Success || r.ErrorCode != domain.ErrorCodeInvalidArgument {
+			t.Errorf("result %d: expected invalid argument, got %+v", i, r)
+		}
+	}
+	if cs.callCount("/services/a") != 0 {
+		t.Errorf("expected no request to the disallowed host %s", serverURL.Host)
+	}
+}
+
+func TestDeliver_DefaultAllowedHosts(t *testing.T) {
+	server, cs := newChatServer(t, nil)
+	client := NewClient(Config{Renderer: channeltest.Renderer{}, HTTPClient: server.Client()})
+
+	result, err := client.Deliver(context.Background(), []string{server.URL + "/services/a"}, channel.Reminder{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Results[0].ErrorCode != domain.ErrorCodeInvalidArgument || cs.callCount("/services/a") != 0 {
+		t.Errorf("expected a host outside the default allowlist to be rejected, got %+v", result.Results[0])
+	}
+}
+
+func TestDeliver_DryRun(t *testing.T) {
+	server, cs := newChatServer(t, nil)
+	client := newTestClient(server)
+
+	result, err := client.Deliver(context.Background(), []string{server.URL + "/services/a"}, channel.Reminder{DryRun: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Results[0].Success || cs.callCount("/services/a") != 0 {
+		t.Errorf("expected a validated but unsent message, got %+v", result.Results[0])
+	}
+}
+
+func TestDeliver_RedirectNotFollowed(t *testing.T) {
+	server, cs := newChatServer(t, nil)
+	redirect := httptest.NewTLSServer(http.RedirectHandler(server.URL+"/elsewhere", http.StatusTemporaryRedirect))
+	t.Cleanup(redirect.Close)
+	client := NewClient(Config{Renderer: channeltest.Renderer{}, HTTPClient: server.Client(), AllowedHosts: []string{"127.0.0.1"}})
+
+	result, err := client.Deliver(context.Background(), []string{redirect.URL + "/services/a"}, channel.Reminder{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Results[0].Success || cs.callCount("/elsewhere") != 0 {
+		t.Errorf("expected the redirect not to be followed, got %+v", result.Results[0])
+	}
+}
+
+func TestDeliver_BoundedParallelism(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		maxInFlight = max(maxInFlight, inFlight)
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+	}))
+	t.Cleanup(server.Close)
+	client := NewClient(Config{Renderer: channeltest.Renderer{}, HTTPClient: server.Client(), AllowedHosts: []string{"127.0.0.1"}, Parallelism: 2})
+
+	addresses := make([]string, 5)
+	for i := range addresses {
+		addresses[i] = fmt.Sprintf("%s/services/%d", server.URL, i)
+	}
+
+	result, err := client.Deliver(context.Background(), addresses, channel.Reminder{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.SuccessCount != len(addresses) {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if maxInFlight != 2 {
+		t.Errorf("expected 2 webhooks in flight, got %d", maxInFlight)
+	}
+}
@@ -0,0 +1,258 @@
+// Package webpush delivers reminders to browsers through native Web Push
+// (RFC 8030), with VAPID authentication (RFC 8292) and aes128gcm payload
+// encryption (RFC 8291).
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/channel"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/model"
+)
+
+const (
+	// defaultTTL is used when the renderer sets no TTL header, which RFC 8030 requires.
+	defaultTTL = 24 * time.Hour
+	// defaultParallelism bounds the subscriptions pushed to at once.
+	defaultParallelism = 8
+)
+
+// DefaultAllowedHosts are the push services of the major browsers. A
+// leading "*." matches any subdomain.
+var DefaultAllowedHosts = []string{
+	"fcm.googleapis.com",
+	"updates.push.services.mozilla.com",
+	"*.notify.windows.com",
+	"web.push.apple.com",
+}
+
+// Renderer produces the payload and headers of a push message.
+// *fcm.Client implements it, so browsers receive the same versioned data
+// payload as FCM data-only messages.
+type Renderer interface {
+	DataPayload(reminder channel.Reminder) map[string]string
+	WebpushHeaders(taskType domain.Type) map[string]string
+}
+
+type Config struct {
+	VAPID *VAPIDKeys
+	// Subject is the VAPID contact, a mailto: or https: URL.
+	Subject  string
+	Renderer Renderer
+	// HTTPClient sends push requests. Nil uses a client with channel.DefaultTimeout.
+	// Redirects are never followed, since they could leave AllowedHosts.
+	HTTPClient *http.Client
+	// AllowedHosts restricts subscription endpoints to these hosts, so that
+	// callers cannot make the server post to arbitrary addresses. Nil uses
+	// DefaultAllowedHosts.
+	AllowedHosts []string
+	// Parallelism is the number of subscriptions pushed to at once. Zero uses defaultParallelism.
+	Parallelism int
+}
+
+type Client struct {
+	vapid        *VAPIDKeys
+	subject      string
+	renderer     Renderer
+	httpClient   *http.Client
+	allowedHosts []string
+	parallelism  int
+}
+
+var _ channel.Channel = (*Client)(nil)
+
+func NewClient(cfg Config) *Client {
+	httpClient := http.Client{Timeout: channel.DefaultTimeout}
+	if cfg.HTTPClient != nil {
+		httpClient = *cfg.HTTPClient
+	}
+	httpClient.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	if cfg.AllowedHosts == nil {
+		cfg.AllowedHosts = DefaultAllowedHosts
+	}
+	if cfg.Parallelism <= 0 {
+		cfg.Parallelism = defaultParallelism
+	}
+
+	return &Client{
+		vapid:        cfg.VAPID,
+		subject:      cfg.Subject,
+		renderer:     cfg.Renderer,
+		httpClient:   &httpClient,
+		allowedHosts: cfg.AllowedHosts,
+		parallelism:  cfg.Parallelism,
+	}
+}
+
+// Deliver implements channel.Channel. Each address is a JSON-serialised
+// PushSubscription. Dry runs parse and encrypt for every subscription but
+// send nothing.
+func (c *Client) Deliver(ctx context.Context, addresses []string, reminder channel.Reminder) (*model.BulkResult, error) {
+	payload, err := json.Marshal(c.renderer.DataPayload(reminder))
+	if err != nil {
+		return nil, err
+	}
+	headers := c.renderer.WebpushHeaders(reminder.TaskType)
+
+	results := make([]model.TokenResult, len(addresses))
+	slots := make(chan struct{}, c.parallelism)
+	var wg sync.WaitGroup
+	for i, address := range addresses {
+		slots <- struct{}{}
+		wg.Go(func() {
+			defer func() { <-slots }()
+			results[i] = c.push(ctx, address, payload, headers, reminder.DryRun)
+		})
+	}
+	wg.Wait()
+
+	successCount := 0
+	for _, result := range results {
+		if result.Success {
+			successCount++
+		}
+	}
+
+	return &model.BulkResult{
+		Total:        len(addresses),
+		SuccessCount: successCount,
+		FailureCount: len(addresses) - successCount,
+		Status:       domain.DeliveryStatusComplete,
+		Results:      results,
+	}, nil
+}
+
+// Redact implements channel.Redacter: a subscription holds its auth secret,
+// so results name it by the origin of its push service.
+func (c *Client) Redact(address string) string {
+	var s Subscription
+	if err := json.Unmarshal([]byte(address), &s); err != nil {
+		return ""
+	}
+
+	return channel.Origin(s.Endpoint)
+}
+
+func (c *Client) push(ctx context.Context, address string, payload []byte, headers map[string]string, dryRun bool) model.TokenResult {
+	result := model.TokenResult{Token: c.Redact(address), Attempts: 1}
+	fail := func(code domain.ErrorCode, err error) model.TokenResult {
+		result.Error = err.Error()
+		result.ErrorCode = code
+		result.ShouldRemoveToken = code.ShouldRemoveToken()
+
+		slog.Warn("web push failed for subscription",
+			"error_code", code.String(),
+			"error", err.Error(),
+		)
+		return result
+	}
+
+	sub, err := parseSubscription(address)
+	if err != nil {
+		return fail(domain.ErrorCodeInvalidArgument, err)
+	}
+	if !c.allowed(sub.endpoint.Hostname()) {
+		return fail(domain.ErrorCodeInvalidArgument, fmt.Errorf("%w: push service %s is not allowed", ErrInvalidSubscription, sub.endpoint.Hostname()))
+	}
+
+	body, err := encrypt(payload, sub)
+	if err != nil {
+		return fail(domain.ErrorCodeInvalidArgument, err)
+	}
+
+	if dryRun {
+		result.Success = true
+		return result
+	}
+
+	authorization, err := c.vapid.authorization(sub.endpoint, c.subject, time.Now())
+	if err != nil {
+		return fail(domain.ErrorCodeInternal, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.endpoint.String(), bytes.NewReader(body))
+	if err != nil {
+		return fail(domain.ErrorCodeInvalidArgument, err)
+	}
+	req.Header.Set("TTL", strconv.Itoa(int(defaultTTL.Seconds())))
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Authorization", authorization)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// Drop the URL from the error: its path identifies the subscription.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fail(domain.ErrorCodeUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		result.Success = true
+		result.MessageID = resp.Header.Get("Location")
+		return result
+	}
+
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fail(classifyStatus(resp.StatusCode), fmt.Errorf("push service returned %d: %s", resp.StatusCode, bytes.TrimSpace(message)))
+}
+
+// allowed reports whether host matches AllowedHosts.
+func (c *Client) allowed(host string) bool {
+	host = strings.ToLower(host)
+	for _, pattern := range c.allowedHosts {
+		pattern = strings.ToLower(pattern)
+		if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+			if strings.HasSuffix(host, suffix) && len(host) > len(suffix) {
+				return true
+			}
+			continue
+		}
+		if host == pattern {
+			return true
+		}
+	}
+
+	return false
+}
+
+// classifyStatus maps a push service error status to an error code.
+func classifyStatus(status int) domain.ErrorCode {
+	switch {
+	case status == http.StatusNotFound || status == http.StatusGone:
+		return domain.ErrorCodeSubscriptionGone
+	case status == http.StatusTooManyRequests:
+		return domain.ErrorCodeQuotaExceeded
+	case status == http.StatusForbidden:
+		// RFC 8292: the VAPID key does not match the one the subscription was created with.
+		return domain.ErrorCodeSenderIDMismatch
+	case status >= 500:
+		return domain.ErrorCodeUnavailable
+	case status >= 400:
+		return domain.ErrorCodeInvalidArgument
+	default:
+		return domain.ErrorCodeUnknown
+	}
+}
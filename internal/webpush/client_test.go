@@ -0,0 +1,388 @@
+package webpush
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/KasumiMercury/primind-notification-invoker/internal/channel"
+	"github.com/KasumiMercury/primind-notification-invoker/internal/domain"
+)
+
+type stubRenderer struct{}
+
+func (stubRenderer) DataPayload(reminder channel.Reminder) map[string]string {
+	return map[string]string{"task_id": reminder.TaskID.String(), "title": "reminder"}
+}
+
+func (stubRenderer) WebpushHeaders(domain.Type) map[string]string {
+	return map[string]string{"TTL": "900", "Urgency": "high"}
+}
+
+// testSubscription is a browser-side subscription whose private key lets the
+// push service stand-in decrypt payloads.
+type testSubscription struct {
+	address    string
+	uaPrivate  *ecdh.PrivateKey
+	authSecret []byte
+}
+
+func newTestSubscription(t *testing.T, endpoint string) testSubscription {
+	t.Helper()
+
+	uaPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	authSecret := make([]byte, 16)
+	_, _ = rand.Read(authSecret)
+
+	var sub Subscription
+	sub.Endpoint = endpoint
+	sub.Keys.P256dh = base64.RawURLEncoding.EncodeToString(uaPrivate.PublicKey().Bytes())
+	sub.Keys.Auth = base64.RawURLEncoding.EncodeToString(authSecret)
+	address, _ := json.Marshal(sub)
+
+	return testSubscription{address: string(address), uaPrivate: uaPrivate, authSecret: authSecret}
+}
+
+// decrypt reverses encryptWith as a user agent would.
+func (s testSubscription) decrypt(t *testing.T, body []byte) []byte {
+	t.Helper()
+
+	salt, keyLen := body[:16], int(body[20])
+	asPublicBytes, ciphertext := body[21:21+keyLen], body[21+keyLen:]
+
+	asPublic, err := ecdh.P256().NewPublicKey(asPublicBytes)
+	if err != nil {
+		t.Fatalf("invalid application server key: %v", err)
+	}
+	secret, _ := s.uaPrivate.ECDH(asPublic)
+	keyInfo := "WebPush: info\x00" + string(s.uaPrivate.PublicKey().Bytes()) + string(asPublicBytes)
+	ikm, _ := hkdf.Key(sha256.New, secret, s.authSecret, keyInfo, 32)
+	cek, _ := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	nonce, _ := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		t.Fatalf("failed to decrypt payload: %v", err)
+	}
+	if plaintext[len(plaintext)-1] != 0x02 {
+		t.Fatalf("expected last record delimiter, got %x", plaintext[len(plaintext)-1])
+	}
+	return plaintext[:len(plaintext)-1]
+}
+
+// verifyVAPID checks the JWT of an Authorization header against the "k" key.
+func verifyVAPID(t *testing.T, header, wantAudience string) {
+	t.Helper()
+
+	params := strings.Split(strings.TrimPrefix(header, "vapid "), ", ")
+	token := strings.TrimPrefix(params[0], "t=")
+	publicKey, _ := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(params[1], "k="))
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("malformed JWT: %q", token)
+	}
+
+	key, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), publicKey)
+	if err != nil {
+		t.Fatalf("invalid VAPID public key: %v", err)
+	}
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+	if !ecdsa.Verify(key, digest[:], r, s) {
+		t.Error("VAPID signature does not verify")
+	}
+
+	claimsJSON, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims map[string]any
+	_ = json.Unmarshal(claimsJSON, &claims)
+	if claims["aud"] != wantAudience || claims["sub"] != "mailto:ops@example.com" {
+		t.Errorf("unexpected claims: %v", claims)
+	}
+}
+
+type pushRequest struct {
+	path    string
+	headers http.Header
+	body    []byte
+}
+
+// newPushService starts a push service stand-in that answers with the status
+// configured for each path and records every request.
+func newPushService(t *testing.T, statuses map[string]int) (*httptest.Server, func() []pushRequest) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var requests []pushRequest
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, pushRequest{path: r.URL.Path, headers: r.Header.Clone(), body: body})
+		mu.Unlock()
+
+		status, ok := statuses[r.URL.Path]
+		if !ok {
+			status = http.StatusCreated
+		}
+		if status == http.StatusCreated {
+			w.Header().Set("Location", "https://push.example.com/m"+r.URL.Path)
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []pushRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]pushRequest(nil), requests...)
+	}
+}
+
+func newTestClient(t *testing.T, server *httptest.Server) *Client {
+	t.Helper()
+
+	keys, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatalf("failed to generate VAPID keys: %v", err)
+	}
+
+	return NewClient(Config{
+		VAPID:        keys,
+		Subject:      "mailto:ops@example.com",
+		Renderer:     stubRenderer{},
+		HTTPClient:   server.Client(),
+		AllowedHosts: []string{"127.0.0.1"},
+	})
+}
+
+func TestDeliver(t *testing.T) {
+	server, requests := newPushService(t, nil)
+	client := newTestClient(t, server)
+	sub := newTestSubscription(t, server.URL+"/push/1")
+
+	result, err := client.Deliver(context.Background(), []string{sub.address}, channel.Reminder{TaskID: "task-1", TaskType: domain.TypeShort})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if result.SuccessCount != 1 || !result.Results[0].Success || result.Results[0].MessageID != "https://push.example.com/m/push/1" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if result.Results[0].Token != server.URL {
+		t.Errorf("expected the result to name the push service %s, got %q", server.URL, result.Results[0].Token)
+	}
+
+	got := requests()
+	if len(got) != 1 {
+		t.Fatalf("expected 1 push request, got %d", len(got))
+	}
+	req := got[0]
+	if req.headers.Get("Content-Encoding") != "aes128gcm" || req.headers.Get("TTL") != "900" || req.headers.Get("Urgency") != "high" {
+		t.Errorf("unexpected headers: %v", req.headers)
+	}
+	verifyVAPID(t, req.headers.Get("Authorization"), server.URL)
+
+	var payload map[string]string
+	if err := json.Unmarshal(sub.decrypt(t, req.body), &payload); err != nil {
+		t.Fatalf("failed to decode payload: %v", err)
+	}
+	if payload["task_id"] != "task-1" || payload["title"] != "reminder" {
+		t.Errorf("unexpected payload: %v", payload)
+	}
+}
+
+func TestDeliver_StatusMapping(t *testing.T) {
+	server, _ := newPushService(t, map[string]int{
+		"/gone":    http.StatusGone,
+		"/missing": http.StatusNotFound,
+		"/busy":    http.StatusTooManyRequests,
+		"/down":    http.StatusBadGateway,
+		"/key":     http.StatusForbidden,
+		"/large":   http.StatusRequestEntityTooLarge,
+	})
+	client := newTestClient(t, server)
+
+	tests := []struct {
+		path       string
+		wantCode   domain.ErrorCode
+		wantRemove bool
+	}{
+		{path: "/ok"},
+		{path: "/gone", wantCode: domain.ErrorCodeSubscriptionGone, wantRemove: true},
+		{path: "/missing", wantCode: domain.ErrorCodeSubscriptionGone, wantRemove: true},
+		{path: "/busy", wantCode: domain.ErrorCodeQuotaExceeded},
+		{path: "/down", wantCode: domain.ErrorCodeUnavailable},
+		{path: "/key", wantCode: domain.ErrorCodeSenderIDMismatch, wantRemove: true},
+		{path: "/large", wantCode: domain.ErrorCodeInvalidArgument},
+	}
+
+	addresses := make([]string, len(tests))
+	for i, tt := range tests {
+		addresses[i] = newTestSubscription(t, server.URL+tt.path).address
+	}
+
+	result, err := client.Deliver(context.Background(), addresses, channel.Reminder{TaskType: domain.TypeNear})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i, tt := range tests {
+		r := result.Results[i]
+		if r.ErrorCode != tt.wantCode || r.ShouldRemoveToken != tt.wantRemove || r.Success != (tt.wantCode == domain.ErrorCodeNone) {
+			t.Errorf("%s: unexpected result %+v", tt.path, r)
+		}
+	}
+	if result.SuccessCount != 1 || result.FailureCount != len(tests)-1 {
+		t.Errorf("unexpected counts: %+v", result)
+	}
+}
+
+func TestDeliver_InvalidSubscription(t *testing.T) {
+	server, requests := newPushService(t, nil)
+	client := newTestClient(t, server)
+
+	valid := newTestSubscription(t, server.URL+"/push")
+	var insecure Subscription
+	_ = json.Unmarshal([]byte(valid.address), &insecure)
+	insecure.Endpoint = strings.Replace(insecure.Endpoint, "https://", "http://", 1)
+	insecureJSON, _ := json.Marshal(insecure)
+
+	addresses := []string{"not json", string(insecureJSON), `{"endpoint":"https://push.example.com","keys":{"p256dh":"AAAA","auth":"AAAA"}}`}
+	result, err := client.Deliver(context.Background(), addresses, channel.Reminder{TaskType: domain.TypeNear})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i, r := range result.Results {
+		if r.Success || r.ErrorCode != domain.ErrorCodeInvalidArgument || r.ShouldRemoveToken {
+			t.Errorf("result %d: expected invalid argument, got %+v", i, r)
+		}
+	}
+	if len(requests()) != 0 {
+		t.Error("expected no push requests")
+	}
+}
+
+func TestDeliver_DryRun(t *testing.T) {
+	server, requests := newPushService(t, nil)
+	client := newTestClient(t, server)
+
+	result, err := client.Deliver(context.Background(), []string{newTestSubscription(t, server.URL+"/push").address}, channel.Reminder{TaskType: domain.TypeShort, DryRun: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Results[0].Success || len(requests()) != 0 {
+		t.Errorf("expected a validated but unsent push, got %+v and %d requests", result.Results[0], len(requests()))
+	}
+}
+
+func TestParseVAPIDKeys(t *testing.T) {
+	keys, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatalf("failed to generate VAPID keys: %v", err)
+	}
+
+	parsed, err := ParseVAPIDKeys(keys.PrivateKey())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if parsed.PublicKey() != keys.PublicKey() {
+		t.Errorf("expected public key %s, got %s", keys.PublicKey(), parsed.PublicKey())
+	}
+
+	if _, err := ParseVAPIDKeys("not-a-key"); err == nil {
+		t.Error("expected an error for an invalid key")
+	}
+}
+
+func TestEncrypt_PayloadTooLarge(t *testing.T) {
+	sub := newTestSubscription(t, "https://push.example.com")
+	parsed, err := parseSubscription(sub.address)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := encrypt(bytes.Repeat([]byte("a"), maxPayloadSize+1), parsed); err == nil {
+		t.Error("expected an error for an oversized payload")
+	}
+}
+
+func TestDeliver_DisallowedHost(t *testing.T) {
+	server, requests := newPushService(t, nil)
+	keys, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatalf("failed to generate VAPID keys: %v", err)
+	}
+	client := NewClient(Config{VAPID: keys, Subject: "mailto:ops@example.com", Renderer: stubRenderer{}, HTTPClient: server.Client()})
+
+	result, err := client.Deliver(context.Background(), []string{newTestSubscription(t, server.URL+"/push").address}, channel.Reminder{TaskType: domain.TypeShort})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if r := result.Results[0]; r.Success || r.ErrorCode != domain.ErrorCodeInvalidArgument {
+		t.Errorf("expected the endpoint to be rejected, got %+v", r)
+	}
+	if len(requests()) != 0 {
+		t.Error("expected no request to a host outside the allow-list")
+	}
+}
+
+func TestClient_Allowed(t *testing.T) {
+	client := NewClient(Config{})
+
+	tests := map[string]bool{
+		"fcm.googleapis.com":                true,
+		"FCM.googleapis.com":                true,
+		"wns2-by3p.notify.windows.com":      true,
+		"notify.windows.com":                false,
+		"evilnotify.windows.com":            false,
+		"web.push.apple.com":                true,
+		"169.254.169.254":                   false,
+		"metadata.google.internal":          false,
+		"fcm.googleapis.com.example.com":    false,
+		"updates.push.services.mozilla.com": true,
+	}
+	for host, want := range tests {
+		if got := client.allowed(host); got != want {
+			t.Errorf("allowed(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestEncrypt_MessageFitsLimit(t *testing.T) {
+	sub := newTestSubscription(t, "https://push.example.com")
+	parsed, err := parseSubscription(sub.address)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	body, err := encrypt(bytes.Repeat([]byte("a"), maxPayloadSize), parsed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(body) != maxMessageSize {
+		t.Errorf("expected the largest payload to fill %d bytes, got %d", maxMessageSize, len(body))
+	}
+}
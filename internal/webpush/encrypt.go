@@ -0,0 +1,105 @@
+package webpush
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+)
+
+// recordSize is the aes128gcm record size advertised in the header. Payloads
+// are sent as a single record, so it only has to exceed the ciphertext.
+const recordSize = 4096
+
+// maxMessageSize is the largest encrypted message push services have to
+// accept (RFC 8030 Section 7.2); they may reject larger ones with 413.
+const maxMessageSize = 4096
+
+// headerSize is the size of the aes128gcm header: salt, record size, key
+// length and the uncompressed P-256 application server key.
+const headerSize = 16 + 4 + 1 + 65
+
+// maxPayloadSize keeps the message within maxMessageSize: the header, 16
+// bytes of tag and 1 byte of padding delimiter are added to the plaintext.
+const maxPayloadSize = maxMessageSize - headerSize - 17
+
+// encrypt encrypts payload for a subscription as specified by RFC 8291,
+// using a fresh ephemeral key and salt.
+func encrypt(payload []byte, sub *subscription) ([]byte, error) {
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	return encryptWith(payload, sub.uaPublic, sub.authSecret, asPrivate, salt)
+}
+
+// encryptWith is encrypt with a given application server key and salt.
+// The result is the aes128gcm body of RFC 8188: a header carrying salt,
+// record size and the application server public key, followed by one record.
+func encryptWith(payload, uaPublicBytes, authSecret []byte, asPrivate *ecdh.PrivateKey, salt []byte) ([]byte, error) {
+	if len(payload) > maxPayloadSize {
+		return nil, fmt.Errorf("payload of %d bytes exceeds %d bytes", len(payload), maxPayloadSize)
+	}
+
+	uaPublic, err := ecdh.P256().NewPublicKey(uaPublicBytes)
+	if err != nil {
+		return nil, err
+	}
+	ecdhSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asPrivate.PublicKey().Bytes()
+
+	// RFC 8291 Section 3.4: combine the ECDH secret with the auth secret.
+	keyInfo := "WebPush: info\x00" + string(uaPublicBytes) + string(asPublic)
+	ikm, err := hkdf.Key(sha256.New, ecdhSecret, authSecret, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	// RFC 8188 Section 2.2 and 2.3.
+	cek, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: aes128gcm\x00", 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdf.Key(sha256.New, ikm, salt, "Content-Encoding: nonce\x00", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// The last (and only) record ends with the 0x02 padding delimiter.
+	plaintext := append(append([]byte(nil), payload...), 0x02)
+
+	header := make([]byte, 0, 16+4+1+len(asPublic))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, recordSize)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+
+	message := gcm.Seal(header, nonce, plaintext, nil)
+	if len(message) > maxMessageSize {
+		return nil, fmt.Errorf("encrypted message of %d bytes exceeds %d bytes", len(message), maxMessageSize)
+	}
+
+	return message, nil
+}
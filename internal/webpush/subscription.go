@@ -0,0 +1,62 @@
+package webpush
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+var ErrInvalidSubscription = errors.New("invalid push subscription")
+
+// Subscription is a browser PushSubscription as serialised by PushSubscription.toJSON().
+type Subscription struct {
+	Endpoint string `json:"endpoint"`
+	Keys     struct {
+		P256dh string `json:"p256dh"`
+		Auth   string `json:"auth"`
+	} `json:"keys"`
+}
+
+// subscription is a parsed Subscription with decoded keys.
+type subscription struct {
+	endpoint *url.URL
+	// uaPublic is the user agent's uncompressed P-256 public key.
+	uaPublic []byte
+	// authSecret is the 16-byte authentication secret.
+	authSecret []byte
+}
+
+// parseSubscription decodes a JSON-serialised PushSubscription. The endpoint
+// must be an https URL.
+func parseSubscription(data string) (*subscription, error) {
+	var s Subscription
+	if err := json.Unmarshal([]byte(data), &s); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSubscription, err)
+	}
+
+	endpoint, err := url.Parse(s.Endpoint)
+	if err != nil || endpoint.Scheme != "https" || endpoint.Host == "" {
+		return nil, fmt.Errorf("%w: endpoint must be an https URL", ErrInvalidSubscription)
+	}
+
+	uaPublic, err := decodeBase64URL(s.Keys.P256dh)
+	if err != nil || len(uaPublic) != 65 || uaPublic[0] != 0x04 {
+		return nil, fmt.Errorf("%w: p256dh must be an uncompressed P-256 public key", ErrInvalidSubscription)
+	}
+
+	authSecret, err := decodeBase64URL(s.Keys.Auth)
+	if err != nil || len(authSecret) != 16 {
+		return nil, fmt.Errorf("%w: auth must be a 16-byte secret", ErrInvalidSubscription)
+	}
+
+	return &subscription{endpoint: endpoint, uaPublic: uaPublic, authSecret: authSecret}, nil
+}
+
+// decodeBase64URL accepts base64url with or without padding, as browsers and
+// libraries disagree on it.
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
@@ -0,0 +1,101 @@
+package webpush
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// vapidTokenLifetime is the validity of a VAPID JWT. RFC 8292 caps it at 24 hours.
+const vapidTokenLifetime = 12 * time.Hour
+
+// VAPIDKeys identifies the application server to push services (RFC 8292).
+type VAPIDKeys struct {
+	private *ecdsa.PrivateKey
+	// publicKey is the base64url uncompressed public key, sent as the "k" parameter.
+	publicKey string
+}
+
+// ParseVAPIDKeys decodes a base64url raw P-256 private key, the format printed
+// by common VAPID key generators.
+func ParseVAPIDKeys(privateKey string) (*VAPIDKeys, error) {
+	raw, err := decodeBase64URL(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+
+	key, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+
+	return newVAPIDKeys(key)
+}
+
+// GenerateVAPIDKeys creates a new key pair.
+func GenerateVAPIDKeys() (*VAPIDKeys, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return newVAPIDKeys(key)
+}
+
+func newVAPIDKeys(key *ecdsa.PrivateKey) (*VAPIDKeys, error) {
+	public, err := key.PublicKey.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return &VAPIDKeys{private: key, publicKey: base64.RawURLEncoding.EncodeToString(public)}, nil
+}
+
+// PublicKey returns the base64url public key that browsers pass as
+// applicationServerKey when subscribing.
+func (k *VAPIDKeys) PublicKey() string {
+	return k.publicKey
+}
+
+// PrivateKey returns the base64url raw private key accepted by ParseVAPIDKeys.
+func (k *VAPIDKeys) PrivateKey() string {
+	raw, _ := k.private.Bytes()
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// authorization returns the Authorization header value for a push endpoint.
+func (k *VAPIDKeys) authorization(endpoint *url.URL, subject string, now time.Time) (string, error) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`))
+
+	claims, err := json.Marshal(map[string]any{
+		"aud": endpoint.Scheme + "://" + endpoint.Host,
+		"exp": now.Add(vapidTokenLifetime).Unix(),
+		"sub": subject,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	r, s, err := ecdsa.Sign(rand.Reader, k.private, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	// JWS ES256 signatures are the fixed-length concatenation of r and s.
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	token := signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+
+	return "vapid t=" + token + ", k=" + k.publicKey, nil
+}